@@ -0,0 +1,325 @@
+// Package balancer spreads the client's requests over several instances of the bank.
+// A Balancer is an http.RoundTripper: requests are built against utils.BASE_URL as
+// usual and the balancer sends each to the instance its policy picks.
+package balancer
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Policy selects the instance of every request
+type Policy string
+
+const (
+	RoundRobin       Policy = "round-robin"
+	Random           Policy = "random"
+	LeastOutstanding Policy = "least-outstanding" // The instance with the fewest requests awaiting a response
+	Sticky           Policy = "sticky"            // The same instance for every request of a customer
+)
+
+// Policies lists every policy
+var Policies = []Policy{RoundRobin, Random, LeastOutstanding, Sticky}
+
+// ParsePolicy parses a policy name
+func ParsePolicy(s string) (Policy, error) {
+	for _, policy := range Policies {
+		if string(policy) == s {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown policy %q, expected one of %v", s, Policies)
+}
+
+// StickyHeader overrides the key sticky routing hashes, which is otherwise the
+// account of the request
+const StickyHeader = "X-Sticky-Key"
+
+// instance is one target of the balancer
+type instance struct {
+	url         *url.URL
+	outstanding atomic.Int64
+	ejected     atomic.Bool // By a failed health probe, until one succeeds again
+
+	mu      sync.Mutex // Guards the outages
+	outage  *Outage    // Ongoing, nil while the instance serves fine
+	outages []Outage   // Ended
+}
+
+// Balancer routes requests to its targets according to a policy
+type Balancer struct {
+	policy    Policy
+	instances []*instance
+	transport http.RoundTripper
+
+	next     atomic.Uint64 // Round-robin position, also breaks least-outstanding ties
+	mu       sync.Mutex    // Guards rng
+	rng      *rand.Rand
+	observer atomic.Pointer[func(target string, res *vegeta.Result)]
+
+	retries   atomic.Uint64 // Safe reads retried on another instance
+	recovered atomic.Uint64 // Retried reads that succeeded
+}
+
+// New creates a balancer over the target base URLs, e.g. "http://localhost:8081"
+func New(targets []string, policy Policy) (*Balancer, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+	b := &Balancer{
+		policy:    policy,
+		transport: newTransport(),
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, target := range targets {
+		u, err := url.Parse(strings.TrimSuffix(strings.TrimSpace(target), "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid target %q", target)
+		}
+		b.instances = append(b.instances, &instance{url: u})
+	}
+	return b, nil
+}
+
+// ParseTargets parses a comma separated list of base URLs
+func ParseTargets(s string) []string {
+	var targets []string
+	for _, target := range strings.Split(s, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// newTransport mirrors the settings of vegeta's default transport, so attacks behave
+// the same with and without a balancer
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = vegeta.DefaultConnections
+	transport.MaxConnsPerHost = vegeta.DefaultMaxConnections
+	return transport
+}
+
+// Client returns an HTTP client that sends every request through the balancer
+func (b *Balancer) Client() *http.Client {
+	return &http.Client{Transport: b, Timeout: vegeta.DefaultTimeout}
+}
+
+// Policy returns the balancer's policy
+func (b *Balancer) Policy() Policy {
+	return b.policy
+}
+
+// Targets returns the base URLs of the targets
+func (b *Balancer) Targets() []string {
+	targets := make([]string, len(b.instances))
+	for i, in := range b.instances {
+		targets[i] = in.url.String()
+	}
+	return targets
+}
+
+// Observe registers a function that is called with the target and the outcome of every
+// request, concurrently from the requests' goroutines. Nil stops observing.
+func (b *Balancer) Observe(observe func(target string, res *vegeta.Result)) {
+	if observe == nil {
+		b.observer.Store(nil)
+		return
+	}
+	b.observer.Store(&observe)
+}
+
+// maxReadAttempts bounds how many instances a safe read is tried on
+const maxReadAttempts = 3
+
+// RoundTrip sends the request to the instance the policy picks among the healthy ones.
+// A safe read that fails because its instance is unavailable is retried on another.
+func (b *Balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		attempts = maxReadAttempts
+	}
+
+	tried := map[*instance]bool{}
+	for attempt := 1; ; attempt++ {
+		in := b.pick(req, tried)
+		tried[in] = true
+		resp, err := b.send(in, req)
+		if err == nil && !unavailable(resp.StatusCode) {
+			in.recordSuccess(time.Now())
+			if attempt > 1 {
+				b.recovered.Add(1)
+			}
+			return resp, nil
+		}
+		in.recordFailure(time.Now())
+		if attempt == attempts || len(tried) == len(b.instances) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		b.retries.Add(1)
+	}
+}
+
+// unavailable reports whether a status code means the instance, not the request, failed
+func unavailable(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// send sends the request to one instance
+func (b *Balancer) send(in *instance, req *http.Request) (*http.Response, error) {
+	routed := req.Clone(req.Context())
+	routed.URL.Scheme = in.url.Scheme
+	routed.URL.Host = in.url.Host
+	routed.URL.Path = in.url.Path + req.URL.Path
+	routed.Host = ""
+
+	in.outstanding.Add(1)
+	started := time.Now()
+	resp, err := b.transport.RoundTrip(routed)
+	if err != nil {
+		in.outstanding.Add(-1)
+		b.observe(in, req, started, 0, err)
+		return nil, err
+	}
+	// The request is outstanding until its body was read
+	resp.Body = &trackedBody{ReadCloser: resp.Body, done: func() {
+		in.outstanding.Add(-1)
+		b.observe(in, req, started, resp.StatusCode, nil)
+	}}
+	return resp, nil
+}
+
+func (b *Balancer) observe(in *instance, req *http.Request, started time.Time, code int, err error) {
+	observe := b.observer.Load()
+	if observe == nil {
+		return
+	}
+	res := &vegeta.Result{
+		Code:      uint16(code),
+		Timestamp: started,
+		Latency:   time.Since(started),
+		Method:    req.Method,
+		URL:       req.URL.String(),
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	(*observe)(in.url.String(), res)
+}
+
+// pick returns the instance of a request according to the policy, among the healthy
+// instances not tried yet. With none left it falls back to the untried ones, then to all.
+func (b *Balancer) pick(req *http.Request, tried map[*instance]bool) *instance {
+	candidates := make([]*instance, 0, len(b.instances))
+	for _, in := range b.instances {
+		if !tried[in] && !in.ejected.Load() {
+			candidates = append(candidates, in)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, in := range b.instances {
+			if !tried[in] {
+				candidates = append(candidates, in)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = b.instances
+	}
+
+	n := uint64(len(candidates))
+	switch b.policy {
+	case Random:
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return candidates[b.rng.Intn(len(candidates))]
+	case LeastOutstanding:
+		start := b.next.Add(1)
+		best := candidates[start%n]
+		for i := uint64(1); i < n; i++ {
+			if in := candidates[(start+i)%n]; in.outstanding.Load() < best.outstanding.Load() {
+				best = in
+			}
+		}
+		return best
+	case Sticky:
+		if key := stickyKey(req); key != "" {
+			// Hash over every instance so a customer only moves while its own is out
+			h := fnv.New64a()
+			h.Write([]byte(key))
+			home := b.instances[h.Sum64()%uint64(len(b.instances))]
+			for _, in := range candidates {
+				if in == home {
+					return home
+				}
+			}
+			return candidates[h.Sum64()%n]
+		}
+	}
+	// Round-robin, also for sticky requests of no customer such as opening an account
+	return candidates[(b.next.Add(1)-1)%n]
+}
+
+// stickyKey returns the customer a request belongs to: StickyHeader when set, else the
+// account read by GET /accounts/{id} or the sender of a transfer
+func stickyKey(req *http.Request) string {
+	if key := req.Header.Get(StickyHeader); key != "" {
+		return key
+	}
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	if path == "/accounts/transfer" {
+		return transferSender(req)
+	}
+	if id, ok := strings.CutPrefix(path, "/accounts/"); ok && !strings.Contains(id, "/") {
+		return id
+	}
+	return ""
+}
+
+// transferSender reads the fromAccountId of a transfer from a copy of its body
+func transferSender(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	var transfer struct {
+		FromAccountID string `json:"fromAccountId"`
+	}
+	if err := json.NewDecoder(body).Decode(&transfer); err != nil {
+		return ""
+	}
+	return transfer.FromAccountID
+}
+
+// trackedBody calls done once, when the body is closed
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (t *trackedBody) Close() error {
+	err := t.ReadCloser.Close()
+	t.once.Do(t.done)
+	return err
+}
@@ -0,0 +1,149 @@
+package balancer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// instances starts n fake app instances answering with their index, the first one
+// taking delay to answer
+func instances(t *testing.T, n int, delay time.Duration) []string {
+	targets := make([]string, n)
+	for i := range targets {
+		index, wait := i, time.Duration(0)
+		if i == 0 {
+			wait = delay
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(wait)
+			w.Write([]byte{byte('0' + index)})
+		}))
+		t.Cleanup(server.Close)
+		targets[i] = server.URL
+	}
+	return targets
+}
+
+// send makes a request against the single URL clients know and returns the instance
+// that answered
+func send(t *testing.T, client *http.Client, method, path, body string) string {
+	req, err := http.NewRequest(method, "http://localhost:8080"+path, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	answer, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(answer)
+}
+
+func TestRoundRobin(t *testing.T) {
+	b, err := New(instances(t, 3, 0), RoundRobin)
+	require.NoError(t, err)
+
+	var answers []string
+	for i := 0; i < 6; i++ {
+		answers = append(answers, send(t, b.Client(), "GET", "/accounts", ""))
+	}
+	assert.Equal(t, []string{"0", "1", "2", "0", "1", "2"}, answers)
+}
+
+func TestRandomReachesEveryInstance(t *testing.T) {
+	b, err := New(instances(t, 3, 0), Random)
+	require.NoError(t, err)
+
+	seen := map[string]int{}
+	for i := 0; i < 60; i++ {
+		seen[send(t, b.Client(), "GET", "/accounts", "")]++
+	}
+	assert.Len(t, seen, 3)
+}
+
+func TestStickyPerCustomer(t *testing.T) {
+	b, err := New(instances(t, 3, 0), Sticky)
+	require.NoError(t, err)
+	client := b.Client()
+
+	for _, account := range []string{"acc-1", "acc-2", "acc-3", "acc-4"} {
+		read := send(t, client, "GET", "/accounts/"+account, "")
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, read, send(t, client, "GET", "/accounts/"+account, ""))
+		}
+		transfer := send(t, client, "POST", "/accounts/transfer", `{"fromAccountId":"`+account+`","toAccountId":"x","amount":1}`)
+		assert.Equal(t, read, transfer, "a customer's transfers go where its reads go")
+	}
+}
+
+func TestLeastOutstandingAvoidsSlowInstance(t *testing.T) {
+	b, err := New(instances(t, 2, 200*time.Millisecond), LeastOutstanding)
+	require.NoError(t, err)
+	client := b.Client()
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answer := send(t, client, "GET", "/accounts", "")
+			mu.Lock()
+			counts[answer]++
+			mu.Unlock()
+		}()
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	// Once a request waits on the slow instance, everything else goes to the fast one
+	assert.LessOrEqual(t, counts["0"], 2)
+	assert.GreaterOrEqual(t, counts["1"], 18)
+}
+
+func TestObservePerInstance(t *testing.T) {
+	targets := instances(t, 2, 0)
+	b, err := New(targets, RoundRobin)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	observed := map[string][]*vegeta.Result{}
+	b.Observe(func(target string, res *vegeta.Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		observed[target] = append(observed[target], res)
+	})
+	for i := 0; i < 4; i++ {
+		send(t, b.Client(), "GET", "/accounts/acc-1?x=1", "")
+	}
+
+	require.Len(t, observed[targets[0]], 2)
+	require.Len(t, observed[targets[1]], 2)
+	res := observed[targets[0]][0]
+	assert.Equal(t, uint16(200), res.Code)
+	assert.Equal(t, "http://localhost:8080/accounts/acc-1?x=1", res.URL, "results keep the URL the client asked for")
+
+	b.Observe(nil)
+	send(t, b.Client(), "GET", "/accounts", "")
+	assert.Len(t, observed[targets[0]], 2)
+}
+
+func TestParse(t *testing.T) {
+	policy, err := ParsePolicy("least-outstanding")
+	require.NoError(t, err)
+	assert.Equal(t, LeastOutstanding, policy)
+	_, err = ParsePolicy("fastest")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"http://a:1", "http://b:2"}, ParseTargets(" http://a:1, ,http://b:2"))
+	_, err = New([]string{"localhost:8080"}, RoundRobin)
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HealthPort and HealthPath locate an instance's readiness endpoint on its host
+const (
+	HealthPort = "9001"
+	HealthPath = "/actuator/health/readiness"
+)
+
+// DefaultHealthURL returns the readiness endpoint of a target on the management port
+func DefaultHealthURL(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s:%s%s", u.Scheme, u.Hostname(), HealthPort, HealthPath)
+}
+
+// Outage is a period in which an instance failed requests or health probes
+type Outage struct {
+	Instance       string    `json:"instance"`
+	Start          time.Time `json:"start"`                // First failed request or probe
+	LastError      time.Time `json:"last_error,omitempty"` // Last failed request
+	FailedRequests uint64    `json:"failed_requests"`
+	Ejected        time.Time `json:"ejected,omitempty"` // Zero when the probes never failed
+	End            time.Time `json:"end,omitempty"`     // Readmitted, zero while ongoing
+}
+
+// TimeToDetect returns how long the instance kept receiving requests after its first
+// failure, zero when it was never ejected
+func (o Outage) TimeToDetect() time.Duration {
+	if o.Ejected.IsZero() {
+		return 0
+	}
+	return o.Ejected.Sub(o.Start)
+}
+
+// ErrorWindow returns how long clients saw requests fail on the instance
+func (o Outage) ErrorWindow() time.Duration {
+	if o.LastError.IsZero() {
+		return 0
+	}
+	return o.LastError.Sub(o.Start)
+}
+
+// Duration returns how long the outage lasted, up to now when ongoing
+func (o Outage) Duration() time.Duration {
+	if o.End.IsZero() {
+		return time.Since(o.Start)
+	}
+	return o.End.Sub(o.Start)
+}
+
+// recordFailure records a request the instance failed
+func (in *instance) recordFailure(now time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.begin(now)
+	in.outage.FailedRequests++
+	in.outage.LastError = now
+}
+
+// recordSuccess ends the outage on a request the instance served, unless a failed
+// probe ejected it: then only a successful probe readmits it. Without health checks
+// this is what ends an outage.
+func (in *instance) recordSuccess(now time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if !in.ejected.Load() {
+		in.end(now)
+	}
+}
+
+// begin starts an outage unless one is ongoing, in.mu must be held
+func (in *instance) begin(now time.Time) {
+	if in.outage == nil {
+		in.outage = &Outage{Instance: in.url.String(), Start: now}
+	}
+}
+
+// recordProbe ejects the instance when its probe failed and readmits it, ending the
+// outage, when it succeeded
+func (in *instance) recordProbe(healthy bool, now time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if !healthy {
+		in.begin(now)
+		if !in.ejected.Load() {
+			in.outage.Ejected = now
+			in.ejected.Store(true)
+		}
+		return
+	}
+	in.ejected.Store(false)
+	in.end(now)
+}
+
+// end ends the ongoing outage, if any, in.mu must be held
+func (in *instance) end(now time.Time) {
+	if in.outage != nil {
+		in.outage.End = now
+		in.outages = append(in.outages, *in.outage)
+		in.outage = nil
+	}
+}
+
+// StartHealthChecks probes every instance's health URL at the interval until the
+// context is done. An instance is ejected on its first failed probe and readmitted
+// on its first successful one.
+func (b *Balancer) StartHealthChecks(ctx context.Context, interval time.Duration, healthURLs []string) error {
+	if len(healthURLs) != len(b.instances) {
+		return fmt.Errorf("%d health URLs for %d targets", len(healthURLs), len(b.instances))
+	}
+	client := &http.Client{Timeout: interval}
+	for i, in := range b.instances {
+		go func(in *instance, healthURL string) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				in.recordProbe(probe(ctx, client, healthURL), time.Now())
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(in, healthURLs[i])
+	}
+	return nil
+}
+
+// probe reports whether the health URL answers 200
+func probe(ctx context.Context, client *http.Client, healthURL string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode == http.StatusOK
+}
+
+// Availability is how the balancer coped with unavailable instances
+type Availability struct {
+	Retries   uint64   `json:"retries"`   // Safe reads retried on another instance
+	Recovered uint64   `json:"recovered"` // Retried reads that succeeded
+	Outages   []Outage `json:"outages"`
+}
+
+// Availability returns the retry counts so far and the outages ongoing or ended after since
+func (b *Balancer) Availability(since time.Time) Availability {
+	availability := Availability{Retries: b.retries.Load(), Recovered: b.recovered.Load()}
+	for _, in := range b.instances {
+		in.mu.Lock()
+		for _, outage := range in.outages {
+			if outage.End.After(since) {
+				availability.Outages = append(availability.Outages, outage)
+			}
+		}
+		if in.outage != nil {
+			availability.Outages = append(availability.Outages, *in.outage)
+		}
+		in.mu.Unlock()
+	}
+	sort.Slice(availability.Outages, func(i, j int) bool {
+		return availability.Outages[i].Start.Before(availability.Outages[j].Start)
+	})
+	return availability
+}
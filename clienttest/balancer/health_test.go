@@ -0,0 +1,136 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// killableInstance is a fake app instance whose requests and health probe fail while it is down
+type killableInstance struct {
+	down   atomic.Bool
+	server *httptest.Server
+}
+
+func newKillableInstance(t *testing.T, name string) *killableInstance {
+	k := &killableInstance{}
+	k.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if k.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(k.server.Close)
+	return k
+}
+
+func TestFailoverAndReadmission(t *testing.T) {
+	a, b := newKillableInstance(t, "a"), newKillableInstance(t, "b")
+	lb, err := New([]string{a.server.URL, b.server.URL}, RoundRobin)
+	require.NoError(t, err)
+	client := lb.Client()
+	started := time.Now()
+
+	// Before a probe notices, reads on a are retried on b but writes fail
+	a.down.Store(true)
+	assert.Equal(t, "b", send(t, client, "GET", "/accounts", ""))
+	failed := 0
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post("http://localhost:8080/accounts/transfer", "application/json", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed, "transfers are not retried")
+
+	time.Sleep(time.Millisecond)
+	lb.instances[0].recordProbe(false, time.Now())
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "b", send(t, client, "GET", "/accounts", ""))
+	}
+
+	a.down.Store(false)
+	lb.instances[0].recordProbe(true, time.Now())
+	assert.Equal(t, "a", send(t, client, "GET", "/accounts", ""))
+
+	availability := lb.Availability(started)
+	assert.Equal(t, uint64(1), availability.Retries)
+	assert.Equal(t, uint64(1), availability.Recovered)
+	require.Len(t, availability.Outages, 1)
+	outage := availability.Outages[0]
+	assert.Equal(t, a.server.URL, outage.Instance)
+	assert.Equal(t, uint64(2), outage.FailedRequests)
+	assert.Greater(t, outage.TimeToDetect(), outage.ErrorWindow())
+	assert.False(t, outage.End.IsZero())
+
+	assert.Empty(t, lb.Availability(time.Now()).Outages, "ended before")
+}
+
+func TestHealthChecksEjectAndReadmit(t *testing.T) {
+	a, b := newKillableInstance(t, "a"), newKillableInstance(t, "b")
+	lb, err := New([]string{a.server.URL, b.server.URL}, RoundRobin)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, lb.StartHealthChecks(ctx, 10*time.Millisecond, []string{a.server.URL + "/health", b.server.URL + "/health"}))
+
+	a.down.Store(true)
+	require.Eventually(t, func() bool { return lb.instances[0].ejected.Load() }, time.Second, 5*time.Millisecond)
+	assert.False(t, lb.instances[1].ejected.Load())
+	a.down.Store(false)
+	require.Eventually(t, func() bool { return !lb.instances[0].ejected.Load() }, time.Second, 5*time.Millisecond)
+
+	outages := lb.Availability(time.Time{}).Outages
+	require.Len(t, outages, 1)
+	assert.Zero(t, outages[0].FailedRequests)
+	assert.Zero(t, outages[0].TimeToDetect(), "the probe failed first")
+
+	assert.Error(t, lb.StartHealthChecks(ctx, time.Second, []string{"http://a"}))
+}
+
+func TestOutageEndsOnSuccessWithoutHealthChecks(t *testing.T) {
+	a, b := newKillableInstance(t, "a"), newKillableInstance(t, "b")
+	lb, err := New([]string{a.server.URL, b.server.URL}, RoundRobin)
+	require.NoError(t, err)
+	client := lb.Client()
+
+	a.down.Store(true)
+	assert.Equal(t, "b", send(t, client, "GET", "/accounts", ""), "a fails, retried on b")
+	assert.Equal(t, "b", send(t, client, "GET", "/accounts", ""))
+	require.Len(t, lb.Availability(time.Time{}).Outages, 1)
+	assert.True(t, lb.Availability(time.Time{}).Outages[0].End.IsZero(), "ongoing")
+
+	a.down.Store(false)
+	assert.Equal(t, "a", send(t, client, "GET", "/accounts", ""))
+	outages := lb.Availability(time.Time{}).Outages
+	require.Len(t, outages, 1)
+	assert.False(t, outages[0].End.IsZero(), "the first request a served ended it")
+	assert.Empty(t, lb.Availability(time.Now()).Outages)
+}
+
+func TestEveryInstanceDownFailsOpen(t *testing.T) {
+	a := newKillableInstance(t, "a")
+	lb, err := New([]string{a.server.URL}, RoundRobin)
+	require.NoError(t, err)
+	a.down.Store(true)
+	lb.instances[0].recordProbe(false, time.Now())
+
+	resp, err := lb.Client().Get("http://localhost:8080/accounts")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Zero(t, lb.Availability(time.Time{}).Retries, "no other instance to retry on")
+}
+
+func TestDefaultHealthURL(t *testing.T) {
+	assert.Equal(t, "http://10.0.0.2:9001/actuator/health/readiness", DefaultHealthURL("http://10.0.0.2:8081"))
+}
@@ -0,0 +1,145 @@
+// Package action models customer journeys as sequences of actions. Every action
+// checks its preconditions against the session, performs one operation against the
+// bank and updates the state the session expects the bank to be in, so journeys can
+// assert read-your-writes instead of firing isolated requests.
+package action
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"com.ndnhuy.mybank/domain"
+)
+
+// Action is one step of a journey
+type Action interface {
+	// Name identifies the action in metrics, e.g. "transfer"
+	Name() string
+	// Precondition returns why the action cannot run in the session, nil when it can
+	Precondition(s *Session) error
+	// Execute performs the action against the bank. It returns an *ExpectationError
+	// when the bank answered but not with the state the session expects.
+	Execute(s *Session) error
+	// Apply updates the session's expected state after a successful Execute
+	Apply(s *Session)
+}
+
+// Bank is what actions need from the bank besides a customer's own account
+type Bank interface {
+	OpenAccount(alias string, initialBalance float64) (*domain.Customer, error)
+	ListAccounts() ([]domain.AccountInfo, error)
+}
+
+// HTTPBank is the bank served at utils.BASE_URL
+type HTTPBank struct{}
+
+func (HTTPBank) OpenAccount(alias string, initialBalance float64) (*domain.Customer, error) {
+	return domain.NewCustomerWithAmount(alias, initialBalance)
+}
+
+func (HTTPBank) ListAccounts() ([]domain.AccountInfo, error) {
+	return domain.ListAccounts()
+}
+
+// ErrPrecondition is wrapped by every precondition failure
+var ErrPrecondition = errors.New("precondition not met")
+
+// ExpectationError reports that the bank answered with a state other than expected,
+// e.g. a balance that doesn't reflect the session's own transfer yet
+type ExpectationError struct {
+	Action   string
+	Expected string
+	Actual   string
+}
+
+func (e *ExpectationError) Error() string {
+	return fmt.Sprintf("%s: expected %s, got %s", e.Action, e.Expected, e.Actual)
+}
+
+// Session is the state one simulated user carries through a journey
+type Session struct {
+	ID   int
+	Bank Bank
+	Pool *Pool
+	Rand *rand.Rand
+
+	Customer      *domain.Customer // Opened by the journey, nil until then
+	LastBalance   float64          // Last balance read from the bank
+	BalanceReads  int
+	TransferCount int
+	Transferred   float64 // Total the session's customer sent
+}
+
+// NewSession creates the session of one journey execution
+func NewSession(id int, bank Bank, pool *Pool, seed int64) *Session {
+	return &Session{
+		ID:   id,
+		Bank: bank,
+		Pool: pool,
+		Rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Pool holds the customers journeys transfer money to. Customers opened by a journey
+// join the pool once the journey ends, so no other session writes to an account
+// while its owner still expects to read its own writes.
+type Pool struct {
+	mu           sync.Mutex
+	customers    []*domain.Customer
+	initialTotal float64 // Money the customers started with, opened accounts included
+}
+
+// NewPool creates a pool of the given customers
+func NewPool(customers []*domain.Customer) *Pool {
+	pool := &Pool{customers: append([]*domain.Customer(nil), customers...)}
+	for _, customer := range customers {
+		pool.initialTotal += customer.ExpectedBalance()
+	}
+	return pool
+}
+
+// Opened records the initial balance of an account a journey opened, before the
+// account joins the pool
+func (p *Pool) Opened(initialBalance float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.initialTotal += initialBalance
+}
+
+// InitialTotal returns the money the customers started with. Transfers between them
+// neither create nor destroy any, so it is what their balances must add up to.
+func (p *Pool) InitialTotal() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.initialTotal
+}
+
+// Add adds a customer to the pool
+func (p *Pool) Add(customer *domain.Customer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.customers = append(p.customers, customer)
+}
+
+// Pick returns a random customer other than exclude, or nil when there is none
+func (p *Pool) Pick(rng *rand.Rand, exclude *domain.Customer) *domain.Customer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	candidates := len(p.customers)
+	for attempt := 0; attempt < 2*candidates; attempt++ {
+		customer := p.customers[rng.Intn(candidates)]
+		if customer != exclude {
+			return customer
+		}
+	}
+	return nil
+}
+
+// Customers returns every customer of the pool
+func (p *Pool) Customers() []*domain.Customer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*domain.Customer(nil), p.customers...)
+}
@@ -0,0 +1,134 @@
+package action
+
+import (
+	"fmt"
+	"math"
+)
+
+// balanceTolerance absorbs float rounding between the client's ledger and the bank
+const balanceTolerance = 0.005
+
+// OpenAccount opens the session's account with an initial balance
+type OpenAccount struct {
+	InitialBalance float64
+}
+
+func (a OpenAccount) Name() string { return "open-account" }
+
+func (a OpenAccount) Precondition(s *Session) error {
+	if s.Customer != nil {
+		return fmt.Errorf("%w: session %d already has an account", ErrPrecondition, s.ID)
+	}
+	if a.InitialBalance <= 0 {
+		return fmt.Errorf("%w: initial balance must be positive", ErrPrecondition)
+	}
+	return nil
+}
+
+func (a OpenAccount) Execute(s *Session) error {
+	customer, err := s.Bank.OpenAccount(fmt.Sprintf("journey-%d", s.ID), a.InitialBalance)
+	if err != nil {
+		return err
+	}
+	s.Pool.Opened(a.InitialBalance)
+	s.Customer = customer
+	return nil
+}
+
+func (a OpenAccount) Apply(s *Session) {
+	s.LastBalance = a.InitialBalance
+}
+
+// CheckBalance reads the session's balance and expects it to reflect every write the
+// session made so far (read-your-writes)
+type CheckBalance struct{}
+
+func (a CheckBalance) Name() string { return "check-balance" }
+
+func (a CheckBalance) Precondition(s *Session) error {
+	if s.Customer == nil {
+		return fmt.Errorf("%w: no account opened", ErrPrecondition)
+	}
+	return nil
+}
+
+func (a CheckBalance) Execute(s *Session) error {
+	balance, err := s.Customer.GetCurrentBalance()
+	if err != nil {
+		return err
+	}
+	s.BalanceReads++
+	s.LastBalance = balance
+	if expected := s.Customer.ExpectedBalance(); math.Abs(balance-expected) > balanceTolerance {
+		return &ExpectationError{
+			Action:   a.Name(),
+			Expected: fmt.Sprintf("balance %.2f", expected),
+			Actual:   fmt.Sprintf("%.2f", balance),
+		}
+	}
+	return nil
+}
+
+func (a CheckBalance) Apply(s *Session) {}
+
+// Transfer sends an amount from the session's account to a customer of the pool
+type Transfer struct {
+	Amount float64
+}
+
+func (a Transfer) Name() string { return "transfer" }
+
+func (a Transfer) Precondition(s *Session) error {
+	if s.Customer == nil {
+		return fmt.Errorf("%w: no account opened", ErrPrecondition)
+	}
+	if expected := s.Customer.ExpectedBalance(); expected < a.Amount {
+		return fmt.Errorf("%w: balance %.2f cannot cover %.2f", ErrPrecondition, expected, a.Amount)
+	}
+	return nil
+}
+
+func (a Transfer) Execute(s *Session) error {
+	to := s.Pool.Pick(s.Rand, s.Customer)
+	if to == nil {
+		return fmt.Errorf("no customer to transfer to")
+	}
+	// TransferMoney records the transfer in both ledgers once the bank accepted it
+	return s.Customer.TransferMoney(to, a.Amount)
+}
+
+func (a Transfer) Apply(s *Session) {
+	s.TransferCount++
+	s.Transferred += a.Amount
+}
+
+// ListAccounts lists the bank's accounts and expects the session's account among them
+type ListAccounts struct{}
+
+func (a ListAccounts) Name() string { return "list-accounts" }
+
+func (a ListAccounts) Precondition(s *Session) error {
+	return nil
+}
+
+func (a ListAccounts) Execute(s *Session) error {
+	accounts, err := s.Bank.ListAccounts()
+	if err != nil {
+		return err
+	}
+	if s.Customer == nil {
+		return nil
+	}
+	for _, account := range accounts {
+		if account.ID == s.Customer.GetAccountID() {
+			return nil
+		}
+	}
+	return &ExpectationError{
+		Action:   a.Name(),
+		Expected: fmt.Sprintf("account %s listed", s.Customer.GetAccountID()),
+		Actual:   fmt.Sprintf("%d accounts without it", len(accounts)),
+	}
+}
+
+func (a ListAccounts) Apply(s *Session) {}
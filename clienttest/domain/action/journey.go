@@ -0,0 +1,223 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Journey is a named sequence of actions one session performs in order
+type Journey struct {
+	Name    string
+	Actions []Action
+}
+
+// DefaultJourney opens an account, checks its balance, transfers, re-checks the
+// balance expecting the transfer to show and lists the accounts
+func DefaultJourney() Journey {
+	return Journey{
+		Name: "open-transfer-recheck",
+		Actions: []Action{
+			OpenAccount{InitialBalance: 100},
+			CheckBalance{},
+			Transfer{Amount: 1},
+			CheckBalance{},
+			ListAccounts{},
+		},
+	}
+}
+
+// Outcome classifies how an action ended
+type Outcome string
+
+const (
+	OutcomeSuccess   Outcome = "success"
+	OutcomeFailed    Outcome = "failed"    // The request failed
+	OutcomeViolation Outcome = "violation" // The bank answered with an unexpected state
+	OutcomeSkipped   Outcome = "skipped"   // A precondition was not met
+)
+
+// ActionResult is the outcome of one executed (or skipped) action
+type ActionResult struct {
+	Journey string
+	Action  string
+	Session int
+	Start   time.Time
+	Latency time.Duration
+	Outcome Outcome
+	Err     error
+}
+
+// ActionStats are the metrics of one action across every session
+type ActionStats struct {
+	Executed     uint64        `json:"executed"`
+	Succeeded    uint64        `json:"succeeded"`
+	Failed       uint64        `json:"failed"`
+	Violations   uint64        `json:"violations"`
+	Skipped      uint64        `json:"skipped"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MaxLatency   time.Duration `json:"max_latency"`
+}
+
+// MeanLatency returns the mean latency of the executed actions
+func (s ActionStats) MeanLatency() time.Duration {
+	if s.Executed == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Executed)
+}
+
+func (s *ActionStats) add(result ActionResult) {
+	switch result.Outcome {
+	case OutcomeSkipped:
+		s.Skipped++
+		return
+	case OutcomeSuccess:
+		s.Succeeded++
+	case OutcomeFailed:
+		s.Failed++
+	case OutcomeViolation:
+		s.Violations++
+	}
+	s.Executed++
+	s.TotalLatency += result.Latency
+	if result.Latency > s.MaxLatency {
+		s.MaxLatency = result.Latency
+	}
+}
+
+// Stats are the metrics of a journey run
+type Stats struct {
+	Journeys  uint64                  `json:"journeys"`
+	Completed uint64                  `json:"completed"` // Every action succeeded
+	Aborted   uint64                  `json:"aborted"`
+	Actions   map[string]*ActionStats `json:"actions"`
+}
+
+// ActionNames returns the names of the recorded actions, sorted
+func (s Stats) ActionNames() []string {
+	names := make([]string, 0, len(s.Actions))
+	for name := range s.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Runner executes a journey concurrently, one session per iteration of each worker
+type Runner struct {
+	Journey Journey
+	Bank    Bank
+	Pool    *Pool
+	Workers int
+
+	// OnResult is called with every action result, one at a time
+	OnResult func(ActionResult)
+
+	nextSession atomic.Int64
+	mu          sync.Mutex // Guards stats and serializes OnResult
+	stats       Stats
+}
+
+// NewRunner creates a runner of the journey with the given number of workers
+func NewRunner(journey Journey, bank Bank, pool *Pool, workers int) *Runner {
+	return &Runner{
+		Journey: journey,
+		Bank:    bank,
+		Pool:    pool,
+		Workers: workers,
+		stats:   Stats{Actions: map[string]*ActionStats{}},
+	}
+}
+
+// Run executes journeys on every worker until the context is done, and returns the stats
+func (r *Runner) Run(ctx context.Context) Stats {
+	var wg sync.WaitGroup
+	for i := 0; i < r.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				id := int(r.nextSession.Add(1))
+				r.RunJourney(NewSession(id, r.Bank, r.Pool, time.Now().UnixNano()+int64(id)))
+			}
+		}()
+	}
+	wg.Wait()
+	return r.Stats()
+}
+
+// RunJourney executes the journey's actions in order in the session, stopping at the
+// first action that does not succeed. It reports whether every action succeeded.
+func (r *Runner) RunJourney(s *Session) bool {
+	completed := true
+	for _, action := range r.Journey.Actions {
+		result := ActionResult{Journey: r.Journey.Name, Action: action.Name(), Session: s.ID, Start: time.Now()}
+		if err := action.Precondition(s); err != nil {
+			result.Outcome, result.Err = OutcomeSkipped, err
+		} else {
+			err := action.Execute(s)
+			result.Latency = time.Since(result.Start)
+			var expectation *ExpectationError
+			switch {
+			case err == nil:
+				result.Outcome = OutcomeSuccess
+				action.Apply(s)
+			case errors.As(err, &expectation):
+				result.Outcome, result.Err = OutcomeViolation, err
+			default:
+				result.Outcome, result.Err = OutcomeFailed, err
+			}
+		}
+		r.record(result)
+		if result.Outcome != OutcomeSuccess {
+			completed = false
+			break
+		}
+	}
+
+	// Nobody else writes to the session's account until now, see Pool
+	if s.Customer != nil {
+		r.Pool.Add(s.Customer)
+	}
+
+	r.mu.Lock()
+	r.stats.Journeys++
+	if completed {
+		r.stats.Completed++
+	} else {
+		r.stats.Aborted++
+	}
+	r.mu.Unlock()
+	return completed
+}
+
+func (r *Runner) record(result ActionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.stats.Actions[result.Action]
+	if !ok {
+		stats = &ActionStats{}
+		r.stats.Actions[result.Action] = stats
+	}
+	stats.add(result)
+	if r.OnResult != nil {
+		r.OnResult(result)
+	}
+}
+
+// Stats returns a copy of the stats recorded so far
+func (r *Runner) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Actions = make(map[string]*ActionStats, len(r.stats.Actions))
+	for name, action := range r.stats.Actions {
+		copied := *action
+		stats.Actions[name] = &copied
+	}
+	return stats
+}
@@ -0,0 +1,181 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBank keeps balances in memory. With dropCredits it acknowledges transfers but
+// only debits the sender, like a bank losing writes.
+type fakeBank struct {
+	mu          sync.Mutex
+	balances    map[string]float64
+	order       []string
+	dropCredits bool
+}
+
+func newFakeBank() *fakeBank {
+	return &fakeBank{balances: map[string]float64{}}
+}
+
+func (b *fakeBank) OpenAccount(alias string, initialBalance float64) (*domain.Customer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := fmt.Sprintf("acc-%d", len(b.order)+1)
+	b.balances[id] = initialBalance
+	b.order = append(b.order, id)
+	return domain.NewCustomerWithOperator(&fakeOperator{bank: b, id: id, name: alias}, initialBalance), nil
+}
+
+func (b *fakeBank) ListAccounts() ([]domain.AccountInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	accounts := make([]domain.AccountInfo, 0, len(b.order))
+	for _, id := range b.order {
+		accounts = append(accounts, domain.AccountInfo{ID: id, Balance: b.balances[id]})
+	}
+	return accounts, nil
+}
+
+type fakeOperator struct {
+	bank *fakeBank
+	id   string
+	name string
+}
+
+func (o *fakeOperator) GetAccount(accountID string) (*domain.AccountInfo, error) {
+	o.bank.mu.Lock()
+	defer o.bank.mu.Unlock()
+	return &domain.AccountInfo{ID: accountID, Balance: o.bank.balances[accountID]}, nil
+}
+
+func (o *fakeOperator) GetAccountBalance() (float64, error) {
+	account, err := o.GetAccount(o.id)
+	if err != nil {
+		return 0, err
+	}
+	return account.Balance, nil
+}
+
+func (o *fakeOperator) CreateAccount() (*domain.AccountInfo, error) {
+	return nil, errors.New("accounts are opened through the bank")
+}
+
+func (o *fakeOperator) TransferTo(toUser domain.BankOperator, amount float64) error {
+	o.bank.mu.Lock()
+	defer o.bank.mu.Unlock()
+	if o.bank.balances[o.id] < amount {
+		return errors.New("insufficient funds")
+	}
+	o.bank.balances[o.id] -= amount
+	if !o.bank.dropCredits {
+		o.bank.balances[toUser.GetAccountId()] += amount
+	}
+	return nil
+}
+
+func (o *fakeOperator) GetAccountId() string { return o.id }
+func (o *fakeOperator) GetName() string      { return o.name }
+
+func newPool(t *testing.T, bank *fakeBank, size int) *Pool {
+	customers := make([]*domain.Customer, size)
+	for i := range customers {
+		customer, err := bank.OpenAccount(fmt.Sprintf("pool-%d", i), 100)
+		require.NoError(t, err)
+		customers[i] = customer
+	}
+	return NewPool(customers)
+}
+
+func TestRunJourneyCompletes(t *testing.T) {
+	bank := newFakeBank()
+	pool := newPool(t, bank, 2)
+	runner := NewRunner(DefaultJourney(), bank, pool, 1)
+
+	var actions []string
+	runner.OnResult = func(result ActionResult) { actions = append(actions, result.Action) }
+
+	s := NewSession(1, bank, pool, 1)
+	require.True(t, runner.RunJourney(s))
+	assert.Equal(t, []string{"open-account", "check-balance", "transfer", "check-balance", "list-accounts"}, actions)
+	assert.Equal(t, 99.0, s.LastBalance)
+	assert.Equal(t, 1, s.TransferCount)
+	assert.Len(t, pool.Customers(), 3, "the journey's customer joins the pool")
+	assert.Equal(t, 300.0, pool.InitialTotal(), "the pool's 200 and the opened account's 100")
+
+	stats := runner.Stats()
+	assert.Equal(t, uint64(1), stats.Completed)
+	assert.Equal(t, uint64(2), stats.Actions["check-balance"].Succeeded)
+}
+
+func TestRunJourneySkipsUnmetPrecondition(t *testing.T) {
+	bank := newFakeBank()
+	pool := newPool(t, bank, 1)
+	journey := Journey{Name: "overdraw", Actions: []Action{
+		OpenAccount{InitialBalance: 5},
+		Transfer{Amount: 10},
+		ListAccounts{},
+	}}
+	runner := NewRunner(journey, bank, pool, 1)
+
+	assert.False(t, runner.RunJourney(NewSession(1, bank, pool, 1)))
+	stats := runner.Stats()
+	assert.Equal(t, uint64(1), stats.Actions["transfer"].Skipped)
+	assert.Zero(t, stats.Actions["transfer"].Executed)
+	assert.Nil(t, stats.Actions["list-accounts"], "the journey aborts")
+	assert.Equal(t, uint64(1), stats.Aborted)
+}
+
+func TestCheckBalanceDetectsLostWrite(t *testing.T) {
+	bank := newFakeBank()
+	bank.dropCredits = true
+	pool := newPool(t, bank, 1)
+	s := NewSession(1, bank, pool, 1)
+	for _, a := range []Action{OpenAccount{InitialBalance: 100}, CheckBalance{}} {
+		require.NoError(t, a.Execute(s))
+		a.Apply(s)
+	}
+
+	// The pool customer's credit is lost, the session's own debit is not
+	receiver := pool.Customers()[0]
+	require.NoError(t, receiver.TransferMoney(s.Customer, 10))
+	err := CheckBalance{}.Execute(s)
+
+	var expectation *ExpectationError
+	require.ErrorAs(t, err, &expectation)
+	assert.Equal(t, "balance 110.00", expectation.Expected)
+	assert.Equal(t, "100.00", expectation.Actual)
+}
+
+func TestRunnerKeepsLedgersConsistent(t *testing.T) {
+	bank := newFakeBank()
+	pool := newPool(t, bank, 5)
+	runner := NewRunner(DefaultJourney(), bank, pool, 8)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	stats := runner.Run(ctx)
+
+	require.NotZero(t, stats.Journeys)
+	assert.Equal(t, stats.Journeys, stats.Completed)
+	for _, customer := range pool.Customers() {
+		assert.NoError(t, customer.VerifyBalance())
+	}
+}
+
+func TestPoolPickExcludes(t *testing.T) {
+	bank := newFakeBank()
+	pool := newPool(t, bank, 1)
+	only := pool.Customers()[0]
+	assert.Nil(t, pool.Pick(rand.New(rand.NewSource(1)), only))
+	assert.Equal(t, only, pool.Pick(rand.New(rand.NewSource(1)), nil))
+}
@@ -1,18 +1,26 @@
 package domain
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"sync"
 
+	"com.ndnhuy.mybank/mybankclient"
 	mybankerror "com.ndnhuy.mybank/mybankerror" // Adjust import path as needed
 	"com.ndnhuy.mybank/utils"
 )
 
+// HTTPClient sends the requests of every bank operator, e.g. through a balancer
+var HTTPClient = http.DefaultClient
+
+// bankClient returns the API client of the bank operators, built per call so it
+// follows HTTPClient
+func bankClient() *mybankclient.Client {
+	return mybankclient.New(utils.BASE_URL, mybankclient.WithHTTPClient(HTTPClient))
+}
+
 type BankOperatorImpl struct {
 	InitialBalance float64
 	accountId      string
@@ -28,28 +36,18 @@ func NewBankOperatorImpl(initialBalance float64, name string) *BankOperatorImpl
 	}
 }
 
-func (u *BankOperatorImpl) GetAccount(accountID string) (*AccountInfo, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/accounts/%s", utils.BASE_URL, accountID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get account failed with status: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var account AccountInfo
-	if err := json.Unmarshal(body, &account); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal account info: %w", err)
+// NewBankOperatorForAccount creates an operator of an account that already exists and
+// holds balance
+func NewBankOperatorForAccount(accountID string, balance float64, name string) *BankOperatorImpl {
+	return &BankOperatorImpl{
+		InitialBalance: balance,
+		accountId:      accountID,
+		name:           name,
 	}
+}
 
-	return &account, nil
+func (u *BankOperatorImpl) GetAccount(accountID string) (*AccountInfo, error) {
+	return bankClient().GetAccount(context.Background(), accountID)
 }
 
 func (u *BankOperatorImpl) GetAccountBalance() (float64, error) {
@@ -92,60 +90,11 @@ func (u *BankOperatorImpl) CreateAccount() (*AccountInfo, error) {
 }
 
 func (u *BankOperatorImpl) createAccountRequest() (*AccountInfo, error) {
-	// Create account with initial balance
-	req := CreateAccountRequest{
-		InitialBalance: u.InitialBalance,
-	}
-
-	reqBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal transfer request: %w", err)
-	}
-	resp, err := http.Post(utils.BASE_URL+"/accounts", "application/json", bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create account: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("create account failed with status: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var account AccountInfo
-	if err := json.Unmarshal(body, &account); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal account info: %w", err)
-	}
-	return &account, nil
+	return bankClient().CreateAccount(context.Background(), u.InitialBalance)
 }
 
 func (u *BankOperatorImpl) TransferTo(toUser BankOperator, amount float64) error {
-	transferReq := TransferRequest{
-		FromAccountID: u.accountId,
-		ToAccountID:   toUser.GetAccountId(),
-		Amount:        amount,
-	}
-
-	reqBody, err := json.Marshal(transferReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal transfer request: %w", err)
-	}
-
-	resp, err := http.Post(utils.BASE_URL+"/accounts/transfer", "application/json", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to perform transfer: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("transfer failed with status: %d", resp.StatusCode)
-	}
-
-	return nil
+	return bankClient().Transfer(context.Background(), u.accountId, toUser.GetAccountId(), amount)
 }
 
 func (u *BankOperatorImpl) GetAccountId() string {
@@ -155,3 +104,8 @@ func (u *BankOperatorImpl) GetAccountId() string {
 func (u *BankOperatorImpl) GetName() string {
 	return u.name
 }
+
+// ListAccounts returns every account of the bank
+func ListAccounts() ([]AccountInfo, error) {
+	return bankClient().ListAccounts(context.Background())
+}
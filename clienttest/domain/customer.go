@@ -1,16 +1,32 @@
 package domain
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
 
 type Customer struct {
 	initialBalance float64
 	operator       BankOperator
 
+	mu             sync.Mutex // Guards the ledger and session state, customers are shared by concurrent workers
 	balanceChanges []balanceChange
+	pending        []pendingChange // Changes of transfers not settled yet, see SubmitTransfer
+	session        sessionState
 }
 
 type balanceChange struct {
-	change float64 // positive for deposit, negative for withdrawal
+	change   float64   // positive for deposit, negative for withdrawal
+	balance  float64   // balance after the change
+	recorded time.Time // when the client learned about the change
+}
+
+func newCustomer(operator BankOperator, initialBalance float64) *Customer {
+	return &Customer{
+		operator:       operator,
+		initialBalance: initialBalance,
+	}
 }
 
 func NewCustomer(alias string) (*Customer, error) {
@@ -20,10 +36,7 @@ func NewCustomer(alias string) (*Customer, error) {
 		return nil, err
 	}
 
-	return &Customer{
-		operator:       operator,
-		initialBalance: operator.InitialBalance,
-	}, nil
+	return newCustomer(operator, operator.InitialBalance), nil
 }
 
 func NewCustomerWithAmount(alias string, initialAmount float64) (*Customer, error) {
@@ -33,10 +46,19 @@ func NewCustomerWithAmount(alias string, initialAmount float64) (*Customer, erro
 		return nil, err
 	}
 
-	return &Customer{
-		operator:       operator,
-		initialBalance: operator.InitialBalance,
-	}, nil
+	return newCustomer(operator, operator.InitialBalance), nil
+}
+
+// NewCustomerForAccount creates a customer of an existing account, e.g. one of a seeded
+// dataset, that holds balance
+func NewCustomerForAccount(alias, accountID string, balance float64) *Customer {
+	return newCustomer(NewBankOperatorForAccount(accountID, balance, alias), balance)
+}
+
+// NewCustomerWithOperator creates a customer for an operator whose account already
+// holds initialBalance
+func NewCustomerWithOperator(operator BankOperator, initialBalance float64) *Customer {
+	return newCustomer(operator, initialBalance)
 }
 
 func (c *Customer) TransferMoney(toCustomer *Customer, amount float64) error {
@@ -45,10 +67,7 @@ func (c *Customer) TransferMoney(toCustomer *Customer, amount float64) error {
 	if err != nil {
 		return err
 	} else {
-		// track balance changes
-		c.balanceChanges = append(c.balanceChanges, balanceChange{
-			change: -transferMoney, // negative for withdrawal
-		})
+		c.recordChange(-transferMoney)           // negative for withdrawal
 		toCustomer.onReceiveMoney(transferMoney) // notify recipient
 	}
 
@@ -61,18 +80,24 @@ func (c *Customer) RecordTransfer(toCustomer *Customer, amount float64) error {
 		return fmt.Errorf("invalid transfer parameters")
 	}
 
-	c.balanceChanges = append(c.balanceChanges, balanceChange{
-		change: -amount, // negative for withdrawal
-	})
+	c.recordChange(-amount)           // negative for withdrawal
 	toCustomer.onReceiveMoney(amount) // notify recipient
 
 	return nil
 }
 
 func (c *Customer) onReceiveMoney(amount float64) {
-	// track balance changes
+	c.recordChange(amount) // positive for deposit
+}
+
+// recordChange tracks a balance change the client knows the bank applied
+func (c *Customer) recordChange(change float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.balanceChanges = append(c.balanceChanges, balanceChange{
-		change: amount, // positive for deposit
+		change:   change,
+		balance:  c.balanceAt(len(c.balanceChanges)) + change,
+		recorded: time.Now(),
 	})
 }
 
@@ -81,11 +106,7 @@ func (c *Customer) VerifyBalance() error {
 	if err != nil {
 		return err // error occurred, cannot verify balance
 	}
-	// calculate expected balance based on recorded changes
-	expectedBalance := c.initialBalance
-	for _, change := range c.balanceChanges {
-		expectedBalance += change.change
-	}
+	expectedBalance := c.ExpectedBalance()
 	if actualBalance != expectedBalance {
 		return fmt.Errorf("[%v] balance mismatch: expected %.2f, got %.2f", c.operator.GetName(), expectedBalance, actualBalance)
 	} else {
@@ -94,14 +115,32 @@ func (c *Customer) VerifyBalance() error {
 	return nil
 }
 
+// ExpectedBalance returns the balance the customer should hold according to its
+// initial balance and recorded changes
+func (c *Customer) ExpectedBalance() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.balanceAt(len(c.balanceChanges))
+}
+
 // GetAccountID returns the customer's account ID for load testing
 func (c *Customer) GetAccountID() string {
 	return c.operator.GetAccountId()
 }
 
-// GetCurrentBalance returns the current balance from the bank
+// GetCurrentBalance returns the current balance from the bank and checks the read
+// against the customer's session guarantees
 func (c *Customer) GetCurrentBalance() (float64, error) {
-	return c.operator.GetAccountBalance()
+	c.mu.Lock()
+	required, started := len(c.balanceChanges), time.Now()
+	c.mu.Unlock()
+
+	balance, err := c.operator.GetAccountBalance()
+	if err != nil {
+		return 0, err
+	}
+	c.checkRead(balance, required, started)
+	return balance, nil
 }
 
 // GetName returns the customer's name/alias
@@ -1,18 +1,11 @@
 package domain 
 
+import "com.ndnhuy.mybank/mybankclient"
+
 // AccountInfo represents the account information returned by the API
-type AccountInfo struct {
-	ID      string  `json:"id"`
-	Balance float64 `json:"balance"`
-}
+type AccountInfo = mybankclient.Account
 
 // TransferRequest represents the transfer request payload
-type TransferRequest struct {
-	FromAccountID string  `json:"fromAccountId"`
-	ToAccountID   string  `json:"toAccountId"`
-	Amount        float64 `json:"amount"`
-}
+type TransferRequest = mybankclient.TransferRequest
 
-type CreateAccountRequest struct {
-	InitialBalance float64 `json:"initialBalance"`
-}
+type CreateAccountRequest = mybankclient.CreateAccountRequest
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// balanceTolerance absorbs float rounding when matching a read to a state of the ledger
+const balanceTolerance = 0.005
+
+// SessionGuarantees counts how a customer's balance reads kept the session guarantees.
+// The customer's session covers every change the client recorded for it: transfers it
+// sent and transfers it received from other customers.
+type SessionGuarantees struct {
+	Reads                    uint64        `json:"reads"`
+	ReadYourWritesViolations uint64        `json:"read_your_writes_violations"` // The read missed a change recorded before it started
+	MonotonicReadViolations  uint64        `json:"monotonic_read_violations"`   // The read reflected an older state than an earlier read
+	UnknownReads             uint64        `json:"unknown_reads"`               // The read matched no state of the ledger
+	TotalStaleness           time.Duration `json:"total_staleness"`
+	MaxStaleness             time.Duration `json:"max_staleness"`
+}
+
+// MeanStaleness returns how far behind read-your-writes violations were on average,
+// measured from the oldest change a read missed to the start of the read
+func (g SessionGuarantees) MeanStaleness() time.Duration {
+	if g.ReadYourWritesViolations == 0 {
+		return 0
+	}
+	return g.TotalStaleness / time.Duration(g.ReadYourWritesViolations)
+}
+
+// Violations returns the number of reads that broke a guarantee
+func (g SessionGuarantees) Violations() uint64 {
+	return g.ReadYourWritesViolations + g.MonotonicReadViolations
+}
+
+// Merge adds the counts of other, e.g. to sum up every customer of a run
+func (g *SessionGuarantees) Merge(other SessionGuarantees) {
+	g.Reads += other.Reads
+	g.ReadYourWritesViolations += other.ReadYourWritesViolations
+	g.MonotonicReadViolations += other.MonotonicReadViolations
+	g.UnknownReads += other.UnknownReads
+	g.TotalStaleness += other.TotalStaleness
+	if other.MaxStaleness > g.MaxStaleness {
+		g.MaxStaleness = other.MaxStaleness
+	}
+}
+
+// sessionState tracks the newest ledger state a customer's reads reflected.
+// State 0 is the initial balance and state i the balance after the i-th change.
+type sessionState struct {
+	lastObserved int
+	guarantees   SessionGuarantees
+}
+
+// checkRead matches a balance read to the newest ledger state holding that balance
+// and checks it against the state required when the read started and the state
+// earlier reads observed. Matching the newest state never reports a violation when
+// the balance went back to an earlier value, at the cost of missing some.
+func (c *Customer) checkRead(balance float64, required int, started time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g := &c.session.guarantees
+	g.Reads++
+
+	observed := -1
+	for state := len(c.balanceChanges); state >= 0; state-- {
+		if math.Abs(c.balanceAt(state)-balance) <= balanceTolerance {
+			observed = state
+			break
+		}
+	}
+	if observed < 0 {
+		// A change the bank applied but the client did not record yet, or a lost write
+		g.UnknownReads++
+		return
+	}
+
+	if observed < required {
+		g.ReadYourWritesViolations++
+		staleness := started.Sub(c.balanceChanges[observed].recorded)
+		g.TotalStaleness += staleness
+		if staleness > g.MaxStaleness {
+			g.MaxStaleness = staleness
+		}
+	}
+	if observed < c.session.lastObserved {
+		g.MonotonicReadViolations++
+	} else {
+		c.session.lastObserved = observed
+	}
+}
+
+// balanceAt returns the balance of a ledger state, c.mu must be held
+func (c *Customer) balanceAt(state int) float64 {
+	if state == 0 {
+		return c.initialBalance
+	}
+	return c.balanceChanges[state-1].balance
+}
+
+// SessionGuarantees returns the session guarantee counts of the customer's reads so far
+func (c *Customer) SessionGuarantees() SessionGuarantees {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session.guarantees
+}
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// laggingBank applies transfers on a primary right away. Reads go to a replica that
+// only sees writes older than lag, or alternate between primary and replica when
+// alternate is set.
+type laggingBank struct {
+	mu        sync.Mutex
+	lag       time.Duration
+	alternate bool
+	reads     int
+	writes    map[string][]write
+}
+
+type write struct {
+	at      time.Time
+	balance float64
+}
+
+func newLaggingBank(lag time.Duration) *laggingBank {
+	return &laggingBank{lag: lag, writes: map[string][]write{}}
+}
+
+func (b *laggingBank) open(id string, balance float64) *Customer {
+	b.writes[id] = []write{{at: time.Now().Add(-time.Hour), balance: balance}}
+	return NewCustomerWithOperator(&laggingOperator{bank: b, id: id}, balance)
+}
+
+// balance returns the newest balance written at least lag ago
+func (b *laggingBank) balance(id string, lag time.Duration) float64 {
+	writes := b.writes[id]
+	cutoff := time.Now().Add(-lag)
+	for i := len(writes) - 1; i > 0; i-- {
+		if !writes[i].at.After(cutoff) {
+			return writes[i].balance
+		}
+	}
+	return writes[0].balance
+}
+
+type laggingOperator struct {
+	bank *laggingBank
+	id   string
+}
+
+func (o *laggingOperator) GetAccount(accountID string) (*AccountInfo, error) {
+	o.bank.mu.Lock()
+	defer o.bank.mu.Unlock()
+	lag := o.bank.lag
+	if o.bank.alternate && o.bank.reads%2 == 0 {
+		lag = 0
+	}
+	o.bank.reads++
+	return &AccountInfo{ID: accountID, Balance: o.bank.balance(accountID, lag)}, nil
+}
+
+func (o *laggingOperator) GetAccountBalance() (float64, error) {
+	account, err := o.GetAccount(o.id)
+	if err != nil {
+		return 0, err
+	}
+	return account.Balance, nil
+}
+
+func (o *laggingOperator) CreateAccount() (*AccountInfo, error) {
+	return &AccountInfo{ID: o.id}, nil
+}
+
+func (o *laggingOperator) TransferTo(toUser BankOperator, amount float64) error {
+	o.bank.mu.Lock()
+	defer o.bank.mu.Unlock()
+	now := time.Now()
+	for id, change := range map[string]float64{o.id: -amount, toUser.GetAccountId(): amount} {
+		o.bank.writes[id] = append(o.bank.writes[id], write{at: now, balance: o.bank.balance(id, 0) + change})
+	}
+	return nil
+}
+
+func (o *laggingOperator) GetAccountId() string { return o.id }
+func (o *laggingOperator) GetName() string      { return o.id }
+
+func TestReadYourWritesViolation(t *testing.T) {
+	bank := newLaggingBank(50 * time.Millisecond)
+	alice, bob := bank.open("alice", 100), bank.open("bob", 100)
+	require.NoError(t, alice.TransferMoney(bob, 10))
+	time.Sleep(5 * time.Millisecond)
+
+	// The replica doesn't have the transfer yet
+	balance, err := alice.GetCurrentBalance()
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balance)
+
+	// The receiver's session covers the transfer too
+	_, err = bob.GetCurrentBalance()
+	require.NoError(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+	balance, err = alice.GetCurrentBalance()
+	require.NoError(t, err)
+	assert.Equal(t, 90.0, balance)
+
+	g := alice.SessionGuarantees()
+	assert.Equal(t, uint64(2), g.Reads)
+	assert.Equal(t, uint64(1), g.ReadYourWritesViolations)
+	assert.Zero(t, g.MonotonicReadViolations)
+	assert.GreaterOrEqual(t, g.MaxStaleness, 5*time.Millisecond)
+	assert.Less(t, g.MaxStaleness, 50*time.Millisecond)
+	assert.Equal(t, uint64(1), bob.SessionGuarantees().ReadYourWritesViolations)
+}
+
+func TestMonotonicReadViolation(t *testing.T) {
+	bank := newLaggingBank(time.Hour)
+	bank.alternate = true
+	alice, bob := bank.open("alice", 100), bank.open("bob", 100)
+	require.NoError(t, alice.TransferMoney(bob, 10))
+
+	// Primary first, then the replica which goes back to before the transfer
+	for _, want := range []float64{90, 100, 90} {
+		balance, err := alice.GetCurrentBalance()
+		require.NoError(t, err)
+		assert.Equal(t, want, balance)
+	}
+
+	g := alice.SessionGuarantees()
+	assert.Equal(t, uint64(1), g.MonotonicReadViolations)
+	assert.Equal(t, uint64(1), g.ReadYourWritesViolations)
+	assert.Equal(t, uint64(2), g.Violations())
+}
+
+func TestUnknownRead(t *testing.T) {
+	bank := newLaggingBank(0)
+	alice := bank.open("alice", 100)
+	bank.writes["alice"] = append(bank.writes["alice"], write{at: time.Now(), balance: 42})
+
+	_, err := alice.GetCurrentBalance()
+	require.NoError(t, err)
+	g := alice.SessionGuarantees()
+	assert.Equal(t, uint64(1), g.UnknownReads)
+	assert.Zero(t, g.Violations())
+}
+
+func TestSessionGuaranteesMerge(t *testing.T) {
+	total := SessionGuarantees{Reads: 2, ReadYourWritesViolations: 1, TotalStaleness: 10 * time.Millisecond, MaxStaleness: 10 * time.Millisecond}
+	total.Merge(SessionGuarantees{Reads: 3, ReadYourWritesViolations: 1, TotalStaleness: 30 * time.Millisecond, MaxStaleness: 30 * time.Millisecond})
+	assert.Equal(t, uint64(5), total.Reads)
+	assert.Equal(t, 20*time.Millisecond, total.MeanStaleness())
+	assert.Equal(t, 30*time.Millisecond, total.MaxStaleness)
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// SettlementState is where a transfer stands from the client's view. A transfer across
+// shards runs as a saga or a two-phase commit, so its debit and credit may show up at
+// different times, or, after a partial failure, not at all.
+type SettlementState string
+
+const (
+	SettlementPending     SettlementState = "pending"     // Submitted, the outcome is not known yet
+	SettlementCommitted   SettlementState = "committed"   // Debited and credited
+	SettlementCompensated SettlementState = "compensated" // Failed and neither debited nor credited, or restored
+	// Anomalies: the transfer settled in a state that loses or creates money or never settled
+	SettlementStuck              SettlementState = "stuck"                // Acknowledged, but neither debited nor credited
+	SettlementDebitWithoutCredit SettlementState = "debit-without-credit" // Acknowledged and debited, never credited
+	SettlementCreditWithoutDebit SettlementState = "credit-without-debit"
+	SettlementCompensationFailed SettlementState = "compensation-failed" // Failed, but the debit was never restored
+)
+
+// SettlementStates lists every final state, anomalies last
+var SettlementStates = []SettlementState{SettlementCommitted, SettlementCompensated, SettlementStuck,
+	SettlementDebitWithoutCredit, SettlementCreditWithoutDebit, SettlementCompensationFailed}
+
+// Anomaly reports whether a transfer that settled in this state needs investigating
+func (s SettlementState) Anomaly() bool {
+	return s != SettlementPending && s != SettlementCommitted && s != SettlementCompensated
+}
+
+// PendingTransfer is a transfer submitted to the bank that the customers' ledgers don't
+// reflect yet. It is settled once the client observed which of its debit and credit
+// the bank applied.
+type PendingTransfer struct {
+	From, To     *Customer
+	Amount       float64
+	Submitted    time.Time
+	Acknowledged bool  // The bank confirmed the transfer
+	Err          error // Why the bank didn't confirm it, a rejection or a lost response
+
+	state   SettlementState
+	settled time.Time
+}
+
+// pendingChange is a change of a pending transfer, not part of the ledger yet
+type pendingChange struct {
+	transfer *PendingTransfer
+	change   float64
+}
+
+// SubmitTransfer sends a transfer to the bank and returns it pending. Its outcome is
+// inferred from the balances of both customers, so each of them must take part in no
+// other transfer until it is settled.
+func (c *Customer) SubmitTransfer(toCustomer *Customer, amount float64) *PendingTransfer {
+	transfer := &PendingTransfer{From: c, To: toCustomer, Amount: amount, Submitted: time.Now(), state: SettlementPending}
+	c.addPending(pendingChange{transfer, -amount})
+	toCustomer.addPending(pendingChange{transfer, amount})
+
+	transfer.Err = c.operator.TransferTo(toCustomer.operator, amount)
+	transfer.Acknowledged = transfer.Err == nil
+	return transfer
+}
+
+func (c *Customer) addPending(change pendingChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, change)
+}
+
+// removePending drops the pending change of a transfer
+func (c *Customer) removePending(transfer *PendingTransfer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, change := range c.pending {
+		if change.transfer == transfer {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// PendingTransfers returns how many of the customer's transfers are not settled yet
+func (c *Customer) PendingTransfers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// PollBalance reads the current balance from the bank without checking it against the
+// session guarantees, for balances a pending transfer may be changing
+func (c *Customer) PollBalance() (float64, error) {
+	return c.operator.GetAccountBalance()
+}
+
+// Applied reports which of the transfer's changes the balances read from both
+// customers reflect, compared to their ledgers
+func (t *PendingTransfer) Applied(fromBalance, toBalance float64) (debited, credited bool) {
+	debited = math.Abs(fromBalance-(t.From.ExpectedBalance()-t.Amount)) <= balanceTolerance
+	credited = math.Abs(toBalance-(t.To.ExpectedBalance()+t.Amount)) <= balanceTolerance
+	return debited, credited
+}
+
+// Settle ends the transfer in the state the observed changes imply and records the
+// applied changes in the customers' ledgers, so they keep matching the bank even
+// when the transfer lost or created money
+func (t *PendingTransfer) Settle(debited, credited bool) SettlementState {
+	switch {
+	case debited && credited:
+		t.state = SettlementCommitted
+	case debited && t.Acknowledged:
+		t.state = SettlementDebitWithoutCredit
+	case debited:
+		t.state = SettlementCompensationFailed
+	case credited:
+		t.state = SettlementCreditWithoutDebit
+	case t.Acknowledged:
+		t.state = SettlementStuck
+	default:
+		t.state = SettlementCompensated
+	}
+	t.settled = time.Now()
+
+	t.From.removePending(t)
+	t.To.removePending(t)
+	if debited {
+		t.From.recordChange(-t.Amount)
+	}
+	if credited {
+		t.To.recordChange(t.Amount)
+	}
+	return t.state
+}
+
+// State returns the transfer's state, SettlementPending until it is settled
+func (t *PendingTransfer) State() SettlementState {
+	return t.state
+}
+
+// TimeToSettle returns how long the transfer took from its submission to being settled
+func (t *PendingTransfer) TimeToSettle() time.Duration {
+	if t.settled.IsZero() {
+		return 0
+	}
+	return t.settled.Sub(t.Submitted)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// partialBank applies only the sides of a transfer it is told to and answers with err
+type partialBank struct {
+	balances      map[string]float64
+	debit, credit bool
+	err           error
+}
+
+type partialOperator struct {
+	bank *partialBank
+	id   string
+}
+
+func (b *partialBank) open(id string, balance float64) *Customer {
+	b.balances[id] = balance
+	return NewCustomerWithOperator(&partialOperator{bank: b, id: id}, balance)
+}
+
+func (o *partialOperator) GetAccount(accountID string) (*AccountInfo, error) {
+	return &AccountInfo{ID: accountID, Balance: o.bank.balances[accountID]}, nil
+}
+
+func (o *partialOperator) GetAccountBalance() (float64, error) {
+	return o.bank.balances[o.id], nil
+}
+
+func (o *partialOperator) CreateAccount() (*AccountInfo, error) {
+	return &AccountInfo{ID: o.id}, nil
+}
+
+func (o *partialOperator) TransferTo(toUser BankOperator, amount float64) error {
+	if o.bank.debit {
+		o.bank.balances[o.id] -= amount
+	}
+	if o.bank.credit {
+		o.bank.balances[toUser.GetAccountId()] += amount
+	}
+	return o.bank.err
+}
+
+func (o *partialOperator) GetAccountId() string { return o.id }
+func (o *partialOperator) GetName() string      { return o.id }
+
+func TestSettlementStates(t *testing.T) {
+	rejected := errors.New("transfer failed with status: 500")
+	for _, tc := range []struct {
+		debit, credit bool
+		err           error
+		want          SettlementState
+	}{
+		{true, true, nil, SettlementCommitted},
+		{false, false, rejected, SettlementCompensated},
+		{false, false, nil, SettlementStuck},
+		{true, false, nil, SettlementDebitWithoutCredit},
+		{false, true, nil, SettlementCreditWithoutDebit},
+		{true, false, rejected, SettlementCompensationFailed},
+	} {
+		bank := &partialBank{balances: map[string]float64{}, debit: tc.debit, credit: tc.credit, err: tc.err}
+		from, to := bank.open("a", 100), bank.open("b", 100)
+
+		transfer := from.SubmitTransfer(to, 10)
+		assert.Equal(t, SettlementPending, transfer.State())
+		assert.Equal(t, tc.err == nil, transfer.Acknowledged)
+		assert.Equal(t, 1, from.PendingTransfers())
+		assert.Equal(t, 100.0, from.ExpectedBalance(), "pending transfers are not in the ledger")
+
+		fromBalance, err := from.PollBalance()
+		require.NoError(t, err)
+		toBalance, err := to.PollBalance()
+		require.NoError(t, err)
+		debited, credited := transfer.Applied(fromBalance, toBalance)
+		assert.Equal(t, tc.want, transfer.Settle(debited, credited))
+		assert.Equal(t, tc.want != SettlementCommitted && tc.want != SettlementCompensated, tc.want.Anomaly())
+
+		// The ledgers follow what the bank applied
+		assert.Zero(t, from.PendingTransfers())
+		assert.Zero(t, to.PendingTransfers())
+		assert.NoError(t, from.VerifyBalance())
+		assert.NoError(t, to.VerifyBalance())
+		assert.Positive(t, transfer.TimeToSettle())
+	}
+}
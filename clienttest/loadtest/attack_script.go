@@ -1,6 +1,7 @@
 package loadtest
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -19,17 +20,27 @@ func AttackGetAccounts(rps, testDuration int) {
 	fmt.Printf("Press Ctrl+C to stop early if needed\n")
 	fmt.Printf("Tip: Set RPS=50 DURATION=60 to customize load parameters\n\n")
 
+	accountsBefore := countAccounts()
 	queueMetrics := NewQueueMetrics()
-	fmt.Printf("Attack in progress...")
+	run := newRun("get-accounts")
+	fmt.Printf("Attack in progress...\n")
 
 	// Create and use Attacker instance
-	attacker := NewAttacker("http://localhost:8080/accounts", "GET", rps, testDuration, queueMetrics.Metrics)
+	attacker := NewAttacker("http://localhost:8080/accounts", "GET", rps, testDuration, queueMetrics)
+	run.Attach(attacker)
 	attacker.Attack()
 	queueMetrics.Close()
-	fmt.Printf(" completed!\n\n")
+	run.Finish()
+	fmt.Printf("Attack completed!\n\n")
+
+	responseSize := NewResponseSizeGrowth(queueMetrics.TimeSeries().Snapshots())
+	if responseSize != nil {
+		responseSize.AccountsBefore, responseSize.AccountsAfter = accountsBefore, countAccounts()
+	}
 
 	// Print enhanced metrics report
 	queueMetrics.PrintReport()
+	printResponseSizeGrowth(responseSize)
 
 	// Save detailed report to file
 	fmt.Printf("\n=== Detailed Report ===\n")
@@ -42,11 +53,20 @@ func AttackGetAccounts(rps, testDuration int) {
 
 	timestamp := fmt.Sprintf("==== Run at %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
 	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, queueMetrics)
 
 	reporter := vegeta.NewTextReporter(queueMetrics.Metrics)
 	reporter(reportFile)
+	if responseSize != nil {
+		reportFile.WriteString(fmt.Sprintf("Response Size: %.0f -> %.0f bytes (%+.1f%%), Accounts: %d -> %d\n",
+			responseSize.First, responseSize.Last, responseSize.Growth*100, responseSize.AccountsBefore, responseSize.AccountsAfter))
+	}
 	reportFile.WriteString("\n\n")
 	fmt.Printf("Report appended to accounts_loadtest_report.txt\n")
+
+	report := run.Report(queueMetrics)
+	report.ResponseSize = responseSize
+	saveStructuredReport("accounts_loadtest_report", report)
 }
 
 // CustomerTransferTargeter creates transfer requests using customer behaviors
@@ -115,7 +135,8 @@ func AttackTransfers(rps, testDuration int) {
 	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
 
 	queueMetrics := NewQueueMetrics()
-	fmt.Printf("Transfer attack in progress...")
+	run := newRun("transfers")
+	fmt.Printf("Transfer attack in progress...\n")
 
 	// Create customer-based transfer attacker
 	transferTargeter := NewCustomerTransferTargeter(sourceCustomers, destCustomers)
@@ -123,21 +144,18 @@ func AttackTransfers(rps, testDuration int) {
 		targeter: transferTargeter,
 		rate:     vegeta.Rate{Freq: rps, Per: time.Second},
 		duration: time.Duration(testDuration) * time.Second,
-		attacker: vegeta.NewAttacker(),
-		metrics:  queueMetrics.Metrics,
+		attacker: newVegetaAttacker(),
+		metrics:  queueMetrics,
 	}
 
+	run.Attach(attacker)
 	attacker.Attack()
 	queueMetrics.Close()
-	fmt.Printf(" completed!\n\n")
+	fmt.Printf("Attack completed!\n\n")
 
-	finalTotal := verifyCustomerBalances(append(sourceCustomers, destCustomers...))
-	fmt.Printf("Final total balance: %.2f\n", finalTotal)
-	if abs(finalTotal-initialTotal) < 0.01 {
-		fmt.Printf("✅ Balance verification passed - no money lost or created\n")
-	} else {
-		fmt.Printf("❌ Balance verification failed - money discrepancy: %.2f\n", finalTotal-initialTotal)
-	}
+	verification := verifyTotalBalance(append(sourceCustomers, destCustomers...), initialTotal)
+	run.RecordVerification(verification)
+	run.Finish()
 
 	// Print enhanced metrics report
 	queueMetrics.PrintReport()
@@ -153,12 +171,15 @@ func AttackTransfers(rps, testDuration int) {
 
 	timestamp := fmt.Sprintf("==== Transfer Attack Run at %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
 	reportFile.WriteString(timestamp)
-	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, finalTotal))
+	writeInterruption(reportFile, queueMetrics)
+	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, verification.FinalTotal))
 
 	reporter := vegeta.NewTextReporter(queueMetrics.Metrics)
 	reporter(reportFile)
 	reportFile.WriteString("\n\n")
 	fmt.Printf("Report appended to transfer_attack_report.txt\n")
+
+	saveStructuredReport("transfer_attack_report", run.Report(queueMetrics))
 }
 
 // setupTransferCustomers creates test customers for transfer attacks
@@ -168,58 +189,104 @@ func setupTransferCustomers() (sourceCustomers, destCustomers []*domain.Customer
 	const initialBalance = 100.0
 
 	// Create source customers with money
-	for i := 0; i < numSourceCustomers; i++ {
-		customer, err := domain.NewCustomerWithAmount(fmt.Sprintf("source-%d", i), initialBalance)
-		if err != nil {
-			return nil, nil, 0, fmt.Errorf("failed to create source customer %d: %w", i, err)
-		}
-		sourceCustomers = append(sourceCustomers, customer)
-		totalBalance += initialBalance
+	sourceCustomers, err = createCustomers("source", numSourceCustomers, initialBalance)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	// Create destination customers with minimal money
-	for i := 0; i < numDestCustomers; i++ {
-		customer, err := domain.NewCustomerWithAmount(fmt.Sprintf("dest-%d", i), 1)
+	destCustomers, err = createCustomers("dest", numDestCustomers, 1)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return sourceCustomers, destCustomers, totalExpectedBalance(append(sourceCustomers, destCustomers...)), nil
+}
+
+// createCustomers creates count customers named <prefix>-<i>, each holding initialBalance,
+// or takes them from AccountDataset when set, each holding at least initialBalance
+func createCustomers(prefix string, count int, initialBalance float64) ([]*domain.Customer, error) {
+	if AccountDataset != nil {
+		return AccountDataset.Customers(context.Background(), bankClient(), prefix, count, initialBalance)
+	}
+	customers := make([]*domain.Customer, 0, count)
+	for i := 0; i < count; i++ {
+		customer, err := domain.NewCustomerWithAmount(fmt.Sprintf("%s-%d", prefix, i), initialBalance)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("failed to create dest customer %d: %w", i, err)
+			return nil, fmt.Errorf("failed to create %s customer %d: %w", prefix, i, err)
 		}
-		destCustomers = append(destCustomers, customer)
-		totalBalance += 1
+		customers = append(customers, customer)
 	}
-
-	return sourceCustomers, destCustomers, totalBalance, nil
+	return customers, nil
 }
 
-func verifyCustomerBalances(customers []*domain.Customer) float64 {
+// totalExpectedBalance returns the total of the customers' ledgers, before any transfer
+// their initial total
+func totalExpectedBalance(customers []*domain.Customer) float64 {
 	total := 0.0
-	allVerified := true
+	for _, customer := range customers {
+		total += customer.ExpectedBalance()
+	}
+	return total
+}
+
+// verifyTotalBalance verifies every customer's balance against its ledger and
+// that the customers' total balance still equals initialTotal
+func verifyTotalBalance(customers []*domain.Customer, initialTotal float64) BalanceVerification {
+	verification := verifyCustomerBalances(customers)
+	verification.InitialTotal = initialTotal
+	fmt.Printf("Final total balance: %.2f\n", verification.FinalTotal)
+	if abs(verification.FinalTotal-initialTotal) < 0.01 {
+		fmt.Printf("✅ Balance verification passed - no money lost or created\n")
+	} else {
+		fmt.Printf("❌ Balance verification failed - money discrepancy: %.2f\n", verification.FinalTotal-initialTotal)
+		verification.Passed = false
+	}
+	return verification
+}
+
+func verifyCustomerBalances(customers []*domain.Customer) BalanceVerification {
+	verification := BalanceVerification{Passed: true}
 
 	for _, customer := range customers {
-		err := customer.VerifyBalance()
-		if err != nil {
-			fmt.Printf("⚠️  %v\n", err)
-			allVerified = false
+		balance := CustomerBalance{
+			Name:      customer.GetName(),
+			AccountID: customer.GetAccountID(),
+			Expected:  customer.ExpectedBalance(),
 		}
 
-		// Get current balance for total calculation
-		balance, err := customer.GetCurrentBalance()
-		if err != nil {
+		actual, err := customer.GetCurrentBalance()
+		switch {
+		case err != nil:
 			fmt.Printf("⚠️  Failed to get balance for %s: %v\n", customer.GetName(), err)
-			continue
+			balance.Error = err.Error()
+		case actual != balance.Expected:
+			fmt.Printf("⚠️  [%v] balance mismatch: expected %.2f, got %.2f\n", customer.GetName(), balance.Expected, actual)
+		default:
+			fmt.Printf("[%v] balance verified: %.2f\n", customer.GetName(), actual)
+			balance.Passed = true
+		}
+		if err == nil {
+			balance.Actual = actual
+			verification.FinalTotal += actual
 		}
-		total += balance
+		if !balance.Passed {
+			verification.Passed = false
+		}
+		verification.Customers = append(verification.Customers, balance)
 	}
 
-	if allVerified {
+	if verification.Passed {
 		fmt.Printf("✅ All customer balances verified successfully\n")
 	} else {
 		fmt.Printf("⚠️  Some customer balance verifications failed\n")
 	}
 
-	return total
+	return verification
 }
 
-// cleanupTransferCustomers logs customer info for cleanup (accounts would need manual cleanup)
+// cleanupTransferCustomers logs customer info for cleanup and appends it to the cleanup
+// manifest (accounts would need manual cleanup)
 func cleanupTransferCustomers(customers []*domain.Customer) {
 	accountIDs := make([]string, len(customers))
 	for i, customer := range customers {
@@ -228,6 +295,7 @@ func cleanupTransferCustomers(customers []*domain.Customer) {
 
 	fmt.Printf("\nTest accounts created: %v\n", accountIDs)
 	fmt.Printf("(Manual cleanup may be required if DELETE endpoint is not available)\n")
+	appendCleanupManifest(customers)
 }
 
 // abs returns absolute value of float64
@@ -1,9 +1,12 @@
 package loadtest
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"com.ndnhuy.mybank/promscrape"
 	vegeta "github.com/tsenart/vegeta/v12/lib"
 )
 
@@ -12,10 +15,16 @@ type Attacker struct {
 	rate     vegeta.Rate      // Rate of requests per second
 	duration time.Duration    // Duration of the load test in seconds
 	attacker *vegeta.Attacker // Vegeta attacker instance
-	metrics  *vegeta.Metrics  // Pointer to metrics for accumulating results
+	metrics  *QueueMetrics    // Pointer to metrics for accumulating results
+
+	observers         []func(*vegeta.Result)   // Called for every result, after metrics are updated
+	snapshotObservers []func(IntervalSnapshot) // Called for every closed time series interval
+
+	sent     atomic.Uint64 // Requests sent since the last time series snapshot
+	inFlight atomic.Int64  // Requests sent but not answered yet
 }
 
-func NewAttacker(targetURL string, method string, rps, durationInSeconds int, metrics *vegeta.Metrics) *Attacker {
+func NewAttacker(targetURL string, method string, rps, durationInSeconds int, metrics *QueueMetrics) *Attacker {
 	return &Attacker{
 		targeter: vegeta.NewStaticTargeter(vegeta.Target{
 			Method: method,
@@ -23,24 +32,119 @@ func NewAttacker(targetURL string, method string, rps, durationInSeconds int, me
 		}),
 		rate:     vegeta.Rate{Freq: rps, Per: time.Second},
 		duration: time.Duration(durationInSeconds) * time.Second,
-		attacker: vegeta.NewAttacker(),
+		attacker: newVegetaAttacker(),
 		metrics:  metrics,
 	}
 }
 
+// Attack runs the attack until its duration elapses or the load test is interrupted,
+// printing one progress row per time series interval. A warm-up, when configured, comes
+// first. Once interrupted it sends nothing more but still waits for the responses in
+// flight.
 func (a *Attacker) Attack() {
-	requestCount := 0
-	for res := range a.attacker.Attack(a.targeter, a.rate, a.duration, "Load Test") {
-		a.metrics.Add(res)
-		requestCount++
-
-		// Print progress every 10 requests
-		if requestCount%10 == 0 {
-			fmt.Printf(".")
+	if warmup := a.warmup(); warmup != nil {
+		a.metrics.SetWarmup(warmup, time.Now())
+	}
+	if !interrupted().IsZero() {
+		return // Before or while warming up, nothing was measured
+	}
+
+	timeSeries := a.metrics.TimeSeries()
+	ticker := time.NewTicker(timeSeries.Interval())
+	defer ticker.Stop()
+	sampler := time.NewTicker(inFlightSampleInterval)
+	defer sampler.Stop()
+
+	monitor := startServerMonitor()
+	if monitor != nil {
+		defer func() {
+			report, err := monitor.Stop(context.Background())
+			if err != nil {
+				fmt.Printf("⚠️  Failed to scrape server metrics after the attack: %v\n", err)
+				return
+			}
+			a.metrics.SetServerMetrics(report)
+		}()
+	}
+
+	PrintProgressHeader()
+	began := time.Now()
+	timeSeries.Begin(began)
+	a.metrics.SetSchedule(began, a.rate)
+	results := a.attacker.Attack(a.countingTargeter(), a.rate, a.duration, MeasuredAttack)
+	interrupts := interruptContext().Done()
+	for {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				// Close the last, usually partial, interval
+				a.closeInterval(time.Now())
+				return
+			}
+			a.inFlight.Add(-1)
+			a.metrics.Add(res)
+			for _, observe := range a.observers {
+				observe(res)
+			}
+		case now := <-ticker.C:
+			a.closeInterval(now)
+		case <-interrupts:
+			a.attacker.Stop() // Results of the requests in flight still arrive
+			interrupts = nil
+		case <-sampler.C:
+			a.metrics.RecordInFlight(a.inFlight.Load())
 		}
 	}
 }
 
+// closeInterval snapshots the current time series interval and hands it to the observers
+func (a *Attacker) closeInterval(now time.Time) {
+	snap := a.metrics.TimeSeries().Snapshot(now, a.sent.Swap(0), a.inFlight.Load())
+	PrintProgressRow(snap)
+	for _, observe := range a.snapshotObservers {
+		observe(snap)
+	}
+}
+
+// countingTargeter wraps the targeter to count requests as vegeta sends them.
+// Vegeta asks for a target right before sending it, so every call is a request going out.
+func (a *Attacker) countingTargeter() vegeta.Targeter {
+	return func(t *vegeta.Target) error {
+		if err := a.targeter(t); err != nil {
+			return err
+		}
+		a.sent.Add(1)
+		a.inFlight.Add(1)
+		return nil
+	}
+}
+
+// OnResult registers a function that is called with every result of the attack.
+// Observers run on the attack goroutine, one result at a time.
+func (a *Attacker) OnResult(observe func(*vegeta.Result)) {
+	a.observers = append(a.observers, observe)
+}
+
+// OnSnapshot registers a function that is called with every closed time series interval,
+// on the attack goroutine
+func (a *Attacker) OnSnapshot(observe func(IntervalSnapshot)) {
+	a.snapshotObservers = append(a.snapshotObservers, observe)
+}
+
 func (a *Attacker) Duration() time.Duration {
 	return a.duration
 }
+
+// startServerMonitor starts scraping ServerMetricsURL, or returns nil when scraping
+// is disabled or the endpoint is unreachable
+func startServerMonitor() *promscrape.Monitor {
+	if ServerMetricsURL == "" {
+		return nil
+	}
+	monitor := promscrape.NewMonitor(promscrape.NewScraper(ServerMetricsURL), ReportInterval)
+	if err := monitor.Start(context.Background()); err != nil {
+		fmt.Printf("⚠️  Server metrics unavailable, reporting client-side metrics only: %v\n", err)
+		return nil
+	}
+	return monitor
+}
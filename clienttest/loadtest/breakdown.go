@@ -0,0 +1,143 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// MetricsBreakdown keeps a separate vegeta.Metrics per label (topology, operation, ...)
+type MetricsBreakdown struct {
+	metrics  map[string]*vegeta.Metrics
+	timeouts map[string]int
+	buckets  vegeta.Buckets // Latency histogram buckets, nil for no histogram
+}
+
+// NewMetricsBreakdown creates an empty MetricsBreakdown
+func NewMetricsBreakdown() *MetricsBreakdown {
+	return &MetricsBreakdown{
+		metrics:  make(map[string]*vegeta.Metrics),
+		timeouts: make(map[string]int),
+	}
+}
+
+// NewMetricsBreakdownWithHistogram creates an empty MetricsBreakdown that also
+// keeps a latency histogram per label
+func NewMetricsBreakdownWithHistogram(buckets vegeta.Buckets) *MetricsBreakdown {
+	b := NewMetricsBreakdown()
+	b.buckets = buckets
+	return b
+}
+
+// Add records a result under the given label
+func (b *MetricsBreakdown) Add(label string, res *vegeta.Result) {
+	m, ok := b.metrics[label]
+	if !ok {
+		m = &vegeta.Metrics{}
+		if b.buckets != nil {
+			m.Histogram = &vegeta.Histogram{Buckets: b.buckets}
+		}
+		b.metrics[label] = m
+	}
+	m.Add(res)
+
+	if isTimeout(res) {
+		b.timeouts[label]++
+	}
+}
+
+// Close computes the summary metrics of every label
+func (b *MetricsBreakdown) Close() {
+	for _, m := range b.metrics {
+		m.Close()
+	}
+}
+
+// Labels returns the recorded labels in sorted order
+func (b *MetricsBreakdown) Labels() []string {
+	labels := make([]string, 0, len(b.metrics))
+	for label := range b.metrics {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Get returns the metrics of a label, or nil if nothing was recorded for it
+func (b *MetricsBreakdown) Get(label string) *vegeta.Metrics {
+	return b.metrics[label]
+}
+
+// countServerErrors returns how many responses had a 5xx status code
+func countServerErrors(m *vegeta.Metrics) int {
+	count := 0
+	for code, n := range m.StatusCodes {
+		if strings.HasPrefix(code, "5") {
+			count += n
+		}
+	}
+	return count
+}
+
+// Timeouts returns how many requests of a label never got a response in time
+func (b *MetricsBreakdown) Timeouts(label string) int {
+	return b.timeouts[label]
+}
+
+// isTimeout reports whether a result failed because the client gave up waiting
+func isTimeout(res *vegeta.Result) bool {
+	return res.Code == 0 && (strings.Contains(res.Error, "Timeout") || strings.Contains(res.Error, "deadline exceeded"))
+}
+
+// PrintReport prints one line per label followed by the unique errors of each label
+func (b *MetricsBreakdown) PrintReport(title string) {
+	width := 8
+	for _, label := range b.Labels() {
+		if len(label) > width {
+			width = len(label)
+		}
+	}
+
+	fmt.Printf("\n📋 %s:\n", title)
+	fmt.Printf("   %-*s %8s %9s %12s %12s %12s %6s %9s %10s %10s\n",
+		width, "", "Requests", "Success", "Mean", "P99", "Max", "5xx", "Timeouts", "Bytes In", "Bytes Out")
+	for _, label := range b.Labels() {
+		m := b.metrics[label]
+		fmt.Printf("   %-*s %8d %8.2f%% %12v %12v %12v %6d %9d %10d %10d\n",
+			width, label, m.Requests, m.Success*100, m.Latencies.Mean, m.Latencies.P99, m.Latencies.Max,
+			countServerErrors(m), b.timeouts[label], m.BytesIn.Total, m.BytesOut.Total)
+	}
+
+	for _, label := range b.Labels() {
+		m := b.metrics[label]
+		if len(m.Errors) == 0 {
+			continue
+		}
+		fmt.Printf("\n   ⚠️  Errors for %s:\n", label)
+		for _, e := range m.Errors {
+			fmt.Printf("      - %s\n", e)
+		}
+	}
+}
+
+// BreakdownEntry is the structured form of one label of a MetricsBreakdown
+type BreakdownEntry struct {
+	Label    string          `json:"label"`
+	Timeouts int             `json:"timeouts"`
+	Metrics  *vegeta.Metrics `json:"metrics"`
+}
+
+// Entries returns every label with its metrics, in sorted label order
+func (b *MetricsBreakdown) Entries() []BreakdownEntry {
+	entries := make([]BreakdownEntry, 0, len(b.metrics))
+	for _, label := range b.Labels() {
+		entries = append(entries, BreakdownEntry{
+			Label:    label,
+			Timeouts: b.timeouts[label],
+			Metrics:  b.metrics[label],
+		})
+	}
+	return entries
+}
@@ -0,0 +1,69 @@
+package loadtest
+
+import (
+	"time"
+
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// labeledResult is a request of a closed-loop scenario, one that issues its requests
+// through the domain instead of vegeta, with the label it is broken down by
+type labeledResult struct {
+	label string
+	res   *vegeta.Result
+}
+
+// operationResult converts a request made through the domain into a result the queue
+// metrics can record. The domain errors don't carry a status code, so failed
+// requests get none.
+func operationResult(method, path string, start time.Time, latency time.Duration, err error) *vegeta.Result {
+	res := &vegeta.Result{
+		Code:      200,
+		Timestamp: start,
+		Latency:   latency,
+		Method:    method,
+		URL:       utils.BASE_URL + path,
+	}
+	if err != nil {
+		res.Code = 0
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// collectResults records results until the channel is closed, on the calling goroutine,
+// and prints one progress row per time series interval like Attacker.Attack does
+func collectResults(run *Run, qm *QueueMetrics, breakdown *MetricsBreakdown, results <-chan labeledResult) {
+	timeSeries := qm.TimeSeries()
+	ticker := time.NewTicker(timeSeries.Interval())
+	defer ticker.Stop()
+
+	PrintProgressHeader()
+	timeSeries.Begin(time.Now())
+	var seq, sent uint64
+	closeInterval := func(now time.Time) {
+		snap := timeSeries.Snapshot(now, sent, 0)
+		sent = 0
+		PrintProgressRow(snap)
+		if run.exporter != nil {
+			run.exporter.PushSnapshot(snap)
+		}
+	}
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				closeInterval(time.Now())
+				return
+			}
+			result.res.Seq = seq
+			seq++
+			sent++
+			qm.Add(result.res)
+			breakdown.Add(result.label, result.res)
+		case now := <-ticker.C:
+			closeInterval(now)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package loadtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Tolerances are how much worse a candidate run may be than its baseline before the
+// difference is reported as a regression
+type Tolerances struct {
+	Throughput float64 // Largest accepted relative throughput drop, e.g. 0.05
+	Latency    float64 // Largest accepted relative latency increase, e.g. 0.10
+	Success    float64 // Largest accepted drop of the success ratio, absolute, e.g. 0.01
+	Alpha      float64 // Significance level of the Mann-Whitney test on latencies
+}
+
+// DefaultTolerances are used by the compare command unless overridden
+var DefaultTolerances = Tolerances{
+	Throughput: 0.05,
+	Latency:    0.10,
+	Success:    0.01,
+	Alpha:      0.05,
+}
+
+// MetricDelta is one metric of a candidate run next to its baseline
+type MetricDelta struct {
+	Name      string  `json:"name"`
+	Unit      string  `json:"unit"`
+	Baseline  float64 `json:"baseline"`
+	Candidate float64 `json:"candidate"`
+	Change    float64 `json:"change"` // Relative, except for ratios where it is absolute
+	Regressed bool    `json:"regressed"`
+}
+
+// RunComparison is the comparison of a candidate run with a baseline run of the same scenario
+type RunComparison struct {
+	Scenario          string             `json:"scenario"`
+	Baseline          string             `json:"baseline"`
+	Candidate         string             `json:"candidate"`
+	Metrics           []MetricDelta      `json:"metrics"`
+	Latency           *MannWhitneyResult `json:"latency,omitempty"` // nil when a run did not store its latencies
+	BaselineVerified  *bool              `json:"baseline_verified,omitempty"`
+	CandidateVerified *bool              `json:"candidate_verified,omitempty"`
+	Regressions       []string           `json:"regressions"`
+}
+
+// Regressed reports whether any regression was found
+func (c RunComparison) Regressed() bool {
+	return len(c.Regressions) > 0
+}
+
+// LoadRuns reads stored run results. Every reference is the path of a structured report
+// (*.jsonl), meaning every run in it, path#run-id for a single run of it, or the ID of
+// a run recorded in HistoryDir.
+func LoadRuns(refs []string) ([]QueueReport, error) {
+	var runs []QueueReport
+	for _, ref := range refs {
+		path, runID, _ := strings.Cut(ref, "#")
+		if !strings.HasSuffix(path, ".jsonl") {
+			report, err := loadHistoryRun(ref)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, report)
+			continue
+		}
+		reports, err := readReports(path)
+		if err != nil {
+			return nil, err
+		}
+		if runID == "" {
+			runs = append(runs, reports...)
+			continue
+		}
+		found := false
+		for _, report := range reports {
+			if report.RunID == runID {
+				runs = append(runs, report)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("run %s not found in %s", runID, path)
+		}
+	}
+	return runs, nil
+}
+
+// loadHistoryRun reads a run recorded in HistoryDir
+func loadHistoryRun(runID string) (QueueReport, error) {
+	if HistoryDir == "" {
+		return QueueReport{}, fmt.Errorf("run history is disabled, cannot look up run %s", runID)
+	}
+	history, err := OpenHistory(HistoryDir)
+	if err != nil {
+		return QueueReport{}, err
+	}
+	return history.Load(runID)
+}
+
+// readReports reads every report line of a structured report file
+func readReports(path string) ([]QueueReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open report file: %w", err)
+	}
+	defer file.Close()
+
+	var reports []QueueReport
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var report QueueReport
+		if err := json.Unmarshal(scanner.Bytes(), &report); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, lineNo, err)
+		}
+		reports = append(reports, report)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return reports, nil
+}
+
+// CompareReports compares every run against the first one, the baseline. All runs
+// must belong to the same scenario.
+func CompareReports(reports []QueueReport, tolerances Tolerances) ([]RunComparison, error) {
+	if len(reports) < 2 {
+		return nil, fmt.Errorf("need at least two runs to compare, got %d", len(reports))
+	}
+	baseline := reports[0]
+	for _, report := range reports[1:] {
+		if report.Scenario != baseline.Scenario {
+			return nil, fmt.Errorf("cannot compare scenario %q with %q", report.Scenario, baseline.Scenario)
+		}
+	}
+
+	comparisons := make([]RunComparison, 0, len(reports)-1)
+	for _, candidate := range reports[1:] {
+		comparisons = append(comparisons, CompareRuns(baseline, candidate, tolerances))
+	}
+	return comparisons, nil
+}
+
+// CompareRuns diffs throughput, latency percentiles, success ratio and verification
+// outcome of a candidate run against a baseline. A latency increase beyond tolerance
+// only counts as a regression when the Mann-Whitney test also finds the candidate
+// significantly slower, so that noise between small runs isn't flagged.
+func CompareRuns(baseline, candidate QueueReport, tolerances Tolerances) RunComparison {
+	comparison := RunComparison{
+		Scenario:          baseline.Scenario,
+		Baseline:          runLabel(baseline),
+		Candidate:         runLabel(candidate),
+		BaselineVerified:  baseline.Verified,
+		CandidateVerified: candidate.Verified,
+	}
+
+	slower := true
+	if len(baseline.Latencies) > 0 && len(candidate.Latencies) > 0 {
+		test := MannWhitney(baseline.Latencies, candidate.Latencies)
+		comparison.Latency = &test
+		slower = test.PValue < tolerances.Alpha && test.Z > 0
+	}
+
+	throughput := relativeDelta("throughput", "req/s", overallValue(baseline, throughputOf), overallValue(candidate, throughputOf))
+	throughput.Regressed = throughput.Change < -tolerances.Throughput
+	comparison.add(throughput)
+
+	mean := relativeDelta("mean", "ms", overallValue(baseline, meanOf), overallValue(candidate, meanOf))
+	mean.Regressed = slower && mean.Change > tolerances.Latency
+	comparison.add(mean)
+
+	for _, entry := range baseline.Percentiles {
+		latency, ok := percentileOf(candidate, entry.Quantile)
+		if !ok {
+			continue
+		}
+		delta := relativeDelta(percentileName(entry.Quantile), "ms", milliseconds(entry.Latency), milliseconds(latency))
+		delta.Regressed = slower && delta.Change > tolerances.Latency
+		comparison.add(delta)
+	}
+
+	success := MetricDelta{
+		Name:      "success",
+		Unit:      "%",
+		Baseline:  overallValue(baseline, successOf) * 100,
+		Candidate: overallValue(candidate, successOf) * 100,
+	}
+	success.Change = success.Candidate - success.Baseline
+	success.Regressed = success.Change < -tolerances.Success*100
+	comparison.add(success)
+
+	if candidate.Verified != nil && !*candidate.Verified {
+		comparison.Regressions = append(comparison.Regressions, "balance verification failed")
+	}
+	return comparison
+}
+
+// add appends a metric and records it as a regression when it regressed
+func (c *RunComparison) add(delta MetricDelta) {
+	c.Metrics = append(c.Metrics, delta)
+	if delta.Regressed {
+		c.Regressions = append(c.Regressions, fmt.Sprintf("%s %s", delta.Name, formatChange(delta)))
+	}
+}
+
+func relativeDelta(name, unit string, baseline, candidate float64) MetricDelta {
+	delta := MetricDelta{Name: name, Unit: unit, Baseline: baseline, Candidate: candidate}
+	if baseline != 0 {
+		delta.Change = (candidate - baseline) / baseline
+	}
+	return delta
+}
+
+func throughputOf(report QueueReport) float64 { return report.Overall.Throughput }
+func meanOf(report QueueReport) float64       { return milliseconds(report.Overall.Latencies.Mean) }
+func successOf(report QueueReport) float64    { return report.Overall.Success }
+
+// overallValue reads a value of the report's overall metrics, 0 when they are missing
+func overallValue(report QueueReport, value func(QueueReport) float64) float64 {
+	if report.Overall == nil {
+		return 0
+	}
+	return value(report)
+}
+
+// percentileOf returns the report's latency at quantile q
+func percentileOf(report QueueReport, q float64) (time.Duration, bool) {
+	for _, entry := range report.Percentiles {
+		if entry.Quantile == q {
+			return entry.Latency, true
+		}
+	}
+	return 0, false
+}
+
+// runLabel names a run by its ID, or by its timestamp for reports written before runs had IDs
+func runLabel(report QueueReport) string {
+	if report.RunID != "" {
+		return report.RunID
+	}
+	return report.Timestamp.Format("2006-01-02 15:04:05")
+}
+
+// formatChange renders the change of a metric, e.g. "+12.5%" or "-1.20pp" for ratios
+func formatChange(delta MetricDelta) string {
+	if delta.Unit == "%" {
+		return fmt.Sprintf("%+.2fpp", delta.Change)
+	}
+	return fmt.Sprintf("%+.1f%%", delta.Change*100)
+}
+
+// PrintComparison writes a comparison as a table followed by its regressions
+func PrintComparison(w io.Writer, comparison RunComparison) {
+	fmt.Fprintf(w, "\n📊 %s: %s → %s\n", comparison.Scenario, comparison.Baseline, comparison.Candidate)
+	fmt.Fprintf(w, "   %-12s %14s %14s %10s\n", "Metric", "Baseline", "Candidate", "Change")
+	for _, delta := range comparison.Metrics {
+		marker := ""
+		if delta.Regressed {
+			marker = " ❌"
+		}
+		fmt.Fprintf(w, "   %-12s %14s %14s %10s%s\n", delta.Name,
+			fmt.Sprintf("%.2f %s", delta.Baseline, delta.Unit),
+			fmt.Sprintf("%.2f %s", delta.Candidate, delta.Unit),
+			formatChange(delta), marker)
+	}
+	fmt.Fprintf(w, "   %-12s %14s %14s\n", "verified", formatVerified(comparison.BaselineVerified), formatVerified(comparison.CandidateVerified))
+
+	if test := comparison.Latency; test != nil {
+		fmt.Fprintf(w, "   Mann-Whitney U: z=%.2f, p=%.4f, P(candidate slower)=%.3f (%d vs %d samples)\n",
+			test.Z, test.PValue, test.Superiority, test.BaselineCount, test.CandidateCount)
+	} else {
+		fmt.Fprintln(w, "   Mann-Whitney U: skipped, a run has no stored latencies")
+	}
+
+	if !comparison.Regressed() {
+		fmt.Fprintln(w, "   ✅ No regression")
+		return
+	}
+	for _, regression := range comparison.Regressions {
+		fmt.Fprintf(w, "   ❌ Regression: %s\n", regression)
+	}
+}
+
+func formatVerified(verified *bool) string {
+	switch {
+	case verified == nil:
+		return "-"
+	case *verified:
+		return "passed"
+	default:
+		return "FAILED"
+	}
+}
+
+// Compare loads the referenced runs, compares them against the first one and prints
+// the results. It returns whether any candidate regressed.
+func Compare(refs []string, tolerances Tolerances) (bool, error) {
+	reports, err := LoadRuns(refs)
+	if err != nil {
+		return false, err
+	}
+	comparisons, err := CompareReports(reports, tolerances)
+	if err != nil {
+		return false, err
+	}
+
+	regressed := false
+	for _, comparison := range comparisons {
+		PrintComparison(os.Stdout, comparison)
+		regressed = regressed || comparison.Regressed()
+	}
+	return regressed, nil
+}
@@ -0,0 +1,126 @@
+package loadtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// attackReport builds the report of a run whose latencies are spread evenly from
+// base to base+19ms, with one in ten requests failing unless success
+func attackReport(t *testing.T, runID string, base time.Duration, success bool) QueueReport {
+	t.Helper()
+	qm := NewQueueMetrics()
+	start := time.Now()
+	for i := 0; i < 200; i++ {
+		code := 200
+		if !success && i%10 == 0 {
+			code = 500
+		}
+		qm.Add(&vegeta.Result{
+			Code:      uint16(code),
+			Latency:   base + time.Duration(i%20)*time.Millisecond,
+			Timestamp: start.Add(time.Duration(i) * 10 * time.Millisecond),
+		})
+	}
+	qm.Close()
+	report := qm.Report("get-accounts")
+	report.RunID = runID
+	return report
+}
+
+func TestMannWhitney(t *testing.T) {
+	same := []HistogramBin{{Latency: time.Millisecond, Count: 50}, {Latency: 2 * time.Millisecond, Count: 50}}
+	result := MannWhitney(same, same)
+	assert.InDelta(t, 0.5, result.Superiority, 1e-9)
+	assert.InDelta(t, 1.0, result.PValue, 1e-9)
+
+	// Every candidate latency is above every baseline latency
+	slower := []HistogramBin{{Latency: 3 * time.Millisecond, Count: 50}, {Latency: 4 * time.Millisecond, Count: 50}}
+	result = MannWhitney(same, slower)
+	assert.Equal(t, 1.0, result.Superiority)
+	assert.Positive(t, result.Z)
+	assert.Less(t, result.PValue, 1e-6)
+
+	result = MannWhitney(slower, same)
+	assert.Negative(t, result.Z)
+	assert.Zero(t, result.Superiority)
+
+	// Small exact case: U = 1 out of 4 pairs, ties count half
+	result = MannWhitney(
+		[]HistogramBin{{Latency: 1, Count: 1}, {Latency: 3, Count: 1}},
+		[]HistogramBin{{Latency: 1, Count: 1}, {Latency: 2, Count: 1}},
+	)
+	assert.InDelta(t, 1.5, result.U, 1e-9)
+
+	assert.Equal(t, 1.0, MannWhitney(nil, same).PValue)
+}
+
+func TestCompareRunsFlagsRegressions(t *testing.T) {
+	baseline := attackReport(t, "base", 10*time.Millisecond, true)
+
+	same := CompareRuns(baseline, attackReport(t, "same", 10*time.Millisecond, true), DefaultTolerances)
+	assert.False(t, same.Regressed(), same.Regressions)
+	require.NotNil(t, same.Latency)
+
+	slower := CompareRuns(baseline, attackReport(t, "slower", 30*time.Millisecond, true), DefaultTolerances)
+	assert.True(t, slower.Regressed())
+	assert.Contains(t, slower.Regressions, "p50 +105.2%")
+	for _, delta := range slower.Metrics {
+		if delta.Name == "throughput" {
+			assert.False(t, delta.Regressed)
+		}
+	}
+
+	failed := false
+	failing := attackReport(t, "failing", 10*time.Millisecond, false)
+	failing.Verified = &failed
+	comparison := CompareRuns(baseline, failing, DefaultTolerances)
+	assert.Contains(t, comparison.Regressions, "success -10.00pp")
+	assert.Contains(t, comparison.Regressions, "balance verification failed")
+
+	// Tolerances are configurable
+	lenient := DefaultTolerances
+	lenient.Latency, lenient.Success = 5, 0.2
+	comparison = CompareRuns(baseline, attackReport(t, "slower", 30*time.Millisecond, true), lenient)
+	assert.False(t, comparison.Regressed(), comparison.Regressions)
+}
+
+func TestLoadRunsAndCompareReports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts_loadtest_report.jsonl")
+	appendJSONReport(path, attackReport(t, "run-1", 10*time.Millisecond, true))
+	appendJSONReport(path, attackReport(t, "run-2", 10*time.Millisecond, true))
+
+	runs, err := LoadRuns([]string{path})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.NotEmpty(t, runs[1].Latencies)
+
+	runs, err = LoadRuns([]string{path + "#run-2", path + "#run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "run-2", runs[0].RunID)
+
+	comparisons, err := CompareReports(runs, DefaultTolerances)
+	require.NoError(t, err)
+	require.Len(t, comparisons, 1)
+	assert.Equal(t, "run-2", comparisons[0].Baseline)
+	assert.Equal(t, "run-1", comparisons[0].Candidate)
+
+	_, err = LoadRuns([]string{path + "#missing"})
+	assert.Error(t, err)
+	_, err = CompareReports(runs[:1], DefaultTolerances)
+	assert.Error(t, err)
+
+	other := runs[1]
+	other.Scenario = "transfers"
+	_, err = CompareReports([]QueueReport{runs[0], other}, DefaultTolerances)
+	assert.Error(t, err)
+
+	_, err = LoadRuns([]string{filepath.Join(t.TempDir(), "missing.jsonl")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,186 @@
+package loadtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// inFlightSampleInterval is how often the attacker samples its in-flight requests
+// for the Little's law check
+const inFlightSampleInterval = 10 * time.Millisecond
+
+// Thresholds past which the attacker, not the server, is considered the bottleneck
+const (
+	minSustainedRatio   = 0.95                  // Of the requested rate
+	maxSchedulingLagP99 = 25 * time.Millisecond // Between a request's scheduled and actual send time
+)
+
+// littlesLawTolerance is how far the observed mean concurrency may deviate from λ·W
+const littlesLawTolerance = 0.2
+
+// Schedule is when a constant-rate attack intended to send every request. Vegeta
+// sends request n (its Result.Seq) at began + (n+1)/rate unless it falls behind.
+type Schedule struct {
+	Began    time.Time
+	Interval time.Duration // Between two scheduled requests
+}
+
+// NewSchedule returns the schedule of an attack at rate that began at began,
+// or nil for an unlimited rate, which has no schedule to lag behind
+func NewSchedule(began time.Time, rate vegeta.Rate) *Schedule {
+	if rate.Freq <= 0 || rate.Per <= 0 {
+		return nil
+	}
+	return &Schedule{Began: began, Interval: rate.Per / time.Duration(rate.Freq)}
+}
+
+// Rate returns the scheduled requests per second
+func (s *Schedule) Rate() float64 {
+	return float64(time.Second) / float64(s.Interval)
+}
+
+// Intended returns when the request with the given sequence number should have been sent
+func (s *Schedule) Intended(seq uint64) time.Time {
+	return s.Began.Add(time.Duration(seq+1) * s.Interval)
+}
+
+// Lag returns how late the result's request was sent, never negative
+func (s *Schedule) Lag(res *vegeta.Result) time.Duration {
+	lag := res.Timestamp.Sub(s.Intended(res.Seq))
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// SetSchedule records when the attack began and at which rate it was asked to send,
+// so that results can be corrected for coordinated omission
+func (qm *QueueMetrics) SetSchedule(began time.Time, rate vegeta.Rate) {
+	qm.schedule = NewSchedule(began, rate)
+}
+
+// RecordInFlight records a sample of how many requests await a response
+func (qm *QueueMetrics) RecordInFlight(n int64) {
+	qm.inFlightSum += float64(n)
+	qm.inFlightSamples++
+	if n > qm.inFlightMax {
+		qm.inFlightMax = n
+	}
+}
+
+// recordScheduled records the scheduling lag of a result and its latency measured
+// from the scheduled send time instead of the actual one
+func (qm *QueueMetrics) recordScheduled(res *vegeta.Result) {
+	if qm.schedule == nil {
+		return
+	}
+	lag := qm.schedule.Lag(res)
+	qm.lag.Record(lag)
+	qm.corrected.Record(res.Latency + lag)
+}
+
+// Corrected returns the latencies corrected for coordinated omission, empty when the
+// attack had no schedule
+func (qm *QueueMetrics) Corrected() *HDRHistogram {
+	return qm.corrected
+}
+
+// SchedulingLag returns how late requests were sent compared to their schedule
+func (qm *QueueMetrics) SchedulingLag() *HDRHistogram {
+	return qm.lag
+}
+
+// RequestedRate returns the rate the attack was asked to sustain, 0 when unknown
+func (qm *QueueMetrics) RequestedRate() float64 {
+	if qm.schedule == nil {
+		return 0
+	}
+	return qm.schedule.Rate()
+}
+
+// SustainedRatio returns the achieved send rate as a fraction of the requested one,
+// 1 when the requested rate is unknown
+func (qm *QueueMetrics) SustainedRatio() float64 {
+	requested := qm.RequestedRate()
+	if requested <= 0 {
+		return 1
+	}
+	return qm.Rate / requested
+}
+
+// ClientSaturated reports whether the attacker could not keep to its schedule, in
+// which case the server was offered less load than requested and raw latencies
+// understate what a client sending on schedule would have seen
+func (qm *QueueMetrics) ClientSaturated() bool {
+	if qm.schedule == nil || qm.Requests < 2 {
+		return false
+	}
+	return qm.SustainedRatio() < minSustainedRatio || qm.lag.Quantile(0.99) > maxSchedulingLagP99
+}
+
+// LittlesLawCheck compares the concurrency observed by the attacker with L = λ·W
+type LittlesLawCheck struct {
+	ArrivalRate  float64       `json:"arrival_rate"`  // λ, requests sent per second
+	MeanResponse time.Duration `json:"mean_response"` // W
+	Predicted    float64       `json:"predicted"`     // L = λ·W
+	Observed     float64       `json:"observed"`      // Mean of the in-flight samples
+	MaxInFlight  int64         `json:"max_in_flight"`
+	Samples      int           `json:"samples"`
+	Deviation    float64       `json:"deviation"` // |observed - predicted| / predicted
+	Consistent   bool          `json:"consistent"`
+}
+
+// LittlesLaw checks the sampled in-flight requests against λ·W. A large deviation
+// means the latencies or the rate were not measured over the same requests, e.g.
+// because requests were still queued inside the attacker.
+func (qm *QueueMetrics) LittlesLaw() LittlesLawCheck {
+	check := LittlesLawCheck{
+		ArrivalRate:  qm.Rate,
+		MeanResponse: qm.Latencies.Mean,
+		Predicted:    qm.Rate * qm.Latencies.Mean.Seconds(),
+		MaxInFlight:  qm.inFlightMax,
+		Samples:      qm.inFlightSamples,
+	}
+	if qm.inFlightSamples == 0 {
+		return check
+	}
+	check.Observed = qm.inFlightSum / float64(qm.inFlightSamples)
+	if check.Predicted > 0 {
+		check.Deviation = math.Abs(check.Observed-check.Predicted) / check.Predicted
+	}
+	check.Consistent = check.Deviation <= littlesLawTolerance
+	return check
+}
+
+// printCoordinatedOmission prints raw next to corrected percentiles, the scheduling
+// lag and the Little's law check
+func (qm *QueueMetrics) printCoordinatedOmission() {
+	if qm.schedule == nil || qm.corrected.Total() == 0 {
+		return
+	}
+
+	fmt.Println("\n⏱️  COORDINATED OMISSION:")
+	fmt.Printf("   Requested Rate:        %.2f requests/sec\n", qm.RequestedRate())
+	fmt.Printf("   Achieved Rate:         %.2f requests/sec (%.1f%%)\n", qm.Rate, qm.SustainedRatio()*100)
+	fmt.Printf("   %-8s %14s %14s %14s\n", "", "Raw", "Corrected", "Send Lag")
+	for _, q := range ReportedQuantiles {
+		fmt.Printf("   %-8s %14v %14v %14v\n", percentileName(q), qm.hdr.Quantile(q), qm.corrected.Quantile(q), qm.lag.Quantile(q))
+	}
+	fmt.Printf("   %-8s %14v %14v %14v\n", "max", qm.hdr.Max(), qm.corrected.Max(), qm.lag.Max())
+
+	check := qm.LittlesLaw()
+	if check.Samples == 0 {
+		return
+	}
+	fmt.Println("\n🔁 LITTLE'S LAW (L = λW):")
+	fmt.Printf("   Predicted L (λ·W):     %.2f requests\n", check.Predicted)
+	fmt.Printf("   Observed In-Flight:    %.2f mean, %d max (%d samples)\n", check.Observed, check.MaxInFlight, check.Samples)
+	if check.Consistent {
+		fmt.Printf("   ✅ Consistent (%.1f%% deviation)\n", check.Deviation*100)
+	} else {
+		fmt.Printf("   ⚠️  Inconsistent (%.1f%% deviation): latency and rate were not measured over the same requests\n", check.Deviation*100)
+	}
+}
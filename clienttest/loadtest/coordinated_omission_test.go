@@ -0,0 +1,96 @@
+package loadtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestScheduleLag(t *testing.T) {
+	began := time.Now()
+	schedule := NewSchedule(began, vegeta.Rate{Freq: 10, Per: time.Second})
+	require.NotNil(t, schedule)
+
+	assert.Equal(t, 10.0, schedule.Rate())
+	assert.Equal(t, began.Add(300*time.Millisecond), schedule.Intended(2))
+	assert.Equal(t, 50*time.Millisecond, schedule.Lag(&vegeta.Result{Seq: 2, Timestamp: began.Add(350 * time.Millisecond)}))
+	assert.Zero(t, schedule.Lag(&vegeta.Result{Seq: 2, Timestamp: began.Add(290 * time.Millisecond)}))
+
+	assert.Nil(t, NewSchedule(began, vegeta.Rate{}))
+}
+
+func TestCorrectedLatenciesIncludeSchedulingLag(t *testing.T) {
+	began := time.Now()
+	qm := NewQueueMetrics()
+	qm.SetSchedule(began, vegeta.Rate{Freq: 100, Per: time.Second})
+
+	// Every request was sent 100ms late after the first ten, the attacker fell behind
+	for seq := uint64(0); seq < 100; seq++ {
+		sent := began.Add(time.Duration(seq+1) * 10 * time.Millisecond)
+		if seq >= 10 {
+			sent = sent.Add(100 * time.Millisecond)
+		}
+		qm.Add(&vegeta.Result{Seq: seq, Code: 200, Latency: 5 * time.Millisecond, Timestamp: sent})
+	}
+	qm.Close()
+
+	assert.InDelta(t, float64(5*time.Millisecond), float64(qm.HDR().Quantile(0.99)), float64(50*time.Microsecond))
+	assert.InDelta(t, float64(105*time.Millisecond), float64(qm.Corrected().Quantile(0.99)), float64(time.Millisecond))
+	assert.InDelta(t, float64(100*time.Millisecond), float64(qm.SchedulingLag().Max()), float64(time.Millisecond))
+	assert.True(t, qm.ClientSaturated())
+	assert.Equal(t, "🟠 CLIENT SATURATED", qm.GetSystemStatus())
+
+	report := qm.Report("test")
+	assert.Equal(t, 100.0, report.RequestedRate)
+	assert.True(t, report.ClientSaturated)
+	assert.Len(t, report.Corrected, len(ReportedQuantiles))
+}
+
+func TestLittlesLaw(t *testing.T) {
+	began := time.Now()
+	qm := NewQueueMetrics()
+	for i := 0; i < 101; i++ {
+		qm.Add(&vegeta.Result{Code: 200, Latency: 200 * time.Millisecond, Timestamp: began.Add(time.Duration(i) * 10 * time.Millisecond)})
+	}
+	qm.Close()
+
+	// 100 req/s for 200ms each keeps 20 requests in flight
+	for _, n := range []int64{19, 20, 21, 20} {
+		qm.RecordInFlight(n)
+	}
+	check := qm.LittlesLaw()
+	assert.InDelta(t, 20, check.Predicted, 0.5)
+	assert.Equal(t, 20.0, check.Observed)
+	assert.Equal(t, int64(21), check.MaxInFlight)
+	assert.True(t, check.Consistent)
+
+	for i := 0; i < 4; i++ {
+		qm.RecordInFlight(60)
+	}
+	assert.False(t, qm.LittlesLaw().Consistent)
+}
+
+func TestAttackerKeepsToSchedule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	defer func(metricsURL string) { ServerMetricsURL = metricsURL }(ServerMetricsURL)
+	ServerMetricsURL = ""
+
+	qm := NewQueueMetrics()
+	NewAttacker(server.URL+"/accounts", "GET", 50, 1, qm).Attack()
+	qm.Close()
+
+	assert.Equal(t, 50.0, qm.RequestedRate())
+	assert.Equal(t, qm.Requests, qm.Corrected().Total())
+	assert.False(t, qm.ClientSaturated())
+	assert.Positive(t, qm.LittlesLaw().Samples)
+}
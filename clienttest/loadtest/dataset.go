@@ -0,0 +1,243 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/mybankclient"
+	"com.ndnhuy.mybank/utils"
+)
+
+// AccountDataset is the seeded dataset the scenarios take their customers from instead
+// of creating accounts, nil to create them
+var AccountDataset *Dataset
+
+// DefaultBalanceDistribution is the balance distribution of seeded accounts
+var DefaultBalanceDistribution = BalanceDistribution{Kind: UniformBalance, A: 100, B: 100000}
+
+// Kinds of balance distributions
+const (
+	FixedBalance     = "fixed"     // A
+	UniformBalance   = "uniform"   // Between A and B
+	LognormalBalance = "lognormal" // Median A, shape B, a long tail of rich accounts
+)
+
+// minSeedBalance is the smallest balance an account can be opened with
+const minSeedBalance = 0.01
+
+// BalanceDistribution draws the initial balances of seeded accounts
+type BalanceDistribution struct {
+	Kind string
+	A, B float64
+}
+
+// ParseBalanceDistribution parses "fixed:100", "uniform:100,10000" or "lognormal:500,1.5"
+func ParseBalanceDistribution(s string) (BalanceDistribution, error) {
+	kind, params, _ := strings.Cut(strings.TrimSpace(s), ":")
+	var values []float64
+	for _, param := range strings.Split(params, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(param), 64)
+		if err != nil || value < 0 {
+			return BalanceDistribution{}, fmt.Errorf("invalid parameter %q of balance distribution %q", param, s)
+		}
+		values = append(values, value)
+	}
+
+	dist := BalanceDistribution{Kind: kind}
+	switch {
+	case kind == FixedBalance && len(values) == 1:
+		dist.A = values[0]
+	case kind == UniformBalance && len(values) == 2 && values[0] <= values[1]:
+		dist.A, dist.B = values[0], values[1]
+	case kind == LognormalBalance && len(values) == 2:
+		dist.A, dist.B = values[0], values[1]
+	default:
+		return BalanceDistribution{}, fmt.Errorf("invalid balance distribution %q, expected fixed:<amount>, uniform:<min>,<max> or lognormal:<median>,<sigma>", s)
+	}
+	if dist.A < minSeedBalance {
+		return BalanceDistribution{}, fmt.Errorf("balance distribution %q allows balances below %.2f", s, minSeedBalance)
+	}
+	return dist, nil
+}
+
+func (d BalanceDistribution) String() string {
+	if d.Kind == FixedBalance {
+		return fmt.Sprintf("%s:%g", d.Kind, d.A)
+	}
+	return fmt.Sprintf("%s:%g,%g", d.Kind, d.A, d.B)
+}
+
+// Sample draws a balance, rounded to cents
+func (d BalanceDistribution) Sample(rng *rand.Rand) float64 {
+	var balance float64
+	switch d.Kind {
+	case UniformBalance:
+		balance = d.A + rng.Float64()*(d.B-d.A)
+	case LognormalBalance:
+		balance = d.A * math.Exp(d.B*rng.NormFloat64())
+	default:
+		balance = d.A
+	}
+	return max(math.Round(balance*100)/100, minSeedBalance)
+}
+
+// DatasetAccount is a seeded account and the balance it was opened with
+type DatasetAccount struct {
+	ID      string
+	Balance float64
+}
+
+// Dataset is a file of seeded accounts, CSV with an "id,balance" header, handing out
+// every account at most once per run in random order
+type Dataset struct {
+	Path     string
+	accounts []DatasetAccount
+	ids      map[string]struct{} // Of every account, for Has
+
+	mu   sync.Mutex
+	next int
+}
+
+// LoadDataset reads a dataset file written by the seed command
+func LoadDataset(path string) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer file.Close()
+
+	accounts, err := ReadDataset(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset %s: %w", path, err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("dataset %s has no accounts", path)
+	}
+	rand.Shuffle(len(accounts), func(i, j int) { accounts[i], accounts[j] = accounts[j], accounts[i] })
+	return newDataset(path, accounts), nil
+}
+
+// newDataset indexes the accounts of a dataset
+func newDataset(path string, accounts []DatasetAccount) *Dataset {
+	ids := make(map[string]struct{}, len(accounts))
+	for _, account := range accounts {
+		ids[account.ID] = struct{}{}
+	}
+	return &Dataset{Path: path, accounts: accounts, ids: ids}
+}
+
+// ReadDataset reads the accounts of a dataset file
+func ReadDataset(r io.Reader) ([]DatasetAccount, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.ReuseRecord = true
+	if _, err := cr.Read(); err != nil { // Header
+		return nil, err
+	}
+	var accounts []DatasetAccount
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return accounts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		balance, err := strconv.ParseFloat(record[1], 64)
+		if err != nil || record[0] == "" {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("invalid account on line %d", line)
+		}
+		accounts = append(accounts, DatasetAccount{ID: record[0], Balance: balance})
+	}
+}
+
+// DatasetWriter writes a dataset file as accounts are seeded
+type DatasetWriter struct {
+	cw *csv.Writer
+}
+
+// NewDatasetWriter writes the header of a dataset file to w
+func NewDatasetWriter(w io.Writer) (*DatasetWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "balance"}); err != nil {
+		return nil, err
+	}
+	return &DatasetWriter{cw: cw}, nil
+}
+
+// Write buffers an account, Flush writes it out
+func (w *DatasetWriter) Write(account DatasetAccount) error {
+	return w.cw.Write([]string{account.ID, strconv.FormatFloat(account.Balance, 'f', 2, 64)})
+}
+
+// Flush writes the buffered accounts
+func (w *DatasetWriter) Flush() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+// Len returns how many accounts the dataset holds
+func (d *Dataset) Len() int {
+	return len(d.accounts)
+}
+
+// Has tells whether the account is one of the dataset's
+func (d *Dataset) Has(accountID string) bool {
+	_, ok := d.ids[accountID]
+	return ok
+}
+
+// Customers creates count customers named <prefix>-<i> of accounts the dataset hasn't
+// handed out yet, holding at least minBalance so the scenario can't drain them. Their
+// ledgers start at the balances the accounts hold now, which earlier runs may have
+// moved; accounts they left below minBalance are skipped for the rest of the run.
+func (d *Dataset) Customers(ctx context.Context, client *mybankclient.Client, prefix string, count int, minBalance float64) ([]*domain.Customer, error) {
+	customers := make([]*domain.Customer, 0, count)
+	for len(customers) < count {
+		account, ok := d.take(minBalance)
+		if !ok {
+			return nil, fmt.Errorf("dataset %s ran out of accounts holding at least %.2f after %d of %d %s customers", d.Path, minBalance, len(customers), count, prefix)
+		}
+		current, err := client.GetAccount(ctx, account.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dataset account %s: %w", account.ID, err)
+		}
+		if current.Balance < minBalance {
+			continue
+		}
+		customers = append(customers, domain.NewCustomerForAccount(fmt.Sprintf("%s-%d", prefix, len(customers)), account.ID, current.Balance))
+	}
+	return customers, nil
+}
+
+// take hands out the next account seeded with at least minBalance, the accounts
+// seeded with less can't hold it unless earlier runs paid them. The accounts it skips
+// stay available to later calls.
+func (d *Dataset) take(minBalance float64) (DatasetAccount, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := d.next; i < len(d.accounts); i++ {
+		if d.accounts[i].Balance >= minBalance {
+			d.accounts[d.next], d.accounts[i] = d.accounts[i], d.accounts[d.next]
+			d.next++
+			return d.accounts[d.next-1], true
+		}
+	}
+	return DatasetAccount{}, false
+}
+
+// bankClient returns an API client of the bank, sending through domain.HTTPClient like
+// the customers do
+func bankClient() *mybankclient.Client {
+	return mybankclient.New(utils.BASE_URL, mybankclient.WithHTTPClient(domain.HTTPClient))
+}
@@ -0,0 +1,321 @@
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Scenarios a worker can run
+const (
+	DistributedAccounts  = "accounts"  // GET /accounts
+	DistributedTransfers = "transfers" // Transfers from the worker's sources to the shared destinations
+)
+
+// syncDelay is how far ahead the coordinator schedules the start, long enough for every
+// worker to receive its assignment. Workers rely on clocks synchronized well below it.
+const syncDelay = 2 * time.Second
+
+// Assignment is the slice of a distributed run one worker executes
+type Assignment struct {
+	RunID     string        `json:"run_id"`
+	Scenario  string        `json:"scenario"`
+	TargetURL string        `json:"target_url"`
+	RPS       int           `json:"rps"`
+	Duration  time.Duration `json:"duration"`
+	StartAt   time.Time     `json:"start_at"`
+	Sources   []string      `json:"sources,omitempty"` // Accounts only this worker transfers from
+	Dests     []string      `json:"dests,omitempty"`   // Accounts every worker transfers to
+}
+
+// LedgerEntry is the total a worker transferred between two accounts
+type LedgerEntry struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// workerMessage is one line of a worker's stream: a result while the attack runs, the
+// ledger when it ended, or an error ending the stream
+type workerMessage struct {
+	Result *vegeta.Result `json:"result,omitempty"`
+	Ledger []LedgerEntry  `json:"ledger,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// splitAssignments divides the rate and the source accounts of a run among the workers.
+// Sources are dealt round-robin so no two workers debit the same account. Every
+// assignment gets at least 1 RPS and, for transfers, a source account, so there are
+// fewer assignments than workers when the run has less to share.
+func splitAssignments(base Assignment, workers int, sources []string) []Assignment {
+	workers = min(workers, base.RPS)
+	if base.Scenario == DistributedTransfers {
+		workers = min(workers, len(sources))
+	}
+	if workers <= 0 {
+		return nil
+	}
+	assignments := make([]Assignment, workers)
+	for i := range assignments {
+		assignments[i] = base
+		assignments[i].Sources = nil
+		assignments[i].RPS = base.RPS / workers
+		if i < base.RPS%workers {
+			assignments[i].RPS++
+		}
+	}
+	for i, source := range sources {
+		assignments[i%workers].Sources = append(assignments[i%workers].Sources, source)
+	}
+	return assignments
+}
+
+// runWorker sends an assignment to a worker and hands every streamed result to results,
+// labeled by the worker. It returns the worker's ledger.
+func runWorker(ctx context.Context, worker string, assignment Assignment, results chan<- labeledResult) ([]LedgerEntry, error) {
+	body, err := json.Marshal(assignment)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, worker+"/run", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach worker: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("worker rejected the assignment with status: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg workerMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("failed to decode worker stream: %w", err)
+		}
+		switch {
+		case msg.Error != "":
+			return nil, fmt.Errorf("worker failed: %s", msg.Error)
+		case msg.Result != nil:
+			results <- labeledResult{label: worker, res: msg.Result}
+		default:
+			return msg.Ledger, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read worker stream: %w", err)
+	}
+	return nil, fmt.Errorf("worker stream ended without a ledger")
+}
+
+// coordinate runs the assignments on the workers, recording the merged results on the
+// calling goroutine, and returns every worker's ledger
+func coordinate(run *Run, qm *QueueMetrics, perWorker *MetricsBreakdown, workers []string, assignments []Assignment) ([][]LedgerEntry, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan labeledResult, 1024)
+	ledgers := make([][]LedgerEntry, len(workers))
+	errs := make([]error, len(workers))
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		wg.Add(1)
+		go func(i int, worker string) {
+			defer wg.Done()
+			ledgers[i], errs[i] = runWorker(ctx, worker, assignments[i], results)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", worker, errs[i])
+				cancel()
+			}
+		}(i, worker)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// An interrupt stops the workers, which still stream the results in flight and
+	// their ledgers, so the partial run is merged and verified
+	go func() {
+		select {
+		case <-interruptContext().Done():
+			stopWorkers(workers)
+		case <-ctx.Done():
+		}
+	}()
+
+	// The time series starts with the attack, not with the assignments
+	select {
+	case <-time.After(time.Until(assignments[0].StartAt)):
+	case <-ctx.Done():
+	case <-interruptContext().Done():
+	}
+	collectResults(run, qm, perWorker, results)
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ledgers, nil
+}
+
+// stopWorkers asks every worker to stop its assignment early
+func stopWorkers(workers []string) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	for _, worker := range workers {
+		resp, err := client.Post(worker+"/stop", "application/json", nil)
+		if err != nil {
+			fmt.Printf("Failed to stop worker %s: %v\n", worker, err)
+			continue
+		}
+		resp.Body.Close()
+	}
+}
+
+// applyLedgers records the transfers of every worker on the coordinator's customers
+func applyLedgers(customers []*domain.Customer, ledgers [][]LedgerEntry) error {
+	byAccount := make(map[string]*domain.Customer, len(customers))
+	for _, customer := range customers {
+		byAccount[customer.GetAccountID()] = customer
+	}
+	for _, ledger := range ledgers {
+		for _, entry := range ledger {
+			from, to := byAccount[entry.From], byAccount[entry.To]
+			if from == nil || to == nil {
+				return fmt.Errorf("ledger entry %s -> %s names an unknown account", entry.From, entry.To)
+			}
+			if err := from.RecordTransfer(to, entry.Amount); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AttackDistributed runs a scenario on the workers, started in sync, and merges their
+// results and ledgers into one report
+func AttackDistributed(workers []string, scenario string, rps, testDuration int) {
+	fmt.Printf("Starting distributed %s attack: %d RPS for %d seconds on %d workers\n", scenario, rps, testDuration, len(workers))
+	if rps <= 0 || testDuration <= 0 {
+		fmt.Printf("Invalid distributed run: RPS and duration must be positive\n")
+		return
+	}
+
+	base := Assignment{
+		Scenario:  scenario,
+		TargetURL: utils.BASE_URL,
+		RPS:       rps,
+		Duration:  time.Duration(testDuration) * time.Second,
+	}
+	var customers []*domain.Customer
+	var sources []string
+	var initialTotal float64
+	switch scenario {
+	case DistributedAccounts:
+	case DistributedTransfers:
+		fmt.Printf("Setting up test customers...\n")
+		sourceCustomers, destCustomers, total, err := setupTransferCustomers()
+		if err != nil {
+			fmt.Printf("Failed to setup customers: %v\n", err)
+			return
+		}
+		customers, initialTotal = append(sourceCustomers, destCustomers...), total
+		defer cleanupTransferCustomers(customers)
+		for _, customer := range sourceCustomers {
+			sources = append(sources, customer.GetAccountID())
+		}
+		for _, customer := range destCustomers {
+			base.Dests = append(base.Dests, customer.GetAccountID())
+		}
+		fmt.Printf("Created %d source customers and %d destination customers\n", len(sourceCustomers), len(destCustomers))
+	default:
+		fmt.Printf("Unknown distributed scenario %q, expected %s or %s\n", scenario, DistributedAccounts, DistributedTransfers)
+		return
+	}
+
+	queueMetrics := NewQueueMetrics()
+	perWorker := NewMetricsBreakdownWithHistogram(LatencyBuckets)
+	run := newRun("distributed-" + scenario)
+	run.config.RPS = rps
+	run.config.Duration = base.Duration
+	base.RunID = run.ID
+	base.StartAt = time.Now().Add(syncDelay)
+	assignments := splitAssignments(base, len(workers), sources)
+	if len(assignments) < len(workers) {
+		fmt.Printf("Leaving out %d of %d workers, %d RPS and %d source accounts only go round %d\n",
+			len(workers)-len(assignments), len(workers), rps, len(sources), len(assignments))
+		workers = workers[:len(assignments)]
+	}
+	run.SetParam("workers", fmt.Sprint(workers))
+	for i, worker := range workers {
+		fmt.Printf("  %s: %d RPS, %d source accounts\n", worker, assignments[i].RPS, len(assignments[i].Sources))
+	}
+	fmt.Printf("Starting at %s\n", base.StartAt.Format("15:04:05.000"))
+
+	ledgers, err := coordinate(run, queueMetrics, perWorker, workers, assignments)
+	queueMetrics.Close()
+	perWorker.Close()
+	if err != nil {
+		fmt.Printf("Distributed attack failed: %v\n", err)
+		run.Finish()
+		return
+	}
+	fmt.Printf("Attack completed!\n\n")
+
+	var verification BalanceVerification
+	if scenario == DistributedTransfers {
+		if err := applyLedgers(customers, ledgers); err != nil {
+			fmt.Printf("Failed to merge worker ledgers: %v\n", err)
+		}
+		verification = verifyTotalBalance(customers, initialTotal)
+		run.RecordVerification(verification)
+	}
+	run.Finish()
+
+	queueMetrics.PrintReport()
+	perWorker.PrintReport("PER-WORKER RESULTS")
+
+	fmt.Printf("\n=== Detailed Report ===\n")
+	reportFile, err := os.OpenFile("distributed_attack_report.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	timestamp := fmt.Sprintf("==== Distributed %s Run at %s ===\n", scenario, time.Now().Format("2006-01-02 15:04:05"))
+	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, queueMetrics)
+	reportFile.WriteString(fmt.Sprintf("Workers: %v\n", workers))
+	if scenario == DistributedTransfers {
+		reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, verification.FinalTotal))
+	}
+	vegeta.NewTextReporter(queueMetrics.Metrics)(reportFile)
+	for _, label := range perWorker.Labels() {
+		reportFile.WriteString(fmt.Sprintf("--- Worker: %s\n", label))
+		vegeta.NewTextReporter(perWorker.Get(label))(reportFile)
+	}
+	reportFile.WriteString("\n\n")
+	fmt.Printf("Report appended to distributed_attack_report.txt\n")
+
+	report := run.Report(queueMetrics)
+	report.Breakdowns = map[string][]BreakdownEntry{"workers": perWorker.Entries()}
+	saveStructuredReport("distributed_attack_report", report)
+}
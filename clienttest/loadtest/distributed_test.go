@@ -0,0 +1,238 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransferBank counts the transfers it accepts per pair of accounts, rejecting
+// every transfer from rejectFrom
+type fakeTransferBank struct {
+	mu         sync.Mutex
+	transfers  map[[2]string]int
+	rejectFrom string
+}
+
+func newFakeTransferBank(t *testing.T) (*fakeTransferBank, string) {
+	bank := &fakeTransferBank{transfers: map[[2]string]int{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var transfer domain.TransferRequest
+		if err := json.NewDecoder(r.Body).Decode(&transfer); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if transfer.FromAccountID == bank.rejectFrom {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		bank.mu.Lock()
+		bank.transfers[[2]string{transfer.FromAccountID, transfer.ToAccountID}]++
+		bank.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return bank, server.URL
+}
+
+// accountOperator is a bank operator that only knows its account, for customers whose
+// balances the tests never read
+type accountOperator struct{ id string }
+
+func (o accountOperator) GetAccount(string) (*domain.AccountInfo, error) { return nil, nil }
+func (o accountOperator) GetAccountBalance() (float64, error)            { return 0, nil }
+func (o accountOperator) CreateAccount() (*domain.AccountInfo, error)    { return nil, nil }
+func (o accountOperator) TransferTo(domain.BankOperator, float64) error  { return nil }
+func (o accountOperator) GetAccountId() string                           { return o.id }
+func (o accountOperator) GetName() string                                { return o.id }
+
+func TestSplitAssignments(t *testing.T) {
+	base := Assignment{RPS: 10, Dests: []string{"d"}}
+	assignments := splitAssignments(base, 3, []string{"s0", "s1", "s2", "s3"})
+
+	require.Len(t, assignments, 3)
+	assert.Equal(t, []int{4, 3, 3}, []int{assignments[0].RPS, assignments[1].RPS, assignments[2].RPS})
+	assert.Equal(t, []string{"s0", "s3"}, assignments[0].Sources)
+	assert.Equal(t, []string{"s1"}, assignments[1].Sources)
+	assert.Equal(t, []string{"d"}, assignments[2].Dests, "destinations are shared")
+}
+
+func TestSplitAssignmentsUnevenly(t *testing.T) {
+	sources := []string{"s0", "s1", "s2"}
+	assignments := splitAssignments(Assignment{Scenario: DistributedTransfers, RPS: 100}, 5, sources)
+	require.Len(t, assignments, 3, "one source account each")
+	for _, assignment := range assignments {
+		assert.Len(t, assignment.Sources, 1)
+	}
+	assert.Equal(t, []int{34, 33, 33}, []int{assignments[0].RPS, assignments[1].RPS, assignments[2].RPS})
+
+	assignments = splitAssignments(Assignment{Scenario: DistributedTransfers, RPS: 2}, 5, sources)
+	require.Len(t, assignments, 2, "1 RPS each")
+	assert.Equal(t, []string{"s0", "s2"}, assignments[0].Sources, "every source is still used")
+	assert.Equal(t, 1, assignments[1].RPS)
+
+	assert.Len(t, splitAssignments(Assignment{Scenario: DistributedAccounts, RPS: 3}, 5, nil), 3)
+	assert.Empty(t, splitAssignments(Assignment{Scenario: DistributedTransfers, RPS: 0}, 5, sources))
+}
+
+func TestCoordinateMergesWorkers(t *testing.T) {
+	defer func(metricsURL string) { ServerMetricsURL = metricsURL }(ServerMetricsURL)
+	ServerMetricsURL = ""
+
+	bank, bankURL := newFakeTransferBank(t)
+	var workers []string
+	for i := 0; i < 3; i++ {
+		server := httptest.NewServer(NewWorkerHandler())
+		defer server.Close()
+		workers = append(workers, server.URL)
+	}
+
+	sources := []string{"s0", "s1", "s2", "s3", "s4", "s5"}
+	base := Assignment{
+		RunID:     "test",
+		Scenario:  DistributedTransfers,
+		TargetURL: bankURL,
+		RPS:       60,
+		Duration:  time.Second,
+		StartAt:   time.Now().Add(200 * time.Millisecond),
+		Dests:     []string{"d0", "d1"},
+	}
+	qm := NewQueueMetrics()
+	perWorker := NewMetricsBreakdownWithHistogram(LatencyBuckets)
+	ledgers, err := coordinate(newRun("test"), qm, perWorker, workers, splitAssignments(base, len(workers), sources))
+	qm.Close()
+	perWorker.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(60), qm.Requests)
+	assert.Equal(t, 1.0, qm.Success)
+	assert.ElementsMatch(t, workers, perWorker.Labels())
+	for _, worker := range workers {
+		assert.Equal(t, uint64(20), perWorker.Get(worker).Requests)
+	}
+	assert.False(t, qm.Earliest.Before(base.StartAt), "workers start in sync")
+
+	// The merged ledgers account for every transfer the bank received
+	var customers []*domain.Customer
+	for _, id := range append(sources, base.Dests...) {
+		customers = append(customers, domain.NewCustomerWithOperator(accountOperator{id}, 100))
+	}
+	require.NoError(t, applyLedgers(customers, ledgers))
+	var total float64
+	for _, customer := range customers {
+		total += customer.ExpectedBalance()
+	}
+	assert.Equal(t, 800.0, total)
+	bank.mu.Lock()
+	defer bank.mu.Unlock()
+	received := 0
+	for _, count := range bank.transfers {
+		received += count
+	}
+	assert.Equal(t, 60, received)
+	sent := bank.transfers[[2]string{"s0", "d0"}] + bank.transfers[[2]string{"s0", "d1"}]
+	assert.Equal(t, 100.0-float64(sent), customers[0].ExpectedBalance())
+
+	assert.Error(t, applyLedgers(customers, [][]LedgerEntry{{{From: "s0", To: "unknown", Amount: 1}}}))
+}
+
+func TestWorkerRejectsInvalidAssignments(t *testing.T) {
+	server := httptest.NewServer(NewWorkerHandler())
+	defer server.Close()
+
+	_, err := runWorker(context.Background(), server.URL, Assignment{Scenario: DistributedTransfers, RPS: 1, Duration: time.Second}, nil)
+	assert.ErrorContains(t, err, "status: 400")
+	_, err = runWorker(context.Background(), server.URL, Assignment{Scenario: "deposits", RPS: 1, Duration: time.Second}, nil)
+	assert.ErrorContains(t, err, "status: 400")
+}
+
+func TestWorkerLedgerOnlyTalliesAcceptedTransfers(t *testing.T) {
+	bank, bankURL := newFakeTransferBank(t)
+	bank.rejectFrom = "s1"
+	server := httptest.NewServer(NewWorkerHandler())
+	defer server.Close()
+
+	results := make(chan labeledResult, 100)
+	ledger, err := runWorker(context.Background(), server.URL, Assignment{
+		RunID:     "test",
+		Scenario:  DistributedTransfers,
+		TargetURL: bankURL,
+		RPS:       40,
+		Duration:  500 * time.Millisecond,
+		StartAt:   time.Now(),
+		Sources:   []string{"s0", "s1"},
+		Dests:     []string{"d0"},
+	}, results)
+	require.NoError(t, err)
+	close(results)
+
+	rejected := 0
+	for result := range results {
+		if result.res.Code == http.StatusConflict {
+			rejected++
+		}
+	}
+	assert.Positive(t, rejected)
+	bank.mu.Lock()
+	defer bank.mu.Unlock()
+	require.Len(t, ledger, 1, "nothing from s1")
+	assert.Equal(t, LedgerEntry{From: "s0", To: "d0", Amount: float64(bank.transfers[[2]string{"s0", "d0"}]), Count: bank.transfers[[2]string{"s0", "d0"}]}, ledger[0])
+	assert.Equal(t, 20, rejected+ledger[0].Count)
+}
+
+func TestCoordinateInterrupted(t *testing.T) {
+	withInterrupts(t)
+	defer func(metricsURL string) { ServerMetricsURL = metricsURL }(ServerMetricsURL)
+	ServerMetricsURL = ""
+
+	bank, bankURL := newFakeTransferBank(t)
+	var workers []string
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(NewWorkerHandler())
+		defer server.Close()
+		workers = append(workers, server.URL)
+	}
+	base := Assignment{
+		RunID:     "test",
+		Scenario:  DistributedTransfers,
+		TargetURL: bankURL,
+		RPS:       40,
+		Duration:  10 * time.Second,
+		StartAt:   time.Now().Add(100 * time.Millisecond),
+		Dests:     []string{"d0"},
+	}
+	qm := NewQueueMetrics()
+	perWorker := NewMetricsBreakdownWithHistogram(LatencyBuckets)
+	time.AfterFunc(500*time.Millisecond, Interrupt)
+
+	began := time.Now()
+	ledgers, err := coordinate(newRun("test"), qm, perWorker, workers, splitAssignments(base, len(workers), []string{"s0", "s1"}))
+	qm.Close()
+	perWorker.Close()
+	require.NoError(t, err)
+	assert.Less(t, time.Since(began), 3*time.Second, "the workers stopped long before 10s")
+	assert.Positive(t, qm.Requests)
+
+	// The ledgers still account for every transfer the bank received
+	tallied := 0
+	for _, ledger := range ledgers {
+		for _, entry := range ledger {
+			tallied += entry.Count
+		}
+	}
+	bank.mu.Lock()
+	defer bank.mu.Unlock()
+	received := 0
+	for _, count := range bank.transfers {
+		received += count
+	}
+	assert.Equal(t, received, tallied)
+	assert.Equal(t, int(qm.Requests), received)
+}
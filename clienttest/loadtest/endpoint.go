@@ -0,0 +1,50 @@
+package loadtest
+
+import (
+	"net/url"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Endpoints of the MyBank API as they appear in reports
+const (
+	EndpointGetAccount    = "GET /accounts/{id}"
+	EndpointListAccounts  = "GET /accounts"
+	EndpointCreateAccount = "POST /accounts"
+	EndpointTransfer      = "POST /accounts/transfer"
+)
+
+// ClassifyEndpoint maps a result to the API endpoint it hit, with path
+// parameters replaced by placeholders so all account reads share one key
+func ClassifyEndpoint(res *vegeta.Result) string {
+	path := res.URL
+	if u, err := url.Parse(res.URL); err == nil {
+		path = u.Path
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	if strings.HasPrefix(path, "/accounts/") && path != "/accounts/transfer" && !strings.Contains(path[len("/accounts/"):], "/") {
+		path = "/accounts/{id}"
+	}
+	return res.Method + " " + path
+}
+
+// StatusClass groups a result's status code into 2xx, 3xx, 4xx, 5xx, or
+// "error" when no response was received at all
+func StatusClass(res *vegeta.Result) string {
+	switch {
+	case res.Code == 0:
+		return "error"
+	case res.Code < 200:
+		return "1xx"
+	case res.Code < 300:
+		return "2xx"
+	case res.Code < 400:
+		return "3xx"
+	case res.Code < 500:
+		return "4xx"
+	default:
+		return "5xx"
+	}
+}
@@ -0,0 +1,165 @@
+package loadtest
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+	"time"
+)
+
+// HDRHistogram is a high dynamic range latency histogram in the spirit of HdrHistogram.
+// Latencies are recorded in microseconds with a fixed number of significant digits,
+// so tail percentiles such as p99.99 stay accurate whether requests take 200µs or 20s.
+type HDRHistogram struct {
+	subBucketBits uint     // log2 of the number of exact values in the first bucket
+	counts        []uint64 // Grows on demand as larger values are recorded
+	total         uint64
+	min, max      time.Duration
+}
+
+// NewHDRHistogram creates a histogram keeping the given number of significant
+// decimal digits (1 to 5), e.g. 3 keeps every value within 0.1%
+func NewHDRHistogram(significantDigits int) *HDRHistogram {
+	if significantDigits < 1 {
+		significantDigits = 1
+	}
+	if significantDigits > 5 {
+		significantDigits = 5
+	}
+	// Twice the resolution is needed so that the upper half of every bucket
+	// still distinguishes 10^digits values
+	largestExact := 2 * uint64(math.Pow10(significantDigits))
+	return &HDRHistogram{
+		subBucketBits: uint(bits.Len64(largestExact - 1)),
+	}
+}
+
+// index returns the counts slot of a value: values below the sub-bucket count
+// are stored exactly, larger ones share a slot with values of the same top bits
+func (h *HDRHistogram) index(v uint64) int {
+	subBucketCount := uint64(1) << h.subBucketBits
+	if v < subBucketCount {
+		return int(v)
+	}
+	half := subBucketCount / 2
+	shift := uint(bits.Len64(v)) - h.subBucketBits
+	top := v >> shift
+	return int(subBucketCount + uint64(shift-1)*half + (top - half))
+}
+
+// highestEquivalent returns the largest value stored in the same slot as index
+func (h *HDRHistogram) highestEquivalent(index int) uint64 {
+	subBucketCount := uint64(1) << h.subBucketBits
+	if uint64(index) < subBucketCount {
+		return uint64(index)
+	}
+	half := subBucketCount / 2
+	k := uint64(index) - subBucketCount
+	shift := k/half + 1
+	top := k%half + half
+	return ((top + 1) << shift) - 1
+}
+
+// Record adds a latency to the histogram
+func (h *HDRHistogram) Record(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	i := h.index(uint64(latency / time.Microsecond))
+	if i >= len(h.counts) {
+		grown := make([]uint64, i+1)
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	h.counts[i]++
+
+	if h.total == 0 || latency < h.min {
+		h.min = latency
+	}
+	if latency > h.max {
+		h.max = latency
+	}
+	h.total++
+}
+
+// Total returns the number of recorded latencies
+func (h *HDRHistogram) Total() uint64 {
+	return h.total
+}
+
+// Max returns the largest recorded latency
+func (h *HDRHistogram) Max() time.Duration {
+	return h.max
+}
+
+// Quantile returns the latency below which the given fraction (0 to 1) of requests fall
+func (h *HDRHistogram) Quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return h.min
+	}
+	target := uint64(math.Ceil(q * float64(h.total)))
+	if target > h.total {
+		target = h.total
+	}
+
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= target {
+			value := time.Duration(h.highestEquivalent(i)) * time.Microsecond
+			if value > h.max {
+				return h.max
+			}
+			return value
+		}
+	}
+	return h.max
+}
+
+// ReportedQuantiles are the percentiles shown in reports, including the far tail
+var ReportedQuantiles = []float64{0.5, 0.9, 0.99, 0.999, 0.9999}
+
+// PercentileEntry is one quantile of a latency distribution
+type PercentileEntry struct {
+	Quantile float64       `json:"quantile"`
+	Latency  time.Duration `json:"latency"`
+}
+
+// Percentiles returns the ReportedQuantiles of the histogram
+func (h *HDRHistogram) Percentiles() []PercentileEntry {
+	entries := make([]PercentileEntry, 0, len(ReportedQuantiles))
+	for _, q := range ReportedQuantiles {
+		entries = append(entries, PercentileEntry{Quantile: q, Latency: h.Quantile(q)})
+	}
+	return entries
+}
+
+// percentileName renders a quantile as e.g. "p99.99"
+func percentileName(q float64) string {
+	// Round away float noise such as 0.999*100 = 99.89999...
+	return "p" + strconv.FormatFloat(math.Round(q*1e6)/1e4, 'f', -1, 64)
+}
+
+// HistogramBin is one non-empty slot of an HDRHistogram: Count latencies up to Latency
+type HistogramBin struct {
+	Latency time.Duration `json:"latency"`
+	Count   uint64        `json:"count"`
+}
+
+// Bins returns the non-empty slots in increasing latency order, a compact form of
+// every recorded latency that reports store for later statistical comparison
+func (h *HDRHistogram) Bins() []HistogramBin {
+	var bins []HistogramBin
+	for i, count := range h.counts {
+		if count > 0 {
+			bins = append(bins, HistogramBin{
+				Latency: time.Duration(h.highestEquivalent(i)) * time.Microsecond,
+				Count:   count,
+			})
+		}
+	}
+	return bins
+}
@@ -0,0 +1,73 @@
+package loadtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestHDRHistogramIndexRoundTrip(t *testing.T) {
+	h := NewHDRHistogram(3)
+	for _, v := range []uint64{0, 1, 2047, 2048, 2049, 4095, 4096, 123456, 3_600_000_000} {
+		i := h.index(v)
+		high := h.highestEquivalent(i)
+		assert.GreaterOrEqual(t, high, v, "value %d", v)
+		assert.LessOrEqual(t, float64(high-v), float64(v)*0.001+1, "value %d stored too coarsely", v)
+		assert.Equal(t, i, h.index(high), "value %d", v)
+	}
+}
+
+func TestHDRHistogramTailPercentiles(t *testing.T) {
+	h := NewHDRHistogram(3)
+	// 9990 fast requests and a 10 request tail, like a worker queue that briefly backs up
+	for i := 0; i < 9990; i++ {
+		h.Record(time.Duration(1000+i%100) * time.Microsecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Record(time.Duration(2+i) * time.Second)
+	}
+
+	assert.Equal(t, uint64(10000), h.Total())
+	assert.InDelta(t, float64(1050*time.Microsecond), float64(h.Quantile(0.5)), float64(2*time.Microsecond))
+	assert.Less(t, h.Quantile(0.99), 2*time.Millisecond)
+	assert.InDelta(t, float64(2*time.Second), float64(h.Quantile(0.9991)), float64(3*time.Millisecond))
+	assert.InDelta(t, float64(10*time.Second), float64(h.Quantile(0.9999)), float64(10*time.Millisecond))
+	assert.Equal(t, h.Max(), h.Quantile(1))
+}
+
+func TestPercentileName(t *testing.T) {
+	assert.Equal(t, "p50", percentileName(0.5))
+	assert.Equal(t, "p99.9", percentileName(0.999))
+	assert.Equal(t, "p99.99", percentileName(0.9999))
+}
+
+func TestParseLatencyBuckets(t *testing.T) {
+	buckets, err := ParseLatencyBuckets("5ms, 10ms,1s")
+	require.NoError(t, err)
+	assert.Equal(t, vegeta.Buckets{0, 5 * time.Millisecond, 10 * time.Millisecond, time.Second}, buckets)
+
+	_, err = ParseLatencyBuckets("10ms,5ms")
+	assert.Error(t, err)
+	_, err = ParseLatencyBuckets("fast")
+	assert.Error(t, err)
+}
+
+func TestRenderHistogram(t *testing.T) {
+	h := &vegeta.Histogram{Buckets: vegeta.Buckets{0, 10 * time.Millisecond}}
+	for i := 0; i < 99; i++ {
+		h.Add(&vegeta.Result{Latency: time.Millisecond})
+	}
+	h.Add(&vegeta.Result{Latency: time.Second})
+
+	var buf bytes.Buffer
+	RenderHistogram(&buf, h, 20)
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], strings.Repeat("█", 20)+" 99 (99.00%)")
+	assert.Contains(t, lines[1], "[10ms, +Inf) █"+strings.Repeat(" ", 19)+" 1 (1.00%)")
+}
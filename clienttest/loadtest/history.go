@@ -0,0 +1,261 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryDir is where every run is recorded, "" to disable the history
+var HistoryDir = "loadtest_history"
+
+// historyIndexFile lists every recorded run, the full reports live next to it
+const historyIndexFile = "index.json"
+
+// Environment describes the machine a run was executed on
+type Environment struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	GoVersion string `json:"go_version"`
+	GitCommit string `json:"git_commit,omitempty"` // Of the working directory, when it is a git checkout
+}
+
+// CurrentEnvironment describes the machine this process runs on
+func CurrentEnvironment() *Environment {
+	hostname, _ := os.Hostname()
+	env := &Environment{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+	if out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output(); err == nil {
+		env.GitCommit = strings.TrimSpace(string(out))
+	}
+	return env
+}
+
+// HistoryEntry is the index entry of one recorded run, enough to list and trend runs
+// without reading every report
+type HistoryEntry struct {
+	RunID           string        `json:"run_id"`
+	Scenario        string        `json:"scenario"`
+	Timestamp       time.Time     `json:"timestamp"`
+	File            string        `json:"file"` // Of the full report, relative to the history directory
+	RPS             int           `json:"rps"`
+	Duration        time.Duration `json:"duration"`
+	Requests        uint64        `json:"requests"`
+	Throughput      float64       `json:"throughput"`
+	Success         float64       `json:"success"`
+	Mean            time.Duration `json:"mean"`
+	P50             time.Duration `json:"p50"`
+	P99             time.Duration `json:"p99"`
+	MeanBytesIn     float64       `json:"mean_bytes_in"` // Mean response size
+	Verified        *bool         `json:"verified,omitempty"`
+	ClientSaturated bool          `json:"client_saturated"`
+	Interrupted     bool          `json:"interrupted,omitempty"` // Duration is how long it ran then
+	GitCommit       string        `json:"git_commit,omitempty"`
+}
+
+// newHistoryEntry summarizes a report for the index
+func newHistoryEntry(report QueueReport, file string) HistoryEntry {
+	entry := HistoryEntry{
+		RunID:           report.RunID,
+		Scenario:        report.Scenario,
+		Timestamp:       report.Timestamp,
+		File:            file,
+		Verified:        report.Verified,
+		ClientSaturated: report.ClientSaturated,
+	}
+	if report.Config != nil {
+		entry.RPS = report.Config.RPS
+		entry.Duration = report.Config.Duration
+	}
+	if report.Interrupted != nil {
+		entry.Interrupted = true
+		entry.Duration = report.Interrupted.After.Round(time.Second)
+	}
+	if report.Environment != nil {
+		entry.GitCommit = report.Environment.GitCommit
+	}
+	if report.Overall != nil {
+		entry.Requests = report.Overall.Requests
+		entry.Throughput = report.Overall.Throughput
+		entry.Success = report.Overall.Success
+		entry.Mean = report.Overall.Latencies.Mean
+		entry.MeanBytesIn = report.Overall.BytesIn.Mean
+	}
+	entry.P50, _ = percentileOf(report, 0.5)
+	entry.P99, _ = percentileOf(report, 0.99)
+	return entry
+}
+
+// History is a directory of run reports, one JSON file per run, with an index
+type History struct {
+	dir string
+}
+
+// OpenHistory opens the history in dir, creating the directory if needed
+func OpenHistory(dir string) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &History{dir: dir}, nil
+}
+
+// Record stores the report and adds it to the index
+func (h *History) Record(report QueueReport) (HistoryEntry, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	name := report.RunID
+	if name == "" {
+		name = report.Scenario + "-" + report.Timestamp.Format("20060102-150405")
+	}
+	// Two runs started within the same second share an ID
+	file := name + ".json"
+	for i := 2; h.exists(file); i++ {
+		file = fmt.Sprintf("%s-%d.json", name, i)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(h.dir, file), data); err != nil {
+		return HistoryEntry{}, err
+	}
+
+	entry := newHistoryEntry(report, file)
+	if err := h.writeIndex(append(entries, entry)); err != nil {
+		return HistoryEntry{}, err
+	}
+	return entry, nil
+}
+
+func (h *History) exists(file string) bool {
+	_, err := os.Stat(filepath.Join(h.dir, file))
+	return err == nil
+}
+
+// Entries returns the index, oldest run first
+func (h *History) Entries() ([]HistoryEntry, error) {
+	data, err := os.ReadFile(filepath.Join(h.dir, historyIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history index: %w", err)
+	}
+	var entries []HistoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode history index: %w", err)
+	}
+	return entries, nil
+}
+
+func (h *History) writeIndex(entries []HistoryEntry) error {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history index: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(h.dir, historyIndexFile), data)
+}
+
+// Load reads the full report of a run, the latest one when several share the ID
+func (h *History) Load(runID string) (QueueReport, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return QueueReport{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].RunID != runID {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(h.dir, entries[i].File))
+		if err != nil {
+			return QueueReport{}, fmt.Errorf("failed to read run %s: %w", runID, err)
+		}
+		var report QueueReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return QueueReport{}, fmt.Errorf("failed to decode run %s: %w", runID, err)
+		}
+		return report, nil
+	}
+	return QueueReport{}, fmt.Errorf("run %s not found in history %s", runID, h.dir)
+}
+
+// HistoryFilter selects runs of the history. Zero fields match every run.
+type HistoryFilter struct {
+	Scenario string
+	Since    time.Time
+	Verified *bool // Only runs whose verification passed (true) or failed (false)
+	Last     int   // Keep only the most recent runs
+}
+
+// Query returns the matching runs, oldest first
+func (h *History) Query(filter HistoryFilter) ([]HistoryEntry, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var matching []HistoryEntry
+	for _, entry := range entries {
+		if filter.Scenario != "" && entry.Scenario != filter.Scenario {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.Timestamp.Before(filter.Since) {
+			continue
+		}
+		if filter.Verified != nil && (entry.Verified == nil || *entry.Verified != *filter.Verified) {
+			continue
+		}
+		matching = append(matching, entry)
+	}
+	if filter.Last > 0 && len(matching) > filter.Last {
+		matching = matching[len(matching)-filter.Last:]
+	}
+	return matching, nil
+}
+
+// writeFileAtomic replaces path with data, so that an interrupted write never leaves
+// a truncated index behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// recordHistory records the run in HistoryDir, if enabled
+func recordHistory(report QueueReport) {
+	if HistoryDir == "" {
+		return
+	}
+	history, err := OpenHistory(HistoryDir)
+	if err == nil {
+		_, err = history.Record(report)
+	}
+	if err != nil {
+		fmt.Printf("Failed to record run history: %v\n", err)
+		return
+	}
+	fmt.Printf("Run %s recorded in %s\n", report.RunID, HistoryDir)
+}
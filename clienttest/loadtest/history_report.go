@@ -0,0 +1,151 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TrendMetrics are the metrics the history command can trend, keyed by name
+var TrendMetrics = map[string]struct {
+	Unit  string
+	Value func(HistoryEntry) float64
+}{
+	"p50":        {"ms", func(e HistoryEntry) float64 { return milliseconds(e.P50) }},
+	"p99":        {"ms", func(e HistoryEntry) float64 { return milliseconds(e.P99) }},
+	"mean":       {"ms", func(e HistoryEntry) float64 { return milliseconds(e.Mean) }},
+	"throughput": {"req/s", func(e HistoryEntry) float64 { return e.Throughput }},
+	"success":    {"%", func(e HistoryEntry) float64 { return e.Success * 100 }},
+	"bytes-in":   {"bytes", func(e HistoryEntry) float64 { return e.MeanBytesIn }},
+}
+
+// trendMetricNames returns the names of TrendMetrics, sorted
+func trendMetricNames() []string {
+	names := make([]string, 0, len(TrendMetrics))
+	for name := range TrendMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintHistory writes the runs as a table, oldest first
+func PrintHistory(w io.Writer, entries []HistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No runs recorded")
+		return
+	}
+
+	idWidth := len("Run")
+	for _, entry := range entries {
+		if len(entry.RunID) > idWidth {
+			idWidth = len(entry.RunID)
+		}
+	}
+
+	fmt.Fprintf(w, "%-*s  %-12s %-16s %5s %6s %9s %8s %10s %10s %8s\n", idWidth,
+		"Run", "Scenario", "Time", "RPS", "Dur", "Thruput", "Success", "P50", "P99", "Verified")
+	for _, entry := range entries {
+		verified := formatVerified(entry.Verified)
+		if entry.ClientSaturated {
+			verified += " (sat)"
+		}
+		if entry.Interrupted {
+			verified += " (int)"
+		}
+		fmt.Fprintf(w, "%-*s  %-12s %-16s %5d %6v %9.2f %7.2f%% %10v %10v %8s\n", idWidth,
+			entry.RunID, entry.Scenario, entry.Timestamp.Format("2006-01-02 15:04"), entry.RPS, entry.Duration,
+			entry.Throughput, entry.Success*100, entry.P50, entry.P99, verified)
+	}
+}
+
+// PrintTrend writes one metric across the runs with a bar per run, followed by the
+// overall change and the least-squares slope per run
+func PrintTrend(w io.Writer, entries []HistoryEntry, metric string) error {
+	trend, ok := TrendMetrics[metric]
+	if !ok {
+		return fmt.Errorf("unknown metric %q, expected one of %s", metric, strings.Join(trendMetricNames(), ", "))
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No runs recorded")
+		return nil
+	}
+
+	values := make([]float64, len(entries))
+	largest := 0.0
+	for i, entry := range entries {
+		values[i] = trend.Value(entry)
+		if values[i] > largest {
+			largest = values[i]
+		}
+	}
+
+	fmt.Fprintf(w, "📈 %s (%s) over %d runs:\n", metric, trend.Unit, len(entries))
+	for i, entry := range entries {
+		bar := 0
+		if largest > 0 {
+			bar = int(values[i] / largest * 40)
+		}
+		fmt.Fprintf(w, "   %-16s %-32s %10.2f %s\n",
+			entry.Timestamp.Format("2006-01-02 15:04"), entry.RunID, values[i], strings.Repeat("█", bar))
+	}
+
+	first, last := values[0], values[len(values)-1]
+	if first != 0 {
+		fmt.Fprintf(w, "   First → last: %.2f → %.2f %s (%+.1f%%)\n", first, last, trend.Unit, (last-first)/first*100)
+	}
+	if len(values) > 1 {
+		fmt.Fprintf(w, "   Slope:        %+.3f %s per run\n", slope(values), trend.Unit)
+	}
+	return nil
+}
+
+// slope returns the least-squares slope of values against their index
+func slope(values []float64) float64 {
+	index := make([]float64, len(values))
+	for i := range index {
+		index[i] = float64(i)
+	}
+	return slopeOf(index, values)
+}
+
+// slopeOf returns the least-squares slope of ys against xs
+func slopeOf(xs, ys []float64) float64 {
+	n := float64(len(ys))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range ys {
+		x := xs[i]
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// ShowHistory prints the runs of HistoryDir matching the filter, or the trend of
+// one metric when trend is set
+func ShowHistory(filter HistoryFilter, trend string) error {
+	if HistoryDir == "" {
+		return fmt.Errorf("run history is disabled")
+	}
+	history, err := OpenHistory(HistoryDir)
+	if err != nil {
+		return err
+	}
+	entries, err := history.Query(filter)
+	if err != nil {
+		return err
+	}
+	if trend != "" {
+		return PrintTrend(os.Stdout, entries, trend)
+	}
+	PrintHistory(os.Stdout, entries)
+	return nil
+}
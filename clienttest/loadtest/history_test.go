@@ -0,0 +1,111 @@
+package loadtest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryRecordAndQuery(t *testing.T) {
+	history, err := OpenHistory(t.TempDir())
+	require.NoError(t, err)
+
+	entries, err := history.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	passed, failed := true, false
+	start := time.Now().Add(-time.Hour)
+	for i, base := range []time.Duration{10, 20, 30} {
+		report := attackReport(t, "transfers-run", base*time.Millisecond, true)
+		report.Scenario = "transfers"
+		report.Timestamp = start.Add(time.Duration(i) * time.Minute)
+		report.Verified = &passed
+		report.Config = &RunConfig{RPS: 10 * (i + 1), Duration: time.Second}
+		_, err := history.Record(report)
+		require.NoError(t, err)
+	}
+	other := attackReport(t, "get-accounts-run", 5*time.Millisecond, true)
+	other.Verified = &failed
+	entry, err := history.Record(other)
+	require.NoError(t, err)
+	assert.Equal(t, "get-accounts-run.json", entry.File)
+
+	entries, err = history.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	// Runs sharing an ID get their own file
+	assert.Equal(t, []string{"transfers-run.json", "transfers-run-2.json", "transfers-run-3.json"},
+		[]string{entries[0].File, entries[1].File, entries[2].File})
+	assert.Equal(t, 30, entries[2].RPS)
+	assert.Greater(t, entries[2].P99, entries[0].P99)
+
+	transfers, err := history.Query(HistoryFilter{Scenario: "transfers", Last: 2})
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	assert.Equal(t, 20, transfers[0].RPS)
+
+	failing, err := history.Query(HistoryFilter{Verified: &failed})
+	require.NoError(t, err)
+	require.Len(t, failing, 1)
+	assert.Equal(t, "get-accounts-run", failing[0].RunID)
+
+	recent, err := history.Query(HistoryFilter{Since: start.Add(90 * time.Second)})
+	require.NoError(t, err)
+	assert.Len(t, recent, 2)
+
+	// The latest run of an ID is loaded in full
+	report, err := history.Load("transfers-run")
+	require.NoError(t, err)
+	assert.Equal(t, 30, report.Config.RPS)
+	assert.NotEmpty(t, report.Latencies)
+	_, err = history.Load("missing")
+	assert.Error(t, err)
+}
+
+func TestCompareLoadsRunsFromHistory(t *testing.T) {
+	defer func(dir string) { HistoryDir = dir }(HistoryDir)
+	HistoryDir = t.TempDir()
+
+	recordHistory(attackReport(t, "run-1", 10*time.Millisecond, true))
+	recordHistory(attackReport(t, "run-2", 30*time.Millisecond, true))
+
+	runs, err := LoadRuns([]string{"run-1", "run-2"})
+	require.NoError(t, err)
+	comparisons, err := CompareReports(runs, DefaultTolerances)
+	require.NoError(t, err)
+	assert.True(t, comparisons[0].Regressed())
+}
+
+func TestPrintTrend(t *testing.T) {
+	entries := []HistoryEntry{
+		{RunID: "a", P99: 10 * time.Millisecond},
+		{RunID: "b", P99: 20 * time.Millisecond},
+		{RunID: "c", P99: 30 * time.Millisecond},
+	}
+	var out bytes.Buffer
+	require.NoError(t, PrintTrend(&out, entries, "p99"))
+	assert.Contains(t, out.String(), "10.00 → 30.00 ms (+200.0%)")
+	assert.Contains(t, out.String(), "+10.000 ms per run")
+
+	assert.Error(t, PrintTrend(&out, entries, "p42"))
+}
+
+func TestRunReportIncludesConfig(t *testing.T) {
+	run := newRun("get-accounts")
+	run.Attach(NewAttacker("http://localhost:8080/accounts", "GET", 25, 3, NewQueueMetrics()))
+	run.SetParam("mix", "get-account=100")
+
+	qm := NewQueueMetrics()
+	qm.Close()
+	report := run.Report(qm)
+	require.NotNil(t, report.Config)
+	assert.Equal(t, 25, report.Config.RPS)
+	assert.Equal(t, 3*time.Second, report.Config.Duration)
+	assert.Equal(t, "get-account=100", report.Config.Params["mix"])
+	require.NotNil(t, report.Environment)
+	assert.NotEmpty(t, report.Environment.GoVersion)
+}
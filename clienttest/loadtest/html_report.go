@@ -0,0 +1,386 @@
+package loadtest
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statusClassColors are the chart colors of every StatusClass, in stacking order
+var statusClassColors = []struct{ Class, Color string }{
+	{"2xx", "#2e9d5b"},
+	{"3xx", "#4a7fd4"},
+	{"1xx", "#8c8c8c"},
+	{"4xx", "#e0a31a"},
+	{"5xx", "#d64541"},
+	{"error", "#7a2f8f"},
+}
+
+// htmlRow is a label and its value in a two-column table
+type htmlRow struct {
+	Label string
+	Value string
+}
+
+// htmlPercentile is one row of the percentile table
+type htmlPercentile struct {
+	Name      string
+	Raw       time.Duration
+	Corrected time.Duration
+}
+
+// htmlReportView is what the HTML template renders
+type htmlReportView struct {
+	Report      QueueReport
+	Title       string
+	Config      []htmlRow
+	Environment []htmlRow
+	Queueing    []htmlRow
+	Percentiles []htmlPercentile
+	Warnings    []string
+
+	LatencyChart    template.HTML
+	HistogramChart  template.HTML
+	StatusChart     template.HTML
+	ThroughputChart template.HTML
+}
+
+// WriteHTMLReport writes the report as a single HTML page. Charts are inline SVG and
+// styles are embedded, so the file opens offline and can be attached anywhere.
+func WriteHTMLReport(w io.Writer, report QueueReport) error {
+	return htmlReportTemplate.Execute(w, newHTMLReportView(report))
+}
+
+func newHTMLReportView(report QueueReport) htmlReportView {
+	view := htmlReportView{
+		Report: report,
+		Title:  fmt.Sprintf("%s load test %s", report.Scenario, runLabel(report)),
+	}
+
+	if config := report.Config; config != nil {
+		view.Config = []htmlRow{
+			{"Rate", fmt.Sprintf("%d req/s", config.RPS)},
+			{"Duration", config.Duration.String()},
+			{"Interval", config.Interval.String()},
+			{"Target", config.TargetURL},
+			{"Servers (c)", strconv.Itoa(config.Servers)},
+			{"Latency buckets", config.LatencyBuckets},
+		}
+		if len(config.Targets) > 0 {
+			view.Config = append(view.Config, htmlRow{"Instances",
+				fmt.Sprintf("%s (%s)", strings.Join(config.Targets, ", "), config.BalancePolicy)})
+		}
+		params := make([]string, 0, len(config.Params))
+		for name := range config.Params {
+			params = append(params, name)
+		}
+		sort.Strings(params)
+		for _, name := range params {
+			view.Config = append(view.Config, htmlRow{name, config.Params[name]})
+		}
+	}
+	if env := report.Environment; env != nil {
+		view.Environment = []htmlRow{
+			{"Host", env.Hostname},
+			{"Platform", fmt.Sprintf("%s/%s, %d CPUs", env.OS, env.Arch, env.CPUs)},
+			{"Go", env.GoVersion},
+			{"Commit", env.GitCommit},
+		}
+	}
+
+	view.Queueing = []htmlRow{
+		{"Arrival rate (λ)", fmt.Sprintf("%.2f req/s", report.ArrivalRate)},
+		{"Service time (S)", report.Model.ServiceTime.String()},
+		{"Service rate (μ)", fmt.Sprintf("%.2f req/s", report.ServiceRate)},
+		{"Traffic intensity (ρ)", fmt.Sprintf("%.3f", report.TrafficIntensity)},
+		{"Little's law L = λW", fmt.Sprintf("%.2f predicted, %.2f observed in flight",
+			report.LittlesLaw.Predicted, report.LittlesLaw.Observed)},
+	}
+	if report.RequestedRate > 0 && report.Overall != nil {
+		view.Queueing = append(view.Queueing, htmlRow{"Requested vs sent",
+			fmt.Sprintf("%.2f / %.2f req/s", report.RequestedRate, report.Overall.Rate)})
+	}
+
+	for i, entry := range report.Percentiles {
+		row := htmlPercentile{Name: percentileName(entry.Quantile), Raw: entry.Latency}
+		if i < len(report.Corrected) {
+			row.Corrected = report.Corrected[i].Latency
+		}
+		view.Percentiles = append(view.Percentiles, row)
+	}
+
+	if i := report.Interrupted; i != nil {
+		view.Warnings = append(view.Warnings, fmt.Sprintf("Interrupted after %v of %v, the results are partial", i.After.Round(100*time.Millisecond), i.Planned))
+	}
+	if report.ClientSaturated {
+		view.Warnings = append(view.Warnings, "The load generator could not keep to its schedule, compare the corrected percentiles")
+	}
+	if report.Overall != nil && report.Overall.Success < 1 {
+		view.Warnings = append(view.Warnings, fmt.Sprintf("Success rate is %.2f%%", report.Overall.Success*100))
+	}
+	if report.Verified != nil && !*report.Verified {
+		view.Warnings = append(view.Warnings, "Balance verification failed")
+	}
+	if a := report.Availability; a != nil && len(a.Outages) > 0 {
+		view.Warnings = append(view.Warnings, fmt.Sprintf("%d instance outages during the run", len(a.Outages)))
+	}
+	if g := report.SessionGuarantees; g != nil && g.Violations() > 0 {
+		view.Warnings = append(view.Warnings, fmt.Sprintf("%d balance reads broke read-your-writes or monotonic reads", g.Violations()))
+	}
+
+	view.LatencyChart, view.ThroughputChart, view.StatusChart = timeSeriesCharts(report.TimeSeries)
+	view.HistogramChart = histogramChart(report)
+	return view
+}
+
+// timeSeriesCharts charts latency, throughput against offered rate and status codes per interval
+func timeSeriesCharts(snapshots []IntervalSnapshot) (latency, throughput, status template.HTML) {
+	labels := make([]string, len(snapshots))
+	p50 := chartSeries{Name: "p50", Color: "#2e9d5b"}
+	p90 := chartSeries{Name: "p90", Color: "#e0a31a"}
+	p99 := chartSeries{Name: "p99", Color: "#d64541"}
+	offered := chartSeries{Name: "offered", Color: "#8c8c8c"}
+	thruput := chartSeries{Name: "throughput", Color: "#4a7fd4"}
+	classes := make([]chartSeries, len(statusClassColors))
+	for i, class := range statusClassColors {
+		classes[i] = chartSeries{Name: class.Class, Color: class.Color}
+	}
+
+	for i, snap := range snapshots {
+		labels[i] = snap.Elapsed.Round(100 * time.Millisecond).String()
+		p50.Values = append(p50.Values, milliseconds(snap.P50))
+		p90.Values = append(p90.Values, milliseconds(snap.P90))
+		p99.Values = append(p99.Values, milliseconds(snap.P99))
+		offered.Values = append(offered.Values, snap.OfferedRate)
+		thruput.Values = append(thruput.Values, snap.Throughput)
+		for j := range classes {
+			classes[j].Values = append(classes[j].Values, float64(snap.StatusCodes[classes[j].Name]))
+		}
+	}
+
+	// Only chart the status classes that occurred
+	var present []chartSeries
+	for _, class := range classes {
+		if seriesMax([]chartSeries{class}, false) > 0 {
+			present = append(present, class)
+		}
+	}
+
+	latency = lineChart(labels, "elapsed", "ms", []chartSeries{p50, p90, p99})
+	throughput = lineChart(labels, "elapsed", "req/s", []chartSeries{offered, thruput})
+	status = barChart(labels, "elapsed", "responses", present)
+	return latency, throughput, status
+}
+
+// histogramChart charts the report's latency histogram buckets
+func histogramChart(report QueueReport) template.HTML {
+	if report.Overall == nil || report.Overall.Histogram == nil {
+		return emptyChart()
+	}
+	histogram := report.Overall.Histogram
+	labels := make([]string, len(histogram.Counts))
+	counts := chartSeries{Name: "requests", Color: "#4a7fd4"}
+	for i, count := range histogram.Counts {
+		left, _ := histogram.Buckets.Nth(i)
+		labels[i] = "≥" + left
+		counts.Values = append(counts.Values, float64(count))
+	}
+	return barChart(labels, "latency bucket", "requests", []chartSeries{counts})
+}
+
+// saveHTMLReport writes the report as HTML to path, replacing the previous run's file
+func saveHTMLReport(path string, report QueueReport) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Failed to create HTML report: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := WriteHTMLReport(file, report); err != nil {
+		fmt.Printf("Failed to write HTML report: %v\n", err)
+		return
+	}
+	fmt.Printf("HTML report written to %s\n", path)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"money":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"rate":    func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"rho":     func(v float64) string { return fmt.Sprintf("%.3f", v) },
+	"micros":  func(d time.Duration) time.Duration { return d.Round(time.Microsecond) },
+
+	"percentile": percentileName,
+	"millis":     func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 60rem; color: #222; }
+h1 { font-size: 1.5rem; } h2 { font-size: 1.15rem; margin-top: 2rem; border-bottom: 1px solid #ddd; padding-bottom: .25rem; }
+table { border-collapse: collapse; margin: .5rem 0; } td, th { padding: .2rem .8rem; text-align: left; border-bottom: 1px solid #eee; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+.stats { display: flex; flex-wrap: wrap; gap: 1rem; } .stat { border: 1px solid #ddd; border-radius: 6px; padding: .5rem 1rem; }
+.stat b { display: block; font-size: 1.3rem; }
+.pass { color: #2e9d5b; } .fail { color: #d64541; } .muted { color: #888; }
+.warning { background: #fff4e0; border-left: 4px solid #e0a31a; padding: .5rem 1rem; margin: .5rem 0; }
+svg.chart { width: 100%; height: auto; } .grid { stroke: #eee; } .tick { font-size: 11px; fill: #666; } .axis { font-size: 12px; fill: #444; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="muted">{{.Report.Timestamp.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Warnings}}<div class="warning">⚠️ {{.}}</div>{{end}}
+
+{{with .Report.Overall}}
+<div class="stats">
+<div class="stat">Requests<b>{{.Requests}}</b></div>
+<div class="stat">Throughput<b>{{rate .Throughput}} req/s</b></div>
+<div class="stat">Success<b>{{percent .Success}}</b></div>
+<div class="stat">Mean<b>{{micros .Latencies.Mean}}</b></div>
+<div class="stat">P99<b>{{micros .Latencies.P99}}</b></div>
+{{with $.Report.Verified}}<div class="stat">Verification<b class="{{if .}}pass{{else}}fail{{end}}">{{if .}}passed{{else}}FAILED{{end}}</b></div>{{end}}
+</div>
+{{end}}
+
+{{if .Config}}
+<h2>Run configuration</h2>
+<table>{{range .Config}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>{{end}}
+{{range .Environment}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>{{end}}</table>
+{{end}}
+
+<h2>Latency over time</h2>
+{{.LatencyChart}}
+
+<h2>Throughput vs offered rate</h2>
+{{.ThroughputChart}}
+
+<h2>Status codes over time</h2>
+{{.StatusChart}}
+
+<h2>Latency histogram</h2>
+{{.HistogramChart}}
+
+<h2>Percentiles</h2>
+<table>
+<tr><th></th><th class="num">Raw</th>{{if .Report.Corrected}}<th class="num">Corrected</th>{{end}}</tr>
+{{range .Percentiles}}<tr><th>{{.Name}}</th><td class="num">{{.Raw}}</td>{{if $.Report.Corrected}}<td class="num">{{.Corrected}}</td>{{end}}</tr>{{end}}
+</table>
+
+<h2>Queueing analysis</h2>
+<table>{{range .Queueing}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>{{end}}</table>
+{{with .Report.Model.Predictions}}
+<table>
+<tr><th>Model</th><th class="num">ρ</th><th class="num">Wq</th><th class="num">Lq</th><th class="num">W</th><th class="num">L</th></tr>
+{{range .}}<tr><th>{{.Model}}</th><td class="num">{{rho .Utilization}}</td>{{if .Stable}}<td class="num">{{micros .WaitTime}}</td><td class="num">{{rate .QueueLength}}</td><td class="num">{{micros .Response}}</td><td class="num">{{rate .InSystem}}</td>{{else}}<td colspan="4" class="fail">unstable</td>{{end}}</tr>{{end}}
+<tr><th>Observed</th><td></td><td></td><td></td><td class="num">{{micros $.Report.Model.ObservedResponse}}</td><td class="num">{{rate $.Report.Model.ObservedInSystem}}</td></tr>
+</table>
+{{end}}
+{{with .Report.Server}}
+<table>
+<tr><th>Server</th><td>λ {{rate .ArrivalRate}} req/s, μ {{rate .ServiceRate}} req/s, ρ {{rho .Utilization}}, wait {{.MeanWaitTime}}, service {{.MeanServiceTime}}, queue {{rate .MeanQueueLength}} mean / {{.MaxQueueLength}} max</td></tr>
+</table>
+{{end}}
+
+{{if gt (len .Report.Endpoints) 0}}
+<h2>Endpoints</h2>
+<table>
+<tr><th>Endpoint</th><th class="num">Requests</th><th class="num">Success</th><th class="num">Mean</th><th class="num">P99</th><th class="num">Timeouts</th></tr>
+{{range .Report.Endpoints}}<tr><th>{{.Label}}</th><td class="num">{{.Metrics.Requests}}</td><td class="num">{{percent .Metrics.Success}}</td><td class="num">{{micros .Metrics.Latencies.Mean}}</td><td class="num">{{micros .Metrics.Latencies.P99}}</td><td class="num">{{.Timeouts}}</td></tr>{{end}}
+</table>
+{{end}}
+
+{{if .Report.Instances}}
+<h2>Instances</h2>
+<table>
+<tr><th>Instance</th><th class="num">Requests</th><th class="num">Success</th><th class="num">Mean</th><th class="num">P99</th><th class="num">Timeouts</th></tr>
+{{range .Report.Instances}}<tr><th>{{.Label}}</th><td class="num">{{.Metrics.Requests}}</td><td class="num">{{percent .Metrics.Success}}</td><td class="num">{{micros .Metrics.Latencies.Mean}}</td><td class="num">{{micros .Metrics.Latencies.P99}}</td><td class="num">{{.Timeouts}}</td></tr>{{end}}
+</table>
+{{with .Report.Availability}}
+<p>{{.Retries}} reads retried on another instance, {{.Recovered}} recovered.</p>
+{{if .Outages}}<table>
+<tr><th>Outage of</th><th>Start</th><th class="num">Failed requests</th><th class="num">Time to detect</th><th class="num">Error window</th><th>End</th></tr>
+{{range .Outages}}<tr><th>{{.Instance}}</th><td>{{.Start.Format "15:04:05.000"}}</td><td class="num">{{.FailedRequests}}</td><td class="num">{{if .Ejected.IsZero}}not ejected{{else}}{{millis .TimeToDetect}}{{end}}</td><td class="num">{{millis .ErrorWindow}}</td><td>{{if .End.IsZero}}<span class="fail">ongoing</span>{{else}}{{.End.Format "15:04:05.000"}}{{end}}</td></tr>{{end}}
+</table>{{end}}
+{{end}}
+{{end}}
+
+{{with .Report.Staleness}}
+<h2>Cache staleness</h2>
+<p>{{.Writes}} writes probed, polling every {{.PollInterval}}: {{.StaleReads}} of {{.Reads}} reads stale ({{percent .StaleFraction}}){{if .Timeouts}}, <b class="fail">{{.Timeouts}} never read back</b>{{end}}.</p>
+<table>
+<tr><th>Time to consistency</th><th class="num">Upper bound</th></tr>
+{{range .TimeToConsistency}}<tr><th>{{percentile .Quantile}}</th><td class="num">{{micros .Latency}}</td></tr>{{end}}
+</table>
+{{end}}
+
+{{with .Report.Shards}}
+<h2>Shards</h2>
+<p>{{percent .CrossShardShare}} of transfers across shards (requested {{percent .CrossShardRatio}}), busiest shard at {{rate .MaxToMean}}× the mean.</p>
+<table>
+<tr><th>Shard</th><th class="num">Accounts</th><th class="num">Transfers</th><th class="num">Failed</th><th class="num">Share</th></tr>
+{{range .Shards}}<tr><th>{{.Shard}}</th><td class="num">{{.Accounts}}</td><td class="num">{{.Transfers}}</td><td class="num {{if .Failed}}fail{{end}}">{{.Failed}}</td><td class="num">{{percent .Share}}</td></tr>{{end}}
+</table>
+{{end}}
+
+{{with .Report.Warmup}}
+<h2>Warm-up</h2>
+<p>Excluded from every other figure: {{.Requests}} requests at {{.RPS}} req/s for {{millis .Duration}} (planned {{.Planned}}), {{percent .Success}} successful, mean {{micros .Mean}}.{{if .SteadyState}} {{if .Steady}}Latency was steady when measuring began.{{else}}<b class="fail">Latency never became steady.</b>{{end}}{{end}}</p>
+<table>
+<tr><th>Warm-up latency</th><th class="num">Upper bound</th></tr>
+{{range .Percentiles}}<tr><th>{{percentile .Quantile}}</th><td class="num">{{micros .Latency}}</td></tr>{{end}}
+</table>
+{{end}}
+
+{{with .Report.ResponseSize}}
+<h2>Response size</h2>
+<p>{{printf "%.0f" .First}} bytes per response in the first interval, {{printf "%.0f" .Last}} in the last ({{percent .Growth}} growth, {{printf "%.1f" .BytesPerSecond}} bytes/s){{if ge .AccountsAfter 0}}, {{.AccountsBefore}} accounts before and {{.AccountsAfter}} after{{end}}.</p>
+{{end}}
+
+{{with .Report.Settlement}}
+<h2>Settlement</h2>
+<p>{{.Transfers}} transfers followed until settled, polling every {{.PollInterval}}{{if .Anomalies}}: <b class="fail">{{.Anomalies}} anomalies</b>{{end}}.</p>
+<table>
+<tr><th>State</th><th class="num">Transfers</th></tr>
+{{range .States}}<tr><th>{{.State}}</th><td class="num {{if .State.Anomaly}}fail{{end}}">{{.Count}}</td></tr>{{end}}
+</table>
+<table>
+<tr><th>Time to settle</th><th class="num">Upper bound</th></tr>
+{{range .TimeToSettle}}<tr><th>{{percentile .Quantile}}</th><td class="num">{{micros .Latency}}</td></tr>{{end}}
+</table>
+{{if .Records}}<table>
+<tr><th>Submitted</th><th>From</th><th>To</th><th class="num">Amount</th><th>State</th><th class="num">Time to settle</th><th>Error</th></tr>
+{{range .Records}}<tr><td>{{.Submitted.Format "15:04:05.000"}}</td><td>{{.From}}</td><td>{{.To}}</td><td class="num">{{money .Amount}}</td><td class="fail">{{.State}}</td><td class="num">{{millis .TimeToSettle}}</td><td>{{.Error}}</td></tr>{{end}}
+</table>{{end}}
+{{end}}
+
+{{with .Report.SessionGuarantees}}
+<h2>Session guarantees</h2>
+<table>
+<tr><th>Balance reads</th><td class="num">{{.Reads}}</td></tr>
+<tr><th>Read-your-writes violations</th><td class="num {{if .ReadYourWritesViolations}}fail{{end}}">{{.ReadYourWritesViolations}}</td></tr>
+<tr><th>Monotonic-read violations</th><td class="num {{if .MonotonicReadViolations}}fail{{end}}">{{.MonotonicReadViolations}}</td></tr>
+<tr><th>Reads matching no recorded balance</th><td class="num">{{.UnknownReads}}</td></tr>
+<tr><th>Staleness (mean / max)</th><td class="num">{{.MeanStaleness}} / {{.MaxStaleness}}</td></tr>
+</table>
+{{end}}
+
+{{with .Report.Balances}}
+<h2>Balance verification</h2>
+<p>Initial total {{money .InitialTotal}}, final total {{money .FinalTotal}}: <b class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}passed{{else}}FAILED{{end}}</b></p>
+<table>
+<tr><th>Customer</th><th>Account</th><th class="num">Expected</th><th class="num">Actual</th><th></th></tr>
+{{range .Customers}}<tr><th>{{.Name}}</th><td>{{.AccountID}}</td><td class="num">{{money .Expected}}</td><td class="num">{{if .Error}}<span class="fail">{{.Error}}</span>{{else}}{{money .Actual}}{{end}}</td><td class="{{if .Passed}}pass{{else}}fail{{end}}">{{if .Passed}}✓{{else}}✗{{end}}</td></tr>{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
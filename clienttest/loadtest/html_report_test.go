@@ -0,0 +1,83 @@
+package loadtest
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	qm := NewQueueMetrics()
+	start := time.Now()
+	qm.TimeSeries().Begin(start)
+	for i := 0; i < 40; i++ {
+		code := uint16(200)
+		if i%8 == 0 {
+			code = 500
+		}
+		qm.Add(&vegeta.Result{Code: code, Latency: time.Duration(5+i) * time.Millisecond, Timestamp: start.Add(time.Duration(i) * 50 * time.Millisecond)})
+		if i%10 == 9 {
+			qm.TimeSeries().Snapshot(start.Add(time.Duration(i+1)*50*time.Millisecond), 10, 0)
+		}
+	}
+	qm.Close()
+
+	run := newRun("transfers")
+	run.SetParam("mix", "<script>alert(1)</script>")
+	run.RecordVerification(BalanceVerification{
+		InitialTotal: 200,
+		FinalTotal:   199,
+		Customers: []CustomerBalance{
+			{Name: "source-0", AccountID: "acc-1", Expected: 99, Actual: 99, Passed: true},
+			{Name: "dest-0", AccountID: "acc-2", Expected: 101, Actual: 100},
+		},
+	})
+	report := run.Report(qm)
+	report.SessionGuarantees = &domain.SessionGuarantees{Reads: 10, MonotonicReadViolations: 2}
+	report.Staleness = &StalenessReport{Writes: 4, Reads: 8, StaleReads: 2, StaleFraction: 0.25,
+		TimeToConsistency: []PercentileEntry{{Quantile: 0.999, Latency: 12 * time.Millisecond}}}
+	report.Settlement = &SettlementReport{Transfers: 5, Anomalies: 1, PollInterval: 10 * time.Millisecond,
+		States:  []SettlementCount{{State: domain.SettlementCommitted, Count: 4}, {State: domain.SettlementDebitWithoutCredit, Count: 1}},
+		Records: []SettlementRecord{{From: "settle-0", To: "settle-1", Amount: 1, State: domain.SettlementDebitWithoutCredit, TimeToSettle: 10 * time.Second}}}
+	report.Shards = &ShardImbalance{CrossShardRatio: 0.5, CrossShardShare: 0.4, MaxToMean: 1.5,
+		Shards: []ShardLoad{{Shard: 0, Accounts: 3, Transfers: 6, Share: 0.75}, {Shard: 1, Accounts: 2, Transfers: 2, Failed: 1, Share: 0.25}}}
+	report.Warmup = &WarmupReport{RPS: 5, Planned: 10 * time.Second, Duration: 6 * time.Second, SteadyState: true, Steady: true,
+		Requests: 30, Success: 1, Mean: 40 * time.Millisecond}
+	report.ResponseSize = &ResponseSizeGrowth{First: 1000, Last: 1500, Growth: 0.5, BytesPerSecond: 50, AccountsBefore: 10, AccountsAfter: 15}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteHTMLReport(&out, report))
+	html := out.String()
+
+	// Four charts, status codes stacked per class
+	assert.Equal(t, 4, strings.Count(html, "<svg"))
+	assert.Contains(t, html, "<title>500ms 5xx: 2</title>")
+	assert.Contains(t, html, "dest-0")
+	assert.Contains(t, html, "FAILED")
+	assert.Contains(t, html, "Success rate is 87.50%")
+	assert.Contains(t, html, "2 of 8 reads stale (25.00%)")
+	assert.Contains(t, html, "<th>p99.9</th><td class=\"num\">12ms</td>")
+	assert.Contains(t, html, "40.00% of transfers across shards (requested 50.00%), busiest shard at 1.50× the mean")
+	assert.Contains(t, html, "5 transfers followed until settled, polling every 10ms: <b class=\"fail\">1 anomalies</b>")
+	assert.Contains(t, html, "<th>debit-without-credit</th><td class=\"num fail\">1</td>")
+	assert.Contains(t, html, "30 requests at 5 req/s for 6s (planned 10s), 100.00% successful, mean 40ms. Latency was steady when measuring began.")
+	assert.Contains(t, html, "1000 bytes per response in the first interval, 1500 in the last (50.00% growth, 50.0 bytes/s), 10 accounts before and 15 after")
+	assert.Contains(t, html, "2 balance reads broke read-your-writes or monotonic reads")
+
+	// Parameters are escaped and nothing is loaded from elsewhere
+	assert.NotContains(t, html, "<script>")
+	assert.Empty(t, regexp.MustCompile(`(src|href)=`).FindAllString(html, -1))
+}
+
+func TestNiceCeiling(t *testing.T) {
+	for v, want := range map[float64]float64{0: 1, 0.3: 0.5, 1: 1, 1.2: 2, 4.9: 5, 7: 10, 230: 500} {
+		assert.InDelta(t, want, niceCeiling(v), 1e-9, "%v", v)
+	}
+}
@@ -0,0 +1,78 @@
+package loadtest
+
+import (
+	"fmt"
+	"time"
+
+	"com.ndnhuy.mybank/balancer"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Balancer spreads the requests of every scenario over several app instances,
+// nil sends everything to utils.BASE_URL
+var Balancer *balancer.Balancer
+
+// newVegetaAttacker creates a vegeta attacker that sends through the Balancer when set
+func newVegetaAttacker() *vegeta.Attacker {
+	if Balancer == nil {
+		return vegeta.NewAttacker()
+	}
+	return vegeta.NewAttacker(vegeta.Client(Balancer.Client()))
+}
+
+// observeInstance records a result routed by the Balancer under its instance, unless it
+// belongs to the warm-up
+func (qm *QueueMetrics) observeInstance(target string, res *vegeta.Result) {
+	if qm.warming.Load() {
+		return
+	}
+	qm.instancesMu.Lock()
+	defer qm.instancesMu.Unlock()
+	qm.instances.Add(target, res)
+}
+
+// Instances returns the metrics split per app instance, nil without a Balancer
+func (qm *QueueMetrics) Instances() *MetricsBreakdown {
+	return qm.instances
+}
+
+// recordAvailability records the outages of the attack's period and the reads retried
+// during it
+func (qm *QueueMetrics) recordAvailability() {
+	availability := Balancer.Availability(qm.startTime)
+	availability.Retries -= qm.retriesBase.Retries
+	availability.Recovered -= qm.retriesBase.Recovered
+	qm.availability = &availability
+}
+
+// Availability returns how the Balancer coped with unavailable instances during the
+// attack, nil without a Balancer
+func (qm *QueueMetrics) Availability() *balancer.Availability {
+	return qm.availability
+}
+
+// printAvailability prints the outages of instances and the reads retried around them
+func printAvailability(availability *balancer.Availability) {
+	if availability == nil {
+		return
+	}
+	fmt.Println("\n🩺 FAILOVER:")
+	fmt.Printf("   Retried Reads:         %d (%d recovered on another instance)\n", availability.Retries, availability.Recovered)
+	if len(availability.Outages) == 0 {
+		fmt.Println("   ✅ No instance became unavailable")
+		return
+	}
+	fmt.Printf("   %-28s %12s %8s %14s %14s %12s\n", "Instance", "Start", "Failed", "Time to Detect", "Error Window", "Duration")
+	for _, outage := range availability.Outages {
+		detect := "not ejected"
+		if !outage.Ejected.IsZero() {
+			detect = outage.TimeToDetect().Round(time.Millisecond).String()
+		}
+		duration := outage.Duration().Round(time.Millisecond).String()
+		if outage.End.IsZero() {
+			duration += " (ongoing)"
+		}
+		fmt.Printf("   %-28s %12s %8d %14s %14v %12s\n", outage.Instance, outage.Start.Format("15:04:05.000"),
+			outage.FailedRequests, detect, outage.ErrorWindow().Round(time.Millisecond), duration)
+	}
+}
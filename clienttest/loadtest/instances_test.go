@@ -0,0 +1,41 @@
+package loadtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"com.ndnhuy.mybank/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttackerReportsPerInstance(t *testing.T) {
+	var targets []string
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("[]"))
+		}))
+		defer server.Close()
+		targets = append(targets, server.URL)
+	}
+	b, err := balancer.New(targets, balancer.RoundRobin)
+	require.NoError(t, err)
+
+	defer func(b *balancer.Balancer, metricsURL string) { Balancer, ServerMetricsURL = b, metricsURL }(Balancer, ServerMetricsURL)
+	Balancer, ServerMetricsURL = b, ""
+
+	qm := NewQueueMetrics()
+	// The URL clients know, which the balancer replaces by the instances'
+	NewAttacker("http://localhost:1/accounts", "GET", 20, 1, qm).Attack()
+	qm.Close()
+
+	report := qm.Report("test")
+	require.Len(t, report.Instances, 2)
+	assert.ElementsMatch(t, targets, []string{report.Instances[0].Label, report.Instances[1].Label})
+	assert.Equal(t, qm.Requests, report.Instances[0].Metrics.Requests+report.Instances[1].Metrics.Requests)
+	assert.InDelta(t, report.Instances[0].Metrics.Requests, report.Instances[1].Metrics.Requests, 1)
+	assert.Equal(t, 1.0, qm.Success)
+	require.NotNil(t, report.Availability)
+	assert.Empty(t, report.Availability.Outages)
+}
@@ -0,0 +1,139 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+)
+
+// CleanupManifestPath is where the accounts a run created are appended, for cleaning up
+// after the bank once it has a DELETE endpoint
+var CleanupManifestPath = "cleanup_manifest.jsonl"
+
+// The first interrupt cancels interruptCtx: the running scenario stops sending, waits
+// for the responses in flight and still verifies and reports what it measured
+var (
+	interruptMu     sync.Mutex
+	interruptCtx    context.Context
+	interruptCancel context.CancelFunc
+	interruptedAt   time.Time
+)
+
+func init() {
+	interruptCtx, interruptCancel = context.WithCancel(context.Background())
+}
+
+// Interruption records that a run was stopped before its planned duration
+type Interruption struct {
+	At      time.Time     `json:"at"`
+	After   time.Duration `json:"after"`   // Since the attack started
+	Planned time.Duration `json:"planned"` // Duration the run was configured with
+}
+
+// HandleInterrupts makes the first Ctrl+C, or SIGTERM, stop the scenario gracefully
+// with partial reports, and the second exit right away
+func HandleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Printf("\n⚠️  Interrupted, waiting for the requests in flight before verifying and reporting. Press Ctrl+C again to exit right away\n")
+		Interrupt()
+		<-signals
+		fmt.Printf("\nExiting without reports\n")
+		os.Exit(130)
+	}()
+}
+
+// Interrupt stops the running scenario as Ctrl+C does
+func Interrupt() {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	if interruptedAt.IsZero() {
+		interruptedAt = time.Now()
+	}
+	interruptCancel()
+}
+
+// interruptContext returns a context cancelled by the first interrupt
+func interruptContext() context.Context {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	return interruptCtx
+}
+
+// interrupted returns when the load test was interrupted, zero when it wasn't
+func interrupted() time.Time {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	return interruptedAt
+}
+
+// printInterruption warns that the report only covers the run until the interrupt
+func printInterruption(interruption *Interruption) {
+	if interruption == nil {
+		return
+	}
+	fmt.Printf("\n⚠️  INTERRUPTED after %v, the results cover the requests sent until then\n",
+		interruption.After.Round(100*time.Millisecond))
+}
+
+// writeInterruption marks a text report as interrupted
+func writeInterruption(w io.Writer, qm *QueueMetrics) {
+	if interruption := qm.interruption(); interruption != nil {
+		fmt.Fprintf(w, "INTERRUPTED after %v, partial results\n", interruption.After.Round(100*time.Millisecond))
+	}
+}
+
+// CleanupManifest lists the accounts one run created
+type CleanupManifest struct {
+	Timestamp   time.Time        `json:"timestamp"`
+	Interrupted bool             `json:"interrupted,omitempty"`
+	Accounts    []CleanupAccount `json:"accounts"`
+}
+
+// CleanupAccount is an account listed in a CleanupManifest
+type CleanupAccount struct {
+	Name      string `json:"name"`
+	AccountID string `json:"account_id"`
+}
+
+// appendCleanupManifest appends the customers' accounts to CleanupManifestPath as one
+// JSON line, leaving out the seeded accounts of AccountDataset, which are meant to stay
+func appendCleanupManifest(customers []*domain.Customer) {
+	manifest := CleanupManifest{Timestamp: time.Now(), Interrupted: !interrupted().IsZero()}
+	for _, customer := range customers {
+		if AccountDataset != nil && AccountDataset.Has(customer.GetAccountID()) {
+			continue
+		}
+		manifest.Accounts = append(manifest.Accounts, CleanupAccount{Name: customer.GetName(), AccountID: customer.GetAccountID()})
+	}
+	if len(manifest.Accounts) == 0 {
+		return
+	}
+
+	line, err := json.Marshal(manifest)
+	if err != nil {
+		fmt.Printf("Failed to encode cleanup manifest: %v\n", err)
+		return
+	}
+	file, err := os.OpenFile(CleanupManifestPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open cleanup manifest: %v\n", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		fmt.Printf("Failed to write cleanup manifest: %v\n", err)
+		return
+	}
+	fmt.Printf("%d test accounts appended to %s\n", len(manifest.Accounts), CleanupManifestPath)
+}
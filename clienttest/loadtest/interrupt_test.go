@@ -0,0 +1,125 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// withInterrupts gives a test its own interruption, restored to a fresh one afterwards
+func withInterrupts(t *testing.T) {
+	reset := func() {
+		interruptMu.Lock()
+		defer interruptMu.Unlock()
+		interruptCtx, interruptCancel = context.WithCancel(context.Background())
+		interruptedAt = time.Time{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// slowServer answers every request after delay and counts the requests it received
+func slowServer(t *testing.T, delay time.Duration) (string, *atomic.Int64) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		time.Sleep(delay)
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &received
+}
+
+func TestAttackerInterrupt(t *testing.T) {
+	withInterrupts(t)
+	withWarmup(t, 0, 0, false)
+	url, received := slowServer(t, 100*time.Millisecond)
+
+	qm := NewQueueMetrics()
+	attacker := NewAttacker(url+"/accounts", "GET", 50, 10, qm)
+	run := newRun("interrupt")
+	run.Attach(attacker)
+	observed := 0
+	attacker.OnResult(func(*vegeta.Result) { observed++ })
+	time.AfterFunc(300*time.Millisecond, Interrupt)
+
+	began := time.Now()
+	attacker.Attack()
+	qm.Close()
+	assert.Less(t, time.Since(began), 2*time.Second, "stopped long before 10s")
+	assert.Positive(t, qm.Requests)
+	assert.Equal(t, received.Load(), int64(qm.Requests), "every request in flight was drained")
+	assert.Equal(t, int(qm.Requests), observed)
+	assert.Equal(t, 1.0, qm.Success)
+
+	report := run.Report(qm)
+	require.NotNil(t, report.Interrupted)
+	assert.Equal(t, 10*time.Second, report.Interrupted.Planned)
+	assert.InDelta(t, 300*time.Millisecond, report.Interrupted.After, float64(200*time.Millisecond))
+
+	var html bytes.Buffer
+	require.NoError(t, WriteHTMLReport(&html, report))
+	assert.Contains(t, html.String(), "Interrupted after")
+	entry := newHistoryEntry(report, "interrupt.json")
+	assert.True(t, entry.Interrupted)
+	assert.Less(t, entry.Duration, 10*time.Second, "how long it ran")
+}
+
+func TestAttackerInterruptedWhileWarmingUp(t *testing.T) {
+	withInterrupts(t)
+	withWarmup(t, 10*time.Second, 50, false)
+	url, received := slowServer(t, 10*time.Millisecond)
+
+	qm := NewQueueMetrics()
+	time.AfterFunc(300*time.Millisecond, Interrupt)
+	began := time.Now()
+	NewAttacker(url+"/accounts", "GET", 50, 10, qm).Attack()
+	qm.Close()
+
+	assert.Less(t, time.Since(began), 2*time.Second)
+	warmup := qm.Warmup()
+	require.NotNil(t, warmup)
+	assert.Equal(t, received.Load(), int64(warmup.Requests))
+	assert.Zero(t, qm.Requests, "nothing measured")
+	assert.NotNil(t, qm.interruption())
+}
+
+func TestAppendCleanupManifest(t *testing.T) {
+	withInterrupts(t)
+	path, dataset := CleanupManifestPath, AccountDataset
+	t.Cleanup(func() { CleanupManifestPath, AccountDataset = path, dataset })
+	CleanupManifestPath = filepath.Join(t.TempDir(), "cleanup_manifest.jsonl")
+	AccountDataset = newDataset("dataset.csv", []DatasetAccount{{ID: "seeded", Balance: 100}})
+
+	customers := []*domain.Customer{
+		domain.NewCustomerForAccount("source-0", "seeded", 100),
+		domain.NewCustomerForAccount("dest-0", "created", 1),
+	}
+	appendCleanupManifest(customers)
+	Interrupt()
+	appendCleanupManifest(customers)
+	appendCleanupManifest(customers[:1])
+
+	data, err := os.ReadFile(CleanupManifestPath)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 2, "nothing to clean up after seeded accounts alone")
+	for i, line := range lines {
+		var manifest CleanupManifest
+		require.NoError(t, json.Unmarshal(line, &manifest))
+		assert.Equal(t, []CleanupAccount{{Name: "dest-0", AccountID: "created"}}, manifest.Accounts)
+		assert.Equal(t, i == 1, manifest.Interrupted)
+	}
+}
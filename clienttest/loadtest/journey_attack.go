@@ -0,0 +1,157 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"com.ndnhuy.mybank/domain/action"
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// actionEndpoints maps actions to the endpoint they call, so journeys show up in the
+// per-endpoint report like every other scenario
+var actionEndpoints = map[string]struct{ method, path string }{
+	"open-account":  {"POST", "/accounts"},
+	"check-balance": {"GET", "/accounts/{id}"},
+	"transfer":      {"POST", "/accounts/transfer"},
+	"list-accounts": {"GET", "/accounts"},
+}
+
+// actionResultToVegeta converts an executed action into a result the queue metrics
+// can record, or nil for a skipped action which sent no request. Violations keep
+// their 200 with the violation as error.
+func actionResultToVegeta(result action.ActionResult) *vegeta.Result {
+	if result.Outcome == action.OutcomeSkipped {
+		return nil
+	}
+	endpoint := actionEndpoints[result.Action]
+	var err error
+	if result.Outcome == action.OutcomeFailed {
+		err = result.Err
+	}
+	res := operationResult(endpoint.method, endpoint.path, result.Start, result.Latency, err)
+	res.Attack = result.Journey
+	if result.Outcome == action.OutcomeViolation {
+		res.Error = result.Err.Error()
+	}
+	return res
+}
+
+// AttackJourneys runs the default customer journey on the given number of workers,
+// each starting a new session as soon as its previous journey ends
+func AttackJourneys(workers, testDuration int) {
+	const numCustomers = 20
+	const initialBalance = 100.0
+
+	journey := action.DefaultJourney()
+	fmt.Printf("Starting journey attack: %d workers for %d seconds\n", workers, testDuration)
+	fmt.Printf("Journey: %s\n", journey.Name)
+	fmt.Printf("Setting up test customers...\n")
+
+	customers, err := createCustomers("journey-pool", numCustomers, initialBalance)
+	if err != nil {
+		fmt.Printf("Failed to setup customers: %v\n", err)
+		return
+	}
+	pool := action.NewPool(customers)
+	defer func() { cleanupTransferCustomers(pool.Customers()) }()
+
+	fmt.Printf("Created %d customers\n", len(customers))
+	fmt.Printf("Target: %s\n", utils.BASE_URL)
+	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
+
+	queueMetrics := NewQueueMetrics()
+	actions := NewMetricsBreakdownWithHistogram(LatencyBuckets)
+	run := newRun("journeys")
+	run.config.Duration = time.Duration(testDuration) * time.Second
+	run.SetParam("journey", journey.Name)
+	run.SetParam("workers", fmt.Sprint(workers))
+	fmt.Printf("Journey attack in progress...\n")
+
+	runner := action.NewRunner(journey, action.HTTPBank{}, pool, workers)
+	results := make(chan labeledResult, 1024)
+	runner.OnResult = func(result action.ActionResult) {
+		if res := actionResultToVegeta(result); res != nil {
+			results <- labeledResult{label: result.Action, res: res}
+		}
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(interruptContext(), run.config.Duration)
+		defer cancel()
+		runner.Run(ctx)
+		// Every OnResult call returned, nothing sends anymore
+		close(results)
+	}()
+	collectResults(run, queueMetrics, actions, results)
+	stats := runner.Stats()
+	queueMetrics.Close()
+	actions.Close()
+	fmt.Printf("Attack completed!\n\n")
+
+	// Transfers between pool customers move money without creating any, so the
+	// balances must add up to what the pool and the opened accounts started with
+	all := pool.Customers()
+	initialTotal := pool.InitialTotal()
+	verification := verifyTotalBalance(all, initialTotal)
+	run.RecordVerification(verification)
+	run.Finish()
+
+	queueMetrics.PrintReport()
+	actions.PrintReport("PER-ACTION RESULTS")
+	printJourneyStats(stats)
+	// Only journeys record transfers once the bank acknowledged them, which the
+	// session guarantees rely on
+	run.RecordSessionGuarantees(all)
+
+	fmt.Printf("\n=== Detailed Report ===\n")
+	reportFile, err := os.OpenFile("journey_attack_report.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	timestamp := fmt.Sprintf("==== Journey Attack Run at %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
+	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, queueMetrics)
+	reportFile.WriteString(fmt.Sprintf("Journey: %s, Workers: %d\n", journey.Name, workers))
+	reportFile.WriteString(fmt.Sprintf("Journeys: %d, Completed: %d, Aborted: %d\n", stats.Journeys, stats.Completed, stats.Aborted))
+	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, verification.FinalTotal))
+
+	for _, label := range actions.Labels() {
+		a := stats.Actions[label]
+		reportFile.WriteString(fmt.Sprintf("--- Action: %s (violations: %d, skipped: %d)\n", label, a.Violations, a.Skipped))
+		vegeta.NewTextReporter(actions.Get(label))(reportFile)
+	}
+	reportFile.WriteString("\n\n")
+	fmt.Printf("Report appended to journey_attack_report.txt\n")
+
+	report := run.Report(queueMetrics)
+	report.Breakdowns = map[string][]BreakdownEntry{"actions": actions.Entries()}
+	report.Journeys = &stats
+	saveStructuredReport("journey_attack_report", report)
+}
+
+// printJourneyStats prints how many journeys completed and the outcome of every action
+func printJourneyStats(stats action.Stats) {
+	fmt.Printf("\n🧭 JOURNEYS:\n")
+	fmt.Printf("   Started: %d, Completed: %d, Aborted: %d\n", stats.Journeys, stats.Completed, stats.Aborted)
+	fmt.Printf("   %-14s %9s %9s %7s %10s %8s %12s %12s\n",
+		"", "Executed", "Succeeded", "Failed", "Violations", "Skipped", "Mean", "Max")
+	for _, name := range stats.ActionNames() {
+		a := stats.Actions[name]
+		fmt.Printf("   %-14s %9d %9d %7d %10d %8d %12v %12v\n",
+			name, a.Executed, a.Succeeded, a.Failed, a.Violations, a.Skipped, a.MeanLatency(), a.MaxLatency)
+	}
+
+	violations := uint64(0)
+	for _, a := range stats.Actions {
+		violations += a.Violations
+	}
+	if violations > 0 {
+		fmt.Printf("   ❌ %d actions observed a state other than the session expected\n", violations)
+	}
+}
@@ -0,0 +1,33 @@
+package loadtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/domain/action"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionResultToVegeta(t *testing.T) {
+	start := time.Now()
+	result := action.ActionResult{Journey: "j", Action: "check-balance", Start: start, Latency: time.Millisecond, Outcome: action.OutcomeSuccess}
+
+	res := actionResultToVegeta(result)
+	require.NotNil(t, res)
+	assert.Equal(t, EndpointGetAccount, ClassifyEndpoint(res))
+	assert.Equal(t, uint16(200), res.Code)
+
+	result.Outcome, result.Err = action.OutcomeViolation, &action.ExpectationError{Action: "check-balance", Expected: "balance 1.00", Actual: "2.00"}
+	res = actionResultToVegeta(result)
+	assert.Equal(t, uint16(200), res.Code)
+	assert.Contains(t, res.Error, "expected balance 1.00")
+
+	result.Outcome, result.Err = action.OutcomeFailed, errors.New("connection refused")
+	res = actionResultToVegeta(result)
+	assert.Equal(t, "error", StatusClass(res))
+
+	result.Outcome = action.OutcomeSkipped
+	assert.Nil(t, actionResultToVegeta(result))
+}
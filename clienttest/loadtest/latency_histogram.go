@@ -0,0 +1,64 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// ParseLatencyBuckets parses histogram buckets such as "0,5ms,10ms,50ms" or "[5ms,10ms]".
+// A leading 0 bucket is added when missing.
+func ParseLatencyBuckets(s string) (vegeta.Buckets, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") {
+		s = "[" + s + "]"
+	}
+	var buckets vegeta.Buckets
+	if err := buckets.UnmarshalText([]byte(s)); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return nil, fmt.Errorf("buckets must be increasing: %v", buckets)
+		}
+	}
+	return buckets, nil
+}
+
+// RenderHistogram draws the histogram as horizontal bars, the largest bucket
+// taking barWidth characters
+func RenderHistogram(w io.Writer, h *vegeta.Histogram, barWidth int) {
+	if h == nil || h.Total == 0 {
+		fmt.Fprintf(w, "   (no samples)\n")
+		return
+	}
+
+	var largest uint64
+	for _, count := range h.Counts {
+		if count > largest {
+			largest = count
+		}
+	}
+
+	labels := make([]string, len(h.Buckets))
+	labelWidth := 0
+	for i := range h.Buckets {
+		left, right := h.Buckets.Nth(i)
+		labels[i] = fmt.Sprintf("[%s, %s)", left, right)
+		if len(labels[i]) > labelWidth {
+			labelWidth = len(labels[i])
+		}
+	}
+
+	for i, count := range h.Counts {
+		bar := int(float64(count) / float64(largest) * float64(barWidth))
+		if bar == 0 && count > 0 {
+			bar = 1 // keep rare buckets visible, they are often the interesting ones
+		}
+		fmt.Fprintf(w, "   %-*s %s%s %d (%.2f%%)\n", labelWidth, labels[i],
+			strings.Repeat("█", bar), strings.Repeat(" ", barWidth-bar),
+			count, float64(count)/float64(h.Total)*100)
+	}
+}
@@ -0,0 +1,84 @@
+package loadtest
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MannWhitneyResult is the outcome of a Mann-Whitney U test of a candidate latency
+// distribution against a baseline
+type MannWhitneyResult struct {
+	BaselineCount  uint64  `json:"baseline_count"`
+	CandidateCount uint64  `json:"candidate_count"`
+	U              float64 `json:"u"` // Of the candidate: pairs where the candidate is slower, ties count half
+	Z              float64 `json:"z"` // Positive when the candidate is slower
+	PValue         float64 `json:"p_value"`
+	// Superiority is the probability that a random candidate request is slower than a
+	// random baseline request, 0.5 when neither run is faster
+	Superiority float64 `json:"superiority"`
+}
+
+// MannWhitney runs a two-sided Mann-Whitney U test on two latency distributions given
+// as histogram bins. Latencies within a bin are ties; the p-value uses the normal
+// approximation with tie correction, which is accurate for the sample sizes of a run.
+func MannWhitney(baseline, candidate []HistogramBin) MannWhitneyResult {
+	type tie struct{ baseline, candidate uint64 }
+	ties := map[time.Duration]*tie{}
+	add := func(bins []HistogramBin, candidate bool) uint64 {
+		var total uint64
+		for _, bin := range bins {
+			t, ok := ties[bin.Latency]
+			if !ok {
+				t = &tie{}
+				ties[bin.Latency] = t
+			}
+			if candidate {
+				t.candidate += bin.Count
+			} else {
+				t.baseline += bin.Count
+			}
+			total += bin.Count
+		}
+		return total
+	}
+	result := MannWhitneyResult{
+		BaselineCount:  add(baseline, false),
+		CandidateCount: add(candidate, true),
+		PValue:         1,
+		Superiority:    0.5,
+	}
+	nb, nc := float64(result.BaselineCount), float64(result.CandidateCount)
+	if nb == 0 || nc == 0 {
+		return result
+	}
+
+	latencies := make([]time.Duration, 0, len(ties))
+	for latency := range ties {
+		latencies = append(latencies, latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	// Rank sum of the candidate with mid-ranks for ties
+	var rankSum, tieTerm, ranked float64
+	for _, latency := range latencies {
+		t := ties[latency]
+		size := float64(t.baseline + t.candidate)
+		midRank := ranked + (size+1)/2
+		rankSum += float64(t.candidate) * midRank
+		tieTerm += size*size*size - size
+		ranked += size
+	}
+	result.U = rankSum - nc*(nc+1)/2
+	result.Superiority = result.U / (nb * nc)
+
+	n := nb + nc
+	variance := nb * nc / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		// Every latency is the same
+		return result
+	}
+	result.Z = (result.U - nb*nc/2) / math.Sqrt(variance)
+	result.PValue = math.Erfc(math.Abs(result.Z) / math.Sqrt2)
+	return result
+}
@@ -0,0 +1,300 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Operation is one kind of request in a mixed workload
+type Operation string
+
+const (
+	OpGetAccount    Operation = "get-account"    // GET /accounts/{id}
+	OpListAccounts  Operation = "list-accounts"  // GET /accounts
+	OpCreateAccount Operation = "create-account" // POST /accounts
+	OpTransfer      Operation = "transfer"       // POST /accounts/transfer
+)
+
+// AllOperations lists every operation a mixed workload can issue
+var AllOperations = []Operation{OpGetAccount, OpListAccounts, OpCreateAccount, OpTransfer}
+
+// IsRead reports whether the operation leaves the accounts untouched
+func (op Operation) IsRead() bool {
+	return op == OpGetAccount || op == OpListAccounts
+}
+
+// WorkloadMix holds the relative weight of every operation
+type WorkloadMix map[Operation]int
+
+// DefaultWorkloadMix is an 80% reads / 20% writes blend
+var DefaultWorkloadMix = WorkloadMix{
+	OpGetAccount:    70,
+	OpListAccounts:  10,
+	OpCreateAccount: 5,
+	OpTransfer:      15,
+}
+
+// ParseWorkloadMix parses weights such as "get-account=70,list-accounts=10,transfer=20"
+func ParseWorkloadMix(s string) (WorkloadMix, error) {
+	mix := WorkloadMix{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid mix entry %q, expected <operation>=<weight>", part)
+		}
+		op := Operation(strings.TrimSpace(name))
+		if !op.valid() {
+			return nil, fmt.Errorf("unknown operation: %q", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", op, value)
+		}
+		mix[op] = weight
+	}
+	if mix.total() == 0 {
+		return nil, fmt.Errorf("workload mix has no positive weight")
+	}
+	return mix, nil
+}
+
+func (op Operation) valid() bool {
+	for _, known := range AllOperations {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (mix WorkloadMix) total() int {
+	total := 0
+	for _, weight := range mix {
+		total += weight
+	}
+	return total
+}
+
+// ReadRatio returns the share of reads in the mix, between 0 and 1
+func (mix WorkloadMix) ReadRatio() float64 {
+	reads := 0
+	for op, weight := range mix {
+		if op.IsRead() {
+			reads += weight
+		}
+	}
+	return float64(reads) / float64(mix.total())
+}
+
+// String renders the mix in the same format ParseWorkloadMix accepts
+func (mix WorkloadMix) String() string {
+	var buf bytes.Buffer
+	for _, op := range AllOperations {
+		if mix[op] == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, "%s=%d", op, mix[op])
+	}
+	return buf.String()
+}
+
+// pick returns the operation whose cumulative weight covers n, with 0 <= n < mix.total()
+func (mix WorkloadMix) pick(n int) Operation {
+	for _, op := range AllOperations {
+		if n < mix[op] {
+			return op
+		}
+		n -= mix[op]
+	}
+	return AllOperations[len(AllOperations)-1]
+}
+
+// MixedWorkloadTargeter interleaves weighted reads and writes against a pool of customers
+type MixedWorkloadTargeter struct {
+	customers []*domain.Customer
+	mix       WorkloadMix
+	pending   *pendingTransfers
+
+	mu  sync.Mutex // Guards rng, the targeter is called from many workers
+	rng *rand.Rand
+}
+
+// NewMixedWorkloadTargeter creates a targeter issuing operations in the proportions of mix
+func NewMixedWorkloadTargeter(customers []*domain.Customer, mix WorkloadMix) (*MixedWorkloadTargeter, error) {
+	if mix[OpTransfer] > 0 && len(customers) < 2 {
+		return nil, fmt.Errorf("transfers need at least 2 customers, got %d", len(customers))
+	}
+	if mix[OpGetAccount] > 0 && len(customers) == 0 {
+		return nil, fmt.Errorf("account reads need at least 1 customer")
+	}
+
+	return &MixedWorkloadTargeter{
+		customers: customers,
+		mix:       mix,
+		pending:   newPendingTransfers(),
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Targeter returns the vegeta.Targeter producing the mixed requests
+func (mt *MixedWorkloadTargeter) Targeter() vegeta.Targeter {
+	return func(t *vegeta.Target) error {
+		*t = mt.generateTarget()
+		return nil
+	}
+}
+
+func (mt *MixedWorkloadTargeter) generateTarget() vegeta.Target {
+	mt.mu.Lock()
+	op := mt.mix.pick(mt.rng.Intn(mt.mix.total()))
+	from, to := 0, 0
+	if n := len(mt.customers); n > 1 {
+		from = mt.rng.Intn(n)
+		to = mt.rng.Intn(n - 1)
+		if to >= from {
+			to++ // never transfer to self, that is the topology attack's job
+		}
+	}
+	mt.mu.Unlock()
+
+	switch op {
+	case OpGetAccount:
+		return vegeta.Target{
+			Method: "GET",
+			URL:    utils.BASE_URL + "/accounts/" + mt.customers[from].GetAccountID(),
+		}
+	case OpListAccounts:
+		return vegeta.Target{
+			Method: "GET",
+			URL:    utils.BASE_URL + "/accounts",
+		}
+	case OpCreateAccount:
+		body, _ := json.Marshal(domain.CreateAccountRequest{InitialBalance: 100})
+		return vegeta.Target{
+			Method: "POST",
+			URL:    utils.BASE_URL + "/accounts",
+			Header: http.Header{"Content-Type": []string{"application/json"}},
+			Body:   body,
+		}
+	default:
+		transfer := plannedTransfer{
+			label:  string(OpTransfer),
+			from:   mt.customers[from],
+			to:     mt.customers[to],
+			amount: 1,
+		}
+		body, _ := json.Marshal(domain.TransferRequest{
+			FromAccountID: transfer.from.GetAccountID(),
+			ToAccountID:   transfer.to.GetAccountID(),
+			Amount:        transfer.amount,
+		})
+		return vegeta.Target{
+			Method: "POST",
+			URL:    tagURL(utils.BASE_URL+"/accounts/transfer", mt.pending.add(transfer)),
+			Header: http.Header{"Content-Type": []string{"application/json"}},
+			Body:   body,
+		}
+	}
+}
+
+// Observe keeps the customers' ledgers in sync with the transfers the server accepted.
+// Per-operation metrics come from the QueueMetrics endpoint breakdown.
+func (mt *MixedWorkloadTargeter) Observe(res *vegeta.Result) {
+	mt.pending.settle(res)
+}
+
+// AttackMixed runs a weighted blend of reads and writes
+func AttackMixed(rps, testDuration int, mix WorkloadMix) {
+	const numCustomers = 20
+	const initialBalance = 100.0
+
+	fmt.Printf("Starting mixed workload attack: %d RPS for %d seconds\n", rps, testDuration)
+	fmt.Printf("Workload mix: %s (%.0f%% reads)\n", mix, mix.ReadRatio()*100)
+	fmt.Printf("Setting up test customers...\n")
+
+	customers, err := createCustomers("mixed", numCustomers, initialBalance)
+	if err != nil {
+		fmt.Printf("Failed to setup customers: %v\n", err)
+		return
+	}
+	defer cleanupTransferCustomers(customers)
+	initialTotal := totalExpectedBalance(customers)
+
+	mixedTargeter, err := NewMixedWorkloadTargeter(customers, mix)
+	if err != nil {
+		fmt.Printf("Failed to create targeter: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Created %d customers\n", len(customers))
+	fmt.Printf("Target: %s\n", utils.BASE_URL)
+	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
+
+	queueMetrics := NewQueueMetrics()
+	run := newRun("mixed")
+	run.SetParam("mix", mix.String())
+	fmt.Printf("Mixed workload attack in progress...\n")
+
+	attacker := &Attacker{
+		targeter: mixedTargeter.Targeter(),
+		rate:     vegeta.Rate{Freq: rps, Per: time.Second},
+		duration: time.Duration(testDuration) * time.Second,
+		attacker: newVegetaAttacker(),
+		metrics:  queueMetrics,
+	}
+	attacker.OnResult(mixedTargeter.Observe)
+
+	run.Attach(attacker)
+	attacker.Attack()
+	queueMetrics.Close()
+	fmt.Printf("Attack completed!\n\n")
+
+	// Accounts created by the workload are not tracked, only the customers' balances are verified
+	verification := verifyTotalBalance(customers, initialTotal)
+	run.RecordVerification(verification)
+	run.Finish()
+
+	queueMetrics.PrintReport()
+
+	fmt.Printf("\n=== Detailed Report ===\n")
+	reportFile, err := os.OpenFile("mixed_attack_report.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	timestamp := fmt.Sprintf("==== Mixed Workload Run at %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
+	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, queueMetrics)
+	reportFile.WriteString(fmt.Sprintf("Mix: %s\n", mix))
+	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, verification.FinalTotal))
+
+	for _, label := range queueMetrics.Endpoints().Labels() {
+		reportFile.WriteString(fmt.Sprintf("--- Endpoint: %s\n", label))
+		vegeta.NewTextReporter(queueMetrics.Endpoints().Get(label))(reportFile)
+	}
+	reportFile.WriteString("\n\n")
+	fmt.Printf("Report appended to mixed_attack_report.txt\n")
+
+	saveStructuredReport("mixed_attack_report", run.Report(queueMetrics))
+}
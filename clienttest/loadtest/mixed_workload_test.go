@@ -0,0 +1,33 @@
+package loadtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkloadMix(t *testing.T) {
+	mix, err := ParseWorkloadMix("get-account=60, list-accounts=20,transfer=20")
+	require.NoError(t, err)
+	assert.Equal(t, WorkloadMix{OpGetAccount: 60, OpListAccounts: 20, OpTransfer: 20}, mix)
+	assert.InDelta(t, 0.8, mix.ReadRatio(), 1e-9)
+	assert.Equal(t, "get-account=60,list-accounts=20,transfer=20", mix.String())
+
+	_, err = ParseWorkloadMix("delete-account=10")
+	assert.Error(t, err)
+	_, err = ParseWorkloadMix("transfer=0")
+	assert.Error(t, err)
+	_, err = ParseWorkloadMix("transfer")
+	assert.Error(t, err)
+}
+
+func TestWorkloadMixPickFollowsWeights(t *testing.T) {
+	mix := WorkloadMix{OpGetAccount: 3, OpTransfer: 1}
+
+	counts := map[Operation]int{}
+	for n := 0; n < mix.total(); n++ {
+		counts[mix.pick(n)]++
+	}
+	assert.Equal(t, map[Operation]int{OpGetAccount: 3, OpTransfer: 1}, counts)
+}
@@ -0,0 +1,104 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// pairInitialBalance is what the pair scenarios open their accounts with, or take
+// them from a dataset holding. Every pair debits the same account for the whole run,
+// which must never run dry: a rejected transfer would hide what the scenario measures.
+const pairInitialBalance = 10000.0
+
+// pairRun is a run of a scenario that transfers within pairs of accounts, one transfer
+// at a time per pair, and follows every transfer with balance reads: staleness and
+// settlement
+type pairRun struct {
+	run          *Run
+	metrics      *QueueMetrics
+	operations   *MetricsBreakdown // Keyed by "transfer" and "poll"
+	customers    []*domain.Customer
+	initialTotal float64
+	verification BalanceVerification
+}
+
+// newPairRun starts a run of the scenario over the customers
+func newPairRun(scenario string, customers []*domain.Customer, testDuration int) *pairRun {
+	run := newRun(scenario)
+	run.config.Duration = time.Duration(testDuration) * time.Second
+	return &pairRun{
+		run:          run,
+		metrics:      NewQueueMetrics(),
+		operations:   NewMetricsBreakdownWithHistogram(LatencyBuckets),
+		customers:    customers,
+		initialTotal: totalExpectedBalance(customers),
+	}
+}
+
+// drive runs transfer over and over for every pair until the run's duration elapses or
+// the load test is interrupted, recording the results it sends, then verifies the
+// customers' balances
+func (p *pairRun) drive(pairs [][2]*domain.Customer, transfer func(from, to *domain.Customer, results chan<- labeledResult)) {
+	results := make(chan labeledResult, 1024)
+	deadline, interrupts := time.Now().Add(p.run.config.Duration), interruptContext()
+	var wg sync.WaitGroup
+	for _, pair := range pairs {
+		wg.Add(1)
+		go func(from, to *domain.Customer) {
+			defer wg.Done()
+			for time.Now().Before(deadline) && interrupts.Err() == nil {
+				transfer(from, to, results)
+			}
+		}(pair[0], pair[1])
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	collectResults(p.run, p.metrics, p.operations, results)
+	p.metrics.Close()
+	p.operations.Close()
+	fmt.Printf("Attack completed!\n\n")
+
+	p.verification = verifyTotalBalance(p.customers, p.initialTotal)
+	p.run.RecordVerification(p.verification)
+	p.run.Finish()
+
+	p.metrics.PrintReport()
+	p.operations.PrintReport("PER-OPERATION RESULTS")
+}
+
+// saveReports appends the text report, with the scenario's figures written by details,
+// and saves the structured report, completed by the scenario's figures
+func (p *pairRun) saveReports(baseName, title string, details func(w io.Writer), complete func(report *QueueReport)) {
+	fmt.Printf("\n=== Detailed Report ===\n")
+	reportFile, err := os.OpenFile(baseName+".txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	timestamp := fmt.Sprintf("==== %s Run at %s ===\n", title, time.Now().Format("2006-01-02 15:04:05"))
+	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, p.metrics)
+	details(reportFile)
+	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", p.initialTotal, p.verification.FinalTotal))
+	for _, label := range p.operations.Labels() {
+		reportFile.WriteString(fmt.Sprintf("--- Operation: %s\n", label))
+		vegeta.NewTextReporter(p.operations.Get(label))(reportFile)
+	}
+	reportFile.WriteString("\n\n")
+	fmt.Printf("Report appended to %s.txt\n", baseName)
+
+	report := p.run.Report(p.metrics)
+	report.Breakdowns = map[string][]BreakdownEntry{"operations": p.operations.Entries()}
+	complete(&report)
+	saveStructuredReport(baseName, report)
+}
@@ -0,0 +1,79 @@
+package loadtest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"com.ndnhuy.mybank/domain"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// plannedTransfer is a transfer that has been handed to vegeta but whose result is not yet known
+type plannedTransfer struct {
+	label    string // Topology or operation the transfer belongs to
+	from, to *domain.Customer
+	amount   float64
+}
+
+// pendingTransfers remembers in-flight transfers so that the customers' ledgers
+// only record the transfers the server actually accepted
+type pendingTransfers struct {
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]plannedTransfer
+}
+
+func newPendingTransfers() *pendingTransfers {
+	return &pendingTransfers{
+		pending: make(map[uint64]plannedTransfer),
+	}
+}
+
+// add registers a transfer and returns the tag to attach to its target URL
+func (p *pendingTransfers) add(transfer plannedTransfer) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextID
+	p.nextID++
+	p.pending[id] = transfer
+	return fmt.Sprintf("%s/%d", transfer.label, id)
+}
+
+// settle looks up the transfer behind a result and, if the server accepted it,
+// applies it to the customers' ledgers
+func (p *pendingTransfers) settle(res *vegeta.Result) (plannedTransfer, bool) {
+	_, id, ok := splitTag(resultTag(res))
+	if !ok {
+		return plannedTransfer{}, false
+	}
+
+	p.mu.Lock()
+	transfer, ok := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+	if !ok {
+		return plannedTransfer{}, false
+	}
+
+	if res.Code == http.StatusOK {
+		transfer.from.RecordTransfer(transfer.to, transfer.amount)
+	}
+	return transfer, true
+}
+
+// splitTag splits a "<label>/<id>" tag into its parts
+func splitTag(tag string) (label string, id uint64, ok bool) {
+	sep := strings.LastIndexByte(tag, '/')
+	if sep < 0 {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(tag[sep+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return tag[:sep], id, true
+}
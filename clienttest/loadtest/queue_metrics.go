@@ -2,42 +2,180 @@ package loadtest
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"com.ndnhuy.mybank/balancer"
+	"com.ndnhuy.mybank/promscrape"
 	vegeta "github.com/tsenart/vegeta/v12/lib"
 )
 
+// LatencyBuckets are the latency histogram buckets of every QueueMetrics,
+// overall and per endpoint
+var LatencyBuckets = vegeta.Buckets{
+	0,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
 // QueueMetrics wraps vegeta.Metrics with additional queuing theory calculations
 type QueueMetrics struct {
 	*vegeta.Metrics
 	startTime time.Time
+	hdr       *HDRHistogram                 // High resolution latencies for the far tail (p99.9, p99.99)
+	series    *TimeSeries                   // Per-interval snapshots of the attack
+	server    *promscrape.ServerQueueReport // Server-measured queue figures, nil if not scraped
+
+	schedule        *Schedule     // When requests should have been sent, nil if unknown
+	lag             *HDRHistogram // Scheduling lag of every request
+	corrected       *HDRHistogram // Latencies measured from the scheduled send time
+	inFlightSum     float64       // Of the in-flight samples, for Little's law
+	inFlightSamples int
+	inFlightMax     int64
+
+	endpoints     *MetricsBreakdown // Keyed by endpoint, e.g. "GET /accounts/{id}"
+	statusClasses *MetricsBreakdown // Keyed by endpoint and status class, e.g. "GET /accounts 5xx"
+
+	instancesMu sync.Mutex        // The Balancer observes concurrently
+	instances   *MetricsBreakdown // Keyed by instance base URL, nil without a Balancer
+
+	availability *balancer.Availability // Of the attack's period, nil without a Balancer
+	retriesBase  balancer.Availability  // Retry counts before the attack
+
+	warming atomic.Bool   // The instances observed concurrently belong to the warm-up
+	warmup  *WarmupReport // nil without a warm-up
 }
 
 // NewQueueMetrics creates a new QueueMetrics instance
 func NewQueueMetrics() *QueueMetrics {
-	return &QueueMetrics{
-		Metrics:   &vegeta.Metrics{},
-		startTime: time.Now(),
+	qm := &QueueMetrics{
+		Metrics: &vegeta.Metrics{
+			Histogram: &vegeta.Histogram{Buckets: LatencyBuckets},
+		},
+		startTime:     time.Now(),
+		hdr:           NewHDRHistogram(3),
+		lag:           NewHDRHistogram(3),
+		corrected:     NewHDRHistogram(3),
+		series:        NewTimeSeries(ReportInterval),
+		endpoints:     NewMetricsBreakdownWithHistogram(LatencyBuckets),
+		statusClasses: NewMetricsBreakdownWithHistogram(LatencyBuckets),
+	}
+	if Balancer != nil {
+		qm.instances = NewMetricsBreakdownWithHistogram(LatencyBuckets)
+		qm.retriesBase = Balancer.Availability(time.Now())
+		Balancer.Observe(qm.observeInstance)
+	}
+	return qm
+}
+
+// Add records a result in the overall metrics and in the per-endpoint breakdowns
+func (qm *QueueMetrics) Add(res *vegeta.Result) {
+	qm.Metrics.Add(res)
+	qm.hdr.Record(res.Latency)
+	qm.recordScheduled(res)
+	qm.series.Add(res)
+
+	endpoint := ClassifyEndpoint(res)
+	qm.endpoints.Add(endpoint, res)
+	qm.statusClasses.Add(endpoint+" "+StatusClass(res), res)
+}
+
+// Close computes the summary metrics, overall and per endpoint
+func (qm *QueueMetrics) Close() {
+	qm.Metrics.Close()
+	qm.endpoints.Close()
+	qm.statusClasses.Close()
+	if qm.instances != nil {
+		Balancer.Observe(nil)
+		qm.recordAvailability()
+		qm.instancesMu.Lock()
+		qm.instances.Close()
+		qm.instancesMu.Unlock()
 	}
 }
 
+// HDR returns the high resolution latency histogram
+func (qm *QueueMetrics) HDR() *HDRHistogram {
+	return qm.hdr
+}
+
+// SetWarmup attaches the summary of the warm-up that ended at now, where the
+// measurement begins
+func (qm *QueueMetrics) SetWarmup(report *WarmupReport, now time.Time) {
+	qm.warmup = report
+	qm.startTime = now
+	if qm.instances != nil {
+		qm.retriesBase = Balancer.Availability(now)
+	}
+}
+
+// Warmup returns the summary of the warm-up, nil without one
+func (qm *QueueMetrics) Warmup() *WarmupReport {
+	return qm.warmup
+}
+
+// SetServerMetrics attaches the server-measured queue figures of the run
+func (qm *QueueMetrics) SetServerMetrics(report *promscrape.ServerQueueReport) {
+	qm.server = report
+}
+
+// ServerMetrics returns the server-measured queue figures, or nil if they were not scraped
+func (qm *QueueMetrics) ServerMetrics() *promscrape.ServerQueueReport {
+	return qm.server
+}
+
+// TimeSeries returns the per-interval snapshots of the attack
+func (qm *QueueMetrics) TimeSeries() *TimeSeries {
+	return qm.series
+}
+
+// Endpoints returns the metrics split per endpoint
+func (qm *QueueMetrics) Endpoints() *MetricsBreakdown {
+	return qm.endpoints
+}
+
+// StatusClasses returns the metrics split per endpoint and status class
+func (qm *QueueMetrics) StatusClasses() *MetricsBreakdown {
+	return qm.statusClasses
+}
+
 // GetArrivalRate returns the arrival rate (λ) in requests/second
 func (qm *QueueMetrics) GetArrivalRate() float64 {
 	return qm.Rate
 }
 
-// GetServiceRate returns the service rate (μ) in requests/second
+// GetServiceTime returns the estimated mean service time (S), see EstimateServiceTime
+func (qm *QueueMetrics) GetServiceTime() time.Duration {
+	return EstimateServiceTime(qm.series.Snapshots(), qm.hdr)
+}
+
+// GetServiceRate returns the service rate (μ = 1/S) of one server in requests/second
 func (qm *QueueMetrics) GetServiceRate() float64 {
-	return qm.Throughput
+	serviceTime := qm.GetServiceTime()
+	if serviceTime <= 0 {
+		return 0
+	}
+	return 1 / serviceTime.Seconds()
 }
 
-// GetTrafficIntensity returns the traffic intensity (ρ = λ/μ)
+// GetTrafficIntensity returns the traffic intensity (ρ = λ/(c·μ)) for c = Servers
 func (qm *QueueMetrics) GetTrafficIntensity() float64 {
-	if qm.Throughput <= 0 {
+	mu := qm.GetServiceRate()
+	if mu <= 0 {
 		return 999.0 // Indicate overload
 	}
-	return qm.Rate / qm.Throughput
+	return qm.Rate / (float64(Servers) * mu)
 }
 
 // GetObservationDuration returns how long the test ran
@@ -45,8 +183,12 @@ func (qm *QueueMetrics) GetObservationDuration() time.Duration {
 	return qm.Duration
 }
 
-// GetSystemStatus returns a color-coded status based on traffic intensity
+// GetSystemStatus returns a color-coded status based on traffic intensity. A saturated
+// attacker comes first: the server was then offered less load than requested.
 func (qm *QueueMetrics) GetSystemStatus() string {
+	if qm.ClientSaturated() {
+		return "🟠 CLIENT SATURATED"
+	}
 	rho := qm.GetTrafficIntensity()
 	if rho >= 1.0 {
 		return "🔴 OVERLOADED"
@@ -77,6 +219,9 @@ func (qm *QueueMetrics) AssessResponseTime() string {
 
 // AssessSystemHealth provides overall system health assessment
 func (qm *QueueMetrics) AssessSystemHealth() string {
+	if qm.ClientSaturated() {
+		return "🟠 Load generator could not keep up, server capacity unknown"
+	}
 	rho := qm.GetTrafficIntensity()
 	if rho >= 1.0 {
 		return "🔴 System cannot keep up with demand"
@@ -90,11 +235,22 @@ func (qm *QueueMetrics) AssessSystemHealth() string {
 	return "🟢 System has excess capacity"
 }
 
+// interruption tells when the load test was interrupted, relative to the start of the
+// measurements, nil when it ran its course
+func (qm *QueueMetrics) interruption() *Interruption {
+	at := interrupted()
+	if at.IsZero() {
+		return nil
+	}
+	return &Interruption{At: at, After: max(at.Sub(qm.startTime), 0)}
+}
+
 // PrintReport prints a comprehensive report similar to Java QueueMetrics
 func (qm *QueueMetrics) PrintReport() {
 	fmt.Println("╔══════════════════════════════════════════════════════════════╗")
 	fmt.Println("║                    QUEUING SYSTEM ANALYSIS                  ║")
 	fmt.Println("╚══════════════════════════════════════════════════════════════╝")
+	printInterruption(qm.interruption())
 
 	// System Overview
 	fmt.Println("\n📊 SYSTEM OVERVIEW:")
@@ -112,25 +268,84 @@ func (qm *QueueMetrics) PrintReport() {
 	fmt.Printf("   Max Response Time:    %v\n", qm.Latencies.Max)
 	fmt.Printf("   Success Rate:         %.2f%%\n", qm.Success*100)
 
+	// Tail latency, the single transfer worker shows up here long before the mean moves
+	fmt.Println("\n📈 HIGH-RESOLUTION PERCENTILES:")
+	for _, p := range qm.hdr.Percentiles() {
+		fmt.Printf("   %-8s %v\n", percentileName(p.Quantile)+":", p.Latency)
+	}
+
+	fmt.Println("\n📊 LATENCY HISTOGRAM:")
+	RenderHistogram(os.Stdout, qm.Histogram, 40)
+
 	// Queuing Theory Analysis
 	fmt.Println("\n🔬 QUEUING THEORY ANALYSIS:")
 	fmt.Printf("   Arrival Rate (λ):      %.2f requests/sec\n", qm.GetArrivalRate())
 	fmt.Printf("   Service Rate (μ):      %.2f requests/sec\n", qm.GetServiceRate())
+	fmt.Printf("   Service Time (S):      %v (estimated at low load)\n", qm.GetServiceTime())
+	fmt.Printf("   Servers (c):           %d\n", Servers)
 	fmt.Printf("   Traffic Intensity (ρ): %.3f\n", qm.GetTrafficIntensity())
 	fmt.Printf("   Throughput:           %.2f requests/sec\n", qm.Throughput)
 
+	qm.printCoordinatedOmission()
+	qm.printModelPredictions()
+
+	if qm.server != nil {
+		qm.printServerComparison()
+	}
+
 	// Performance Assessment
 	fmt.Println("\n🎯 PERFORMANCE ASSESSMENT:")
 	fmt.Printf("   Response Time:  %s\n", qm.AssessResponseTime())
 	fmt.Printf("   System Health:  %s\n", qm.AssessSystemHealth())
 
+	// A single endpoint is already covered by the overall numbers
+	if len(qm.endpoints.Labels()) > 1 {
+		qm.endpoints.PrintReport("PER-ENDPOINT RESULTS")
+	}
+	qm.statusClasses.PrintReport("PER-STATUS-CLASS RESULTS")
+	if qm.instances != nil {
+		qm.instances.PrintReport("PER-INSTANCE RESULTS (" + string(Balancer.Policy()) + ")")
+		printAvailability(qm.availability)
+	}
+	printWarmupReport(qm.warmup)
+
 	// Warnings
 	if qm.Success < 1.0 {
 		fmt.Printf("\n⚠️  Warning: Success rate is %.2f%%. Some requests failed!\n", qm.Success*100)
 	}
-	if qm.GetTrafficIntensity() >= 1.0 {
+	if qm.ClientSaturated() {
+		fmt.Printf("\n⚠️  Load generator saturated: sent %.2f of %.2f requested requests/sec, p99 send lag %v. "+
+			"Compare the corrected percentiles, not the raw ones.\n",
+			qm.Rate, qm.RequestedRate(), qm.lag.Quantile(0.99))
+	} else if qm.GetTrafficIntensity() >= 1.0 {
 		fmt.Println("\n⚠️  SYSTEM OVERLOADED!")
 	}
 
 	fmt.Println("\n" + strings.Repeat("═", 66))
 }
+
+// printServerComparison prints client-observed queue figures next to the ones the server measured
+func (qm *QueueMetrics) printServerComparison() {
+	server := qm.server
+	fmt.Println("\n🖥️  CLIENT vs SERVER (transfer queue):")
+	fmt.Printf("   %-22s %14s %14s\n", "", "Client", "Server")
+	fmt.Printf("   %-22s %14.2f %14.2f\n", "Arrival Rate (λ)", qm.GetArrivalRate(), server.ArrivalRate)
+	fmt.Printf("   %-22s %14.2f %14.2f\n", "Service Rate (μ)", qm.GetServiceRate(), server.ServiceRate)
+	fmt.Printf("   %-22s %14.3f %14.3f\n", "Utilization (ρ)", qm.GetTrafficIntensity(), server.Utilization)
+	fmt.Printf("   %-22s %14v %14v\n", "Mean Response Time", qm.Latencies.Mean, server.MeanWaitTime+server.MeanServiceTime)
+	fmt.Printf("   %-22s %14s %14v\n", "Mean Wait Time", "-", server.MeanWaitTime)
+	fmt.Printf("   %-22s %14s %14v\n", "Mean Service Time", "-", server.MeanServiceTime)
+	fmt.Printf("   %-22s %14s %14.2f\n", "Mean Queue Length", "-", server.MeanQueueLength)
+	fmt.Printf("   %-22s %14s %14.0f\n", "Max Queue Length", "-", server.MaxQueueLength)
+	if server.ReportedUtilization != nil {
+		fmt.Printf("   %-22s %14s %14.3f\n", "system.utilization", "-", *server.ReportedUtilization)
+	}
+	fmt.Printf("   (%d scrapes over %v", server.Scrapes, server.Window.Round(time.Millisecond))
+	if server.FailedScrapes > 0 {
+		fmt.Printf(", %d failed", server.FailedScrapes)
+	}
+	fmt.Printf(")\n")
+	if len(server.MissingMetrics) > 0 {
+		fmt.Printf("   ⚠️  Missing server metrics: %s\n", strings.Join(server.MissingMetrics, ", "))
+	}
+}
@@ -0,0 +1,62 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestClassifyEndpoint(t *testing.T) {
+	cases := map[string]*vegeta.Result{
+		EndpointGetAccount:    {Method: "GET", URL: "http://localhost:8080/accounts/8b0c1d3e"},
+		EndpointListAccounts:  {Method: "GET", URL: "http://localhost:8080/accounts"},
+		EndpointCreateAccount: {Method: "POST", URL: "http://localhost:8080/accounts#create-account"},
+		EndpointTransfer:      {Method: "POST", URL: "http://localhost:8080/accounts/transfer#cycle/3"},
+	}
+	for want, res := range cases {
+		assert.Equal(t, want, ClassifyEndpoint(res), res.URL)
+	}
+}
+
+func TestQueueMetricsSplitsPerEndpointAndStatusClass(t *testing.T) {
+	qm := NewQueueMetrics()
+	start := time.Now()
+	results := []*vegeta.Result{
+		{Method: "GET", URL: "http://localhost:8080/accounts", Code: 200, Latency: 80 * time.Millisecond, BytesIn: 1000, Timestamp: start},
+		{Method: "GET", URL: "http://localhost:8080/accounts/a", Code: 200, Latency: 2 * time.Millisecond, BytesIn: 40, Timestamp: start},
+		{Method: "POST", URL: "http://localhost:8080/accounts/transfer", Code: 500, Latency: 3 * time.Millisecond, BytesOut: 60, Timestamp: start},
+		{Method: "POST", URL: "http://localhost:8080/accounts/transfer", Code: 0, Error: "Client.Timeout exceeded", Timestamp: start},
+	}
+	for _, res := range results {
+		qm.Add(res)
+	}
+	qm.Close()
+
+	assert.Equal(t, uint64(4), qm.Requests)
+	assert.Equal(t, []string{EndpointListAccounts, EndpointGetAccount, EndpointTransfer}, qm.Endpoints().Labels())
+
+	transfers := qm.Endpoints().Get(EndpointTransfer)
+	require.NotNil(t, transfers)
+	assert.Equal(t, uint64(2), transfers.Requests)
+	assert.Equal(t, 0.0, transfers.Success)
+	assert.Equal(t, uint64(60), transfers.BytesOut.Total)
+	assert.Equal(t, 1, qm.Endpoints().Timeouts(EndpointTransfer))
+
+	list := qm.Endpoints().Get(EndpointListAccounts)
+	require.NotNil(t, list.Histogram)
+	assert.Equal(t, uint64(1), list.Histogram.Total)
+
+	assert.Equal(t, []string{
+		EndpointListAccounts + " 2xx",
+		EndpointGetAccount + " 2xx",
+		EndpointTransfer + " 5xx",
+		EndpointTransfer + " error",
+	}, qm.StatusClasses().Labels())
+
+	report := qm.Report("test")
+	assert.Len(t, report.Endpoints, 3)
+	assert.Len(t, report.StatusClasses, 4)
+}
@@ -0,0 +1,202 @@
+package loadtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Servers is the number of parallel servers c assumed by the queueing models.
+// The transfer path is drained by the single worker thread of AsyncBankDeskService.
+var Servers = 1
+
+// QueuePrediction holds the steady-state figures a queueing model predicts
+type QueuePrediction struct {
+	Model       string        `json:"model"`
+	Utilization float64       `json:"utilization"`  // ρ = λ / (c·μ)
+	Stable      bool          `json:"stable"`       // ρ < 1, otherwise the queue grows without bound
+	WaitTime    time.Duration `json:"wait_time"`    // Wq, time spent queued
+	QueueLength float64       `json:"queue_length"` // Lq, requests queued
+	Response    time.Duration `json:"response"`     // W = Wq + 1/μ
+	InSystem    float64       `json:"in_system"`    // L = λ·W
+}
+
+// PredictMM1 returns the M/M/1 prediction for arrival rate lambda and service rate mu (per second)
+func PredictMM1(lambda, mu float64) QueuePrediction {
+	prediction := PredictMMc(lambda, mu, 1)
+	prediction.Model = "M/M/1"
+	return prediction
+}
+
+// PredictMMc returns the M/M/c prediction for arrival rate lambda, per-server service
+// rate mu (per second) and c servers, using the Erlang C formula
+func PredictMMc(lambda, mu float64, c int) QueuePrediction {
+	prediction := QueuePrediction{Model: "M/M/c"}
+	if mu <= 0 || c < 1 {
+		return prediction
+	}
+	rho := lambda / (float64(c) * mu)
+	prediction.Utilization = rho
+	if rho >= 1 {
+		return prediction
+	}
+	prediction.Stable = true
+
+	wq := erlangC(lambda/mu, c) / (float64(c)*mu - lambda)
+	w := wq + 1/mu
+	prediction.WaitTime = secondsToDuration(wq)
+	prediction.QueueLength = lambda * wq
+	prediction.Response = secondsToDuration(w)
+	prediction.InSystem = lambda * w
+	return prediction
+}
+
+// erlangC returns the probability that an arrival has to wait, for an offered load
+// a = λ/μ on c servers
+func erlangC(a float64, c int) float64 {
+	// Erlang B by its stable recurrence, then converted to Erlang C
+	b := 1.0
+	for k := 1; k <= c; k++ {
+		b = a * b / (float64(k) + a*b)
+	}
+	rho := a / float64(c)
+	return b / (1 - rho + rho*b)
+}
+
+// MM1MedianResponse returns the median response time of an M/M/1 queue, whose
+// response time is exponentially distributed with rate μ - λ
+func MM1MedianResponse(lambda, mu float64) time.Duration {
+	if lambda >= mu {
+		return 0
+	}
+	return secondsToDuration(math.Ln2 / (mu - lambda))
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// minServiceTimeSamples is how many responses an interval needs before its
+// median is trusted as a service time estimate
+const minServiceTimeSamples = 10
+
+// EstimateServiceTime estimates the mean service time S from latencies measured
+// while little was queued: the lowest interval median of the run, or the 5th
+// percentile of all latencies when no interval has enough responses. Dividing the
+// load by throughput instead would report μ = λ whenever the system keeps up.
+func EstimateServiceTime(snapshots []IntervalSnapshot, latencies *HDRHistogram) time.Duration {
+	var best time.Duration
+	for _, snap := range snapshots {
+		if snap.Completed < minServiceTimeSamples || snap.P50 <= 0 {
+			continue
+		}
+		if best == 0 || snap.P50 < best {
+			best = snap.P50
+		}
+	}
+	if best > 0 {
+		return best
+	}
+	return latencies.Quantile(0.05)
+}
+
+// SweepUtilizations are the single-server utilizations the model predictions are tabulated at
+var SweepUtilizations = []float64{0.1, 0.3, 0.5, 0.7, 0.8, 0.9, 0.95}
+
+// SweepPoint is the predicted mean response time at one arrival rate, for 1 and 2 servers
+type SweepPoint struct {
+	ArrivalRate float64       `json:"arrival_rate"`
+	MM1Response time.Duration `json:"mm1_response"`
+	MM2Response time.Duration `json:"mm2_response"`
+}
+
+// ModelAnalysis compares the run's observed latencies with queueing model predictions
+type ModelAnalysis struct {
+	ServiceTime       time.Duration     `json:"service_time"`
+	ServiceRate       float64           `json:"service_rate"`
+	Servers           int               `json:"servers"`
+	ArrivalRate       float64           `json:"arrival_rate"`
+	Predictions       []QueuePrediction `json:"predictions"`
+	ObservedResponse  time.Duration     `json:"observed_response"`
+	ObservedInSystem  float64           `json:"observed_in_system"` // λ·W from observed latencies
+	ObservedMedian    time.Duration     `json:"observed_median"`
+	PredictedMedian   time.Duration     `json:"predicted_median"` // M/M/1
+	Sweep             []SweepPoint      `json:"sweep"`
+	ResponseDeviation float64           `json:"response_deviation"` // Observed / predicted mean response time of the assumed model
+}
+
+// AnalyzeModel predicts M/M/1 and M/M/c behaviour at the observed arrival rate
+func (qm *QueueMetrics) AnalyzeModel() ModelAnalysis {
+	lambda, mu := qm.GetArrivalRate(), qm.GetServiceRate()
+	analysis := ModelAnalysis{
+		ServiceTime:      qm.GetServiceTime(),
+		ServiceRate:      mu,
+		Servers:          Servers,
+		ArrivalRate:      lambda,
+		ObservedResponse: qm.Latencies.Mean,
+		ObservedInSystem: lambda * qm.Latencies.Mean.Seconds(),
+		ObservedMedian:   qm.hdr.Quantile(0.5),
+		PredictedMedian:  MM1MedianResponse(lambda, mu),
+	}
+
+	analysis.Predictions = append(analysis.Predictions, PredictMM1(lambda, mu))
+	if Servers > 1 {
+		analysis.Predictions = append(analysis.Predictions, PredictMMc(lambda, mu, Servers))
+	}
+	if assumed := analysis.Predictions[len(analysis.Predictions)-1]; assumed.Stable && assumed.Response > 0 {
+		analysis.ResponseDeviation = float64(analysis.ObservedResponse) / float64(assumed.Response)
+	}
+
+	for _, u := range SweepUtilizations {
+		arrival := u * mu
+		analysis.Sweep = append(analysis.Sweep, SweepPoint{
+			ArrivalRate: arrival,
+			MM1Response: PredictMM1(arrival, mu).Response,
+			MM2Response: PredictMMc(arrival, mu, 2).Response,
+		})
+	}
+	return analysis
+}
+
+// printModelPredictions prints the model predictions next to the observed figures
+func (qm *QueueMetrics) printModelPredictions() {
+	analysis := qm.AnalyzeModel()
+	if analysis.ServiceRate <= 0 {
+		return
+	}
+
+	fmt.Println("\n🧮 QUEUEING MODEL PREDICTIONS (at observed λ):")
+	fmt.Printf("   %-10s %8s %12s %10s %12s %10s\n", "Model", "ρ", "Wq", "Lq", "W", "L")
+	for _, p := range analysis.Predictions {
+		if !p.Stable {
+			fmt.Printf("   %-10s %8.3f %s\n", p.Model, p.Utilization, "unstable, the queue grows without bound")
+			continue
+		}
+		fmt.Printf("   %-10s %8.3f %12v %10.2f %12v %10.2f\n",
+			p.Model, p.Utilization, p.WaitTime.Round(time.Microsecond), p.QueueLength, p.Response.Round(time.Microsecond), p.InSystem)
+	}
+	fmt.Printf("   %-10s %8s %12s %10s %12v %10.2f\n", "Observed", "", "", "", analysis.ObservedResponse.Round(time.Microsecond), analysis.ObservedInSystem)
+	if analysis.PredictedMedian > 0 {
+		fmt.Printf("   Median response: predicted %v (M/M/1), observed %v\n",
+			analysis.PredictedMedian.Round(time.Microsecond), analysis.ObservedMedian)
+	}
+
+	fmt.Println("\n   Predicted mean response time by load:")
+	fmt.Printf("   %12s %8s %12s %12s\n", "λ (req/s)", "ρ (c=1)", "M/M/1", "M/M/2")
+	for i, point := range analysis.Sweep {
+		fmt.Printf("   %12.2f %8.2f %12v %12v\n", point.ArrivalRate, SweepUtilizations[i],
+			point.MM1Response.Round(time.Microsecond), point.MM2Response.Round(time.Microsecond))
+	}
+
+	switch {
+	case analysis.ResponseDeviation == 0:
+	case analysis.ResponseDeviation > 1.5:
+		fmt.Printf("\n   ⚠️  Observed response time is %.1fx the model: more queueing than %d exponential server(s) explains\n",
+			analysis.ResponseDeviation, Servers)
+	case analysis.ResponseDeviation < 0.67:
+		fmt.Printf("\n   ℹ️  Observed response time is %.1fx the model: the system serves in parallel or faster than estimated\n",
+			analysis.ResponseDeviation)
+	default:
+		fmt.Printf("\n   ✅ Observed response time is %.2fx the model: the system behaves as modeled\n", analysis.ResponseDeviation)
+	}
+}
@@ -0,0 +1,84 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestPredictMM1(t *testing.T) {
+	p := PredictMM1(8, 10)
+
+	assert.True(t, p.Stable)
+	assert.InDelta(t, 0.8, p.Utilization, 1e-9)
+	assert.InDelta(t, 3.2, p.QueueLength, 1e-9)
+	assert.InDelta(t, 4.0, p.InSystem, 1e-9)
+	assert.InDelta(t, 0.4, p.WaitTime.Seconds(), 1e-6)
+	assert.InDelta(t, 0.5, p.Response.Seconds(), 1e-6)
+}
+
+func TestPredictMMc(t *testing.T) {
+	// Two servers at ρ = 0.5: Erlang C = 1/3
+	p := PredictMMc(1, 1, 2)
+
+	assert.True(t, p.Stable)
+	assert.InDelta(t, 0.5, p.Utilization, 1e-9)
+	assert.InDelta(t, 1.0/3, p.QueueLength, 1e-9)
+	assert.InDelta(t, 4.0/3, p.Response.Seconds(), 1e-6)
+
+	single, mm1 := PredictMMc(8, 10, 1), PredictMM1(8, 10)
+	assert.InDelta(t, mm1.QueueLength, single.QueueLength, 1e-9)
+	assert.Equal(t, mm1.Response, single.Response)
+}
+
+func TestPredictUnstable(t *testing.T) {
+	p := PredictMM1(12, 10)
+	assert.False(t, p.Stable)
+	assert.InDelta(t, 1.2, p.Utilization, 1e-9)
+	assert.Zero(t, p.Response)
+
+	assert.True(t, PredictMMc(12, 10, 2).Stable)
+	assert.Zero(t, MM1MedianResponse(12, 10))
+}
+
+func TestEstimateServiceTime(t *testing.T) {
+	hdr := NewHDRHistogram(3)
+	for i := 1; i <= 100; i++ {
+		hdr.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	// Sparse intervals are ignored in favour of the lowest trusted median
+	snapshots := []IntervalSnapshot{
+		{Completed: 50, P50: 20 * time.Millisecond},
+		{Completed: 3, P50: time.Millisecond},
+		{Completed: 40, P50: 8 * time.Millisecond},
+	}
+	assert.Equal(t, 8*time.Millisecond, EstimateServiceTime(snapshots, hdr))
+
+	// Without a trusted interval it falls back to the 5th percentile
+	assert.InDelta(t, float64(5*time.Millisecond), float64(EstimateServiceTime(snapshots[1:2], hdr)), float64(100*time.Microsecond))
+}
+
+func TestServiceRateComesFromLatency(t *testing.T) {
+	saved := Servers
+	defer func() { Servers = saved }()
+
+	qm := NewQueueMetrics()
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		qm.Add(&vegeta.Result{Code: 200, Latency: 10 * time.Millisecond, Timestamp: start.Add(time.Duration(i) * 10 * time.Millisecond)})
+	}
+	qm.Close()
+
+	// 10ms per request is 100 req/s however many requests were sent
+	assert.InDelta(t, 100, qm.GetServiceRate(), 1)
+	Servers = 2
+	assert.InDelta(t, qm.Rate/200, qm.GetTrafficIntensity(), 0.01)
+
+	analysis := qm.AnalyzeModel()
+	assert.Equal(t, 2, analysis.Servers)
+	assert.Len(t, analysis.Predictions, 2)
+	assert.Len(t, analysis.Sweep, len(SweepUtilizations))
+}
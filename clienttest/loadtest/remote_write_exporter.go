@@ -0,0 +1,118 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"com.ndnhuy.mybank/remotewrite"
+)
+
+// RemoteWriteURL is the Prometheus remote-write endpoint client metrics are streamed to,
+// e.g. http://localhost:9090/api/v1/write. Empty disables streaming.
+var RemoteWriteURL = ""
+
+// RemoteWriteExporter streams per-interval client metrics to Prometheus while an attack runs.
+// Pushes happen on a background goroutine so a slow receiver never delays the attack.
+type RemoteWriteExporter struct {
+	client *remotewrite.Client
+	labels map[string]string
+
+	batches chan []remotewrite.TimeSeries
+	done    chan struct{}
+
+	dropped int // Batches that did not fit in the queue
+
+	// Owned by the push goroutine until done is closed
+	pushed, failed int
+}
+
+// NewRemoteWriteExporter creates an exporter labelling every series with the run ID and scenario
+func NewRemoteWriteExporter(url, runID, scenario string) *RemoteWriteExporter {
+	e := &RemoteWriteExporter{
+		client: remotewrite.NewClient(url),
+		labels: map[string]string{
+			"job":      "mybank-loadtest",
+			"run_id":   runID,
+			"scenario": scenario,
+		},
+		batches: make(chan []remotewrite.TimeSeries, 64),
+		done:    make(chan struct{}),
+	}
+	go e.push()
+	return e
+}
+
+func (e *RemoteWriteExporter) push() {
+	defer close(e.done)
+	for batch := range e.batches {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := e.client.Write(ctx, batch)
+		cancel()
+		if err != nil {
+			if e.failed == 0 {
+				fmt.Printf("⚠️  Remote-write failed, further failures are only counted: %v\n", err)
+			}
+			e.failed++
+			continue
+		}
+		e.pushed++
+	}
+}
+
+// enqueue hands a batch to the push goroutine, dropping it if the receiver is too far behind
+func (e *RemoteWriteExporter) enqueue(batch []remotewrite.TimeSeries) {
+	select {
+	case e.batches <- batch:
+	default:
+		e.dropped++
+	}
+}
+
+// series creates a series carrying the exporter's labels plus extra ones
+func (e *RemoteWriteExporter) series(name string, extra map[string]string, value float64, ts time.Time) remotewrite.TimeSeries {
+	labels := make(map[string]string, len(e.labels)+len(extra))
+	for k, v := range e.labels {
+		labels[k] = v
+	}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	return remotewrite.NewTimeSeries(name, labels, value, ts)
+}
+
+// PushSnapshot streams one interval of the attack
+func (e *RemoteWriteExporter) PushSnapshot(snap IntervalSnapshot) {
+	ts := snap.End
+	batch := []remotewrite.TimeSeries{
+		e.series("mybank_loadtest_offered_rate", nil, snap.OfferedRate, ts),
+		e.series("mybank_loadtest_throughput", nil, snap.Throughput, ts),
+		e.series("mybank_loadtest_in_flight", nil, float64(snap.InFlight), ts),
+	}
+	// Intervals without responses have no ratio or latency to report
+	if snap.Completed > 0 {
+		batch = append(batch,
+			e.series("mybank_loadtest_success_ratio", nil, snap.SuccessRatio, ts),
+			e.series("mybank_loadtest_latency_seconds", map[string]string{"quantile": "0.5"}, snap.P50.Seconds(), ts),
+			e.series("mybank_loadtest_latency_seconds", map[string]string{"quantile": "0.9"}, snap.P90.Seconds(), ts),
+			e.series("mybank_loadtest_latency_seconds", map[string]string{"quantile": "0.99"}, snap.P99.Seconds(), ts),
+		)
+	}
+	e.enqueue(batch)
+}
+
+// PushVerification streams the balance verification outcome, 1 for passed and 0 for failed
+func (e *RemoteWriteExporter) PushVerification(passed bool) {
+	value := 0.0
+	if passed {
+		value = 1
+	}
+	e.enqueue([]remotewrite.TimeSeries{e.series("mybank_loadtest_verification_passed", nil, value, time.Now())})
+}
+
+// Close waits for pending pushes and returns how many batches were pushed and how many failed
+func (e *RemoteWriteExporter) Close() (pushed, failed int) {
+	close(e.batches)
+	<-e.done
+	return e.pushed, e.failed + e.dropped
+}
@@ -0,0 +1,64 @@
+package loadtest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStreamsSnapshotsToRemoteWrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	defer func(interval time.Duration, metricsURL, remoteWriteURL string) {
+		ReportInterval, ServerMetricsURL, RemoteWriteURL = interval, metricsURL, remoteWriteURL
+	}(ReportInterval, ServerMetricsURL, RemoteWriteURL)
+	ReportInterval = 250 * time.Millisecond
+	ServerMetricsURL = ""
+	RemoteWriteURL = receiver.URL
+
+	qm := NewQueueMetrics()
+	run := newRun("remote-write-test")
+	attacker := NewAttacker(server.URL+"/accounts", "GET", 20, 1, qm)
+	run.Attach(attacker)
+	attacker.Attack()
+	qm.Close()
+	run.RecordVerification(BalanceVerification{Passed: true})
+	run.Finish()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// One batch per interval plus the verification outcome
+	require.Equal(t, len(qm.TimeSeries().Snapshots())+1, len(bodies))
+	for _, body := range bodies {
+		// Snappy literals leave label values readable in the body
+		assert.True(t, bytes.Contains(body, []byte(run.ID)), "every series carries the run ID")
+	}
+	assert.True(t, bytes.Contains(bodies[0], []byte("mybank_loadtest_offered_rate")))
+	assert.True(t, bytes.Contains(bodies[len(bodies)-1], []byte("mybank_loadtest_verification_passed")))
+
+	report := run.Report(qm)
+	assert.Equal(t, run.ID, report.RunID)
+	require.NotNil(t, report.Verified)
+	assert.True(t, *report.Verified)
+}
@@ -0,0 +1,114 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"com.ndnhuy.mybank/balancer"
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/domain/action"
+	"com.ndnhuy.mybank/promscrape"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// QueueReport is the structured form of a QueueMetrics report
+type QueueReport struct {
+	Timestamp         time.Time                     `json:"timestamp"`
+	RunID             string                        `json:"run_id,omitempty"`
+	Scenario          string                        `json:"scenario"`
+	Verified          *bool                         `json:"verified,omitempty"`           // Balance verification outcome, nil when not applicable
+	Balances          *BalanceVerification          `json:"balances,omitempty"`           // Per-customer detail of the verification
+	SessionGuarantees *domain.SessionGuarantees     `json:"session_guarantees,omitempty"` // Checked on the customers' balance reads
+	Config            *RunConfig                    `json:"config,omitempty"`
+	Environment       *Environment                  `json:"environment,omitempty"`
+	ArrivalRate       float64                       `json:"arrival_rate"`
+	ServiceRate       float64                       `json:"service_rate"`
+	TrafficIntensity  float64                       `json:"traffic_intensity"`
+	Overall           *vegeta.Metrics               `json:"overall"` // Includes the latency histogram as "buckets"
+	Percentiles       []PercentileEntry             `json:"percentiles"`
+	Latencies         []HistogramBin                `json:"latencies"` // Every latency at HDR resolution, for comparing runs
+	RequestedRate     float64                       `json:"requested_rate,omitempty"`
+	Corrected         []PercentileEntry             `json:"corrected_percentiles,omitempty"` // Corrected for coordinated omission
+	SchedulingLag     []PercentileEntry             `json:"scheduling_lag,omitempty"`
+	ClientSaturated   bool                          `json:"client_saturated"`
+	LittlesLaw        LittlesLawCheck               `json:"littles_law"`
+	Endpoints         []BreakdownEntry              `json:"endpoints"`
+	StatusClasses     []BreakdownEntry              `json:"status_classes"`
+	Instances         []BreakdownEntry              `json:"instances,omitempty"`    // Per app instance when spread by a Balancer
+	Availability      *balancer.Availability        `json:"availability,omitempty"` // Outages and retries of the instances
+	Model             ModelAnalysis                 `json:"model"`
+	TimeSeries        []IntervalSnapshot            `json:"time_series"`
+	Server            *promscrape.ServerQueueReport `json:"server,omitempty"`        // Scraped from the server's Prometheus endpoint
+	Breakdowns        map[string][]BreakdownEntry   `json:"breakdowns,omitempty"`    // Scenario specific, e.g. per topology
+	Journeys          *action.Stats                 `json:"journeys,omitempty"`      // Outcome of every action, journey scenario only
+	Staleness         *StalenessReport              `json:"staleness,omitempty"`     // Staleness probe only
+	Shards            *ShardImbalance               `json:"shards,omitempty"`        // Shard scenario only
+	Settlement        *SettlementReport             `json:"settlement,omitempty"`    // Settlement verification only
+	ResponseSize      *ResponseSizeGrowth           `json:"response_size,omitempty"` // Account listing only
+	Warmup            *WarmupReport                 `json:"warmup,omitempty"`        // Excluded from every other figure
+	Interrupted       *Interruption                 `json:"interrupted,omitempty"`   // Set when Ctrl+C cut the run short
+}
+
+// Report returns the structured report of a closed QueueMetrics
+func (qm *QueueMetrics) Report(scenario string) QueueReport {
+	report := QueueReport{
+		Timestamp:        time.Now(),
+		Scenario:         scenario,
+		ArrivalRate:      qm.GetArrivalRate(),
+		ServiceRate:      qm.GetServiceRate(),
+		TrafficIntensity: qm.GetTrafficIntensity(),
+		Overall:          qm.Metrics,
+		Percentiles:      qm.hdr.Percentiles(),
+		Latencies:        qm.hdr.Bins(),
+		RequestedRate:    qm.RequestedRate(),
+		ClientSaturated:  qm.ClientSaturated(),
+		LittlesLaw:       qm.LittlesLaw(),
+		Endpoints:        qm.endpoints.Entries(),
+		StatusClasses:    qm.statusClasses.Entries(),
+		Model:            qm.AnalyzeModel(),
+		TimeSeries:       qm.series.Snapshots(),
+		Server:           qm.server,
+		Warmup:           qm.warmup,
+	}
+	if qm.instances != nil {
+		report.Instances = qm.instances.Entries()
+		report.Availability = qm.availability
+	}
+	if qm.corrected.Total() > 0 {
+		report.Corrected = qm.corrected.Percentiles()
+		report.SchedulingLag = qm.lag.Percentiles()
+	}
+	return report
+}
+
+// saveStructuredReport appends the report as a single JSON line to <baseName>.jsonl,
+// so the file keeps one line per run, writes the run's time series to
+// <baseName>_timeseries.csv for plotting and the HTML report to <baseName>.html,
+// and records the run in HistoryDir
+func saveStructuredReport(baseName string, report QueueReport) {
+	appendJSONReport(baseName+".jsonl", report)
+	saveTimeSeriesCSV(baseName+"_timeseries.csv", report.TimeSeries)
+	saveHTMLReport(baseName+".html", report)
+	recordHistory(report)
+}
+
+// appendJSONReport appends the report as a single JSON line
+func appendJSONReport(path string, report QueueReport) {
+	line, err := json.Marshal(report)
+	if err != nil {
+		fmt.Printf("Failed to encode structured report: %v\n", err)
+		return
+	}
+
+	reportFile, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open structured report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	reportFile.Write(append(line, '\n'))
+	fmt.Printf("Structured report appended to %s\n", path)
+}
@@ -0,0 +1,87 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+
+	"com.ndnhuy.mybank/mybankclient"
+)
+
+// listPageSize is how many accounts countAccounts asks for per page
+const listPageSize = 500
+
+// ResponseSizeGrowth is how the size of the responses changed over an attack, e.g.
+// GET /accounts growing with every account created meanwhile or left behind by
+// earlier runs
+type ResponseSizeGrowth struct {
+	First          float64 `json:"first"` // Mean bytes per response of the first interval with responses
+	Last           float64 `json:"last"`  // Of the last interval with responses
+	Min            float64 `json:"min"`
+	Max            float64 `json:"max"`
+	Growth         float64 `json:"growth"`           // Last relative to First, 0.1 for 10% larger
+	BytesPerSecond float64 `json:"bytes_per_second"` // Fitted trend of the mean size
+	AccountsBefore int     `json:"accounts_before"`  // Listed before the attack, -1 when the listing failed
+	AccountsAfter  int     `json:"accounts_after"`   // Listed after the attack, -1 when the listing failed
+}
+
+// NewResponseSizeGrowth fits the mean response size of the intervals, nil when none
+// received a response
+func NewResponseSizeGrowth(snapshots []IntervalSnapshot) *ResponseSizeGrowth {
+	var elapsed, sizes []float64
+	for _, snap := range snapshots {
+		if snap.Completed > 0 {
+			elapsed = append(elapsed, snap.Elapsed.Seconds())
+			sizes = append(sizes, snap.MeanBytesIn)
+		}
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	growth := &ResponseSizeGrowth{
+		First:          sizes[0],
+		Last:           sizes[len(sizes)-1],
+		Min:            sizes[0],
+		Max:            sizes[0],
+		BytesPerSecond: slopeOf(elapsed, sizes),
+	}
+	for _, size := range sizes {
+		growth.Min = min(growth.Min, size)
+		growth.Max = max(growth.Max, size)
+	}
+	if growth.First > 0 {
+		growth.Growth = growth.Last/growth.First - 1
+	}
+	return growth
+}
+
+// countAccounts streams the accounts of the bank page by page and counts them, -1
+// when the listing fails
+func countAccounts() int {
+	count := 0
+	err := bankClient().StreamAccounts(context.Background(), listPageSize, func(mybankclient.Account) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Failed to count accounts: %v\n", err)
+		return -1
+	}
+	return count
+}
+
+// printResponseSizeGrowth prints how the response size changed over the attack
+func printResponseSizeGrowth(growth *ResponseSizeGrowth) {
+	fmt.Println("\n📦 RESPONSE SIZE:")
+	if growth == nil {
+		fmt.Println("   No responses received")
+		return
+	}
+	fmt.Printf("   First Interval:        %.0f bytes per response\n", growth.First)
+	fmt.Printf("   Last Interval:         %.0f bytes per response\n", growth.Last)
+	fmt.Printf("   Range:                 %.0f - %.0f bytes\n", growth.Min, growth.Max)
+	fmt.Printf("   Growth:                %+.1f%% (%+.1f bytes/s)\n", growth.Growth*100, growth.BytesPerSecond)
+	if growth.AccountsBefore >= 0 && growth.AccountsAfter >= 0 {
+		fmt.Printf("   Accounts Listed:       %d before, %d after\n", growth.AccountsBefore, growth.AccountsAfter)
+	}
+}
@@ -0,0 +1,28 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseSizeGrowth(t *testing.T) {
+	assert.Nil(t, NewResponseSizeGrowth(nil))
+
+	snapshots := []IntervalSnapshot{
+		{Elapsed: time.Second, Completed: 10, MeanBytesIn: 1000},
+		{Elapsed: 2 * time.Second}, // No responses, skipped
+		{Elapsed: 3 * time.Second, Completed: 10, MeanBytesIn: 1100},
+		{Elapsed: 4 * time.Second, Completed: 10, MeanBytesIn: 1150},
+	}
+	growth := NewResponseSizeGrowth(snapshots)
+	require.NotNil(t, growth)
+	assert.Equal(t, 1000.0, growth.First)
+	assert.Equal(t, 1150.0, growth.Last)
+	assert.Equal(t, 1000.0, growth.Min)
+	assert.Equal(t, 1150.0, growth.Max)
+	assert.InDelta(t, 0.15, growth.Growth, 1e-9)
+	assert.InDelta(t, 50.0, growth.BytesPerSecond, 1e-9, "fitted against the elapsed time")
+}
@@ -0,0 +1,123 @@
+package loadtest
+
+import (
+	"fmt"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/utils"
+)
+
+// Run identifies one execution of a scenario and owns the outputs that follow
+// the attack while it progresses
+type Run struct {
+	ID       string
+	Scenario string
+	Started  time.Time
+
+	config       RunConfig
+	verification *BalanceVerification // nil when the scenario has nothing to verify
+	guarantees   *domain.SessionGuarantees
+	exporter     *RemoteWriteExporter
+}
+
+// RunConfig is the configuration a run was started with
+type RunConfig struct {
+	RPS            int               `json:"rps"`
+	Duration       time.Duration     `json:"duration"`
+	Interval       time.Duration     `json:"interval"`
+	Servers        int               `json:"servers"`
+	TargetURL      string            `json:"target_url"`
+	Targets        []string          `json:"targets,omitempty"` // App instances the Balancer spread requests over
+	BalancePolicy  string            `json:"balance_policy,omitempty"`
+	LatencyBuckets string            `json:"latency_buckets"`
+	Params         map[string]string `json:"params,omitempty"` // Scenario specific, e.g. the workload mix
+}
+
+// newRun starts a run of the scenario, streaming to RemoteWriteURL when set
+func newRun(scenario string) *Run {
+	started := time.Now()
+	run := &Run{
+		ID:       fmt.Sprintf("%s-%s", scenario, started.Format("20060102-150405")),
+		Scenario: scenario,
+		Started:  started,
+		config: RunConfig{
+			Interval:       ReportInterval,
+			Servers:        Servers,
+			TargetURL:      utils.BASE_URL,
+			LatencyBuckets: fmt.Sprint(LatencyBuckets),
+		},
+	}
+	if Balancer != nil {
+		run.config.Targets = Balancer.Targets()
+		run.config.BalancePolicy = string(Balancer.Policy())
+	}
+	if AccountDataset != nil {
+		run.SetParam("dataset", AccountDataset.Path)
+	}
+	if RemoteWriteURL != "" {
+		run.exporter = NewRemoteWriteExporter(RemoteWriteURL, run.ID, scenario)
+		fmt.Printf("Streaming metrics to %s (run_id=%s)\n", RemoteWriteURL, run.ID)
+	}
+	return run
+}
+
+// Attach hooks the run's outputs to the attacker and records its rate and duration
+func (r *Run) Attach(attacker *Attacker) {
+	r.config.RPS = attacker.rate.Freq
+	r.config.Duration = attacker.duration
+	if r.exporter != nil {
+		attacker.OnSnapshot(r.exporter.PushSnapshot)
+	}
+}
+
+// SetParam records a scenario specific parameter of the run's configuration
+func (r *Run) SetParam(name, value string) {
+	if r.config.Params == nil {
+		r.config.Params = map[string]string{}
+	}
+	r.config.Params[name] = value
+}
+
+// RecordVerification records the outcome of the balance verification
+func (r *Run) RecordVerification(verification BalanceVerification) {
+	r.verification = &verification
+	if r.exporter != nil {
+		r.exporter.PushVerification(verification.Passed)
+	}
+}
+
+// RecordSessionGuarantees records the session guarantee counts of the customers' reads
+// and prints them
+func (r *Run) RecordSessionGuarantees(customers []*domain.Customer) {
+	guarantees := sessionGuarantees(customers)
+	r.guarantees = &guarantees
+	printSessionGuarantees(guarantees)
+}
+
+// Finish flushes the run's outputs
+func (r *Run) Finish() {
+	if r.exporter != nil {
+		pushed, failed := r.exporter.Close()
+		fmt.Printf("Pushed %d metric batches to %s (%d failed)\n", pushed, RemoteWriteURL, failed)
+	}
+}
+
+// Report returns the structured report of the run
+func (r *Run) Report(qm *QueueMetrics) QueueReport {
+	report := qm.Report(r.Scenario)
+	report.RunID = r.ID
+	if r.verification != nil {
+		report.Verified = &r.verification.Passed
+		report.Balances = r.verification
+	}
+	report.SessionGuarantees = r.guarantees
+	if interruption := qm.interruption(); interruption != nil {
+		interruption.Planned = r.config.Duration
+		report.Interrupted = interruption
+	}
+	config := r.config
+	report.Config = &config
+	report.Environment = CurrentEnvironment()
+	return report
+}
@@ -0,0 +1,182 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/mybankclient"
+)
+
+// Defaults of the seed command
+const (
+	DefaultDatasetPath     = "dataset.csv"
+	DefaultSeedConcurrency = 16
+)
+
+// maxSeedFailures is how many account creations may fail before seeding stops
+const maxSeedFailures = 100
+
+// SeedConfig configures a seeding
+type SeedConfig struct {
+	Accounts    int
+	Concurrency int // Accounts created at the same time
+	Balances    BalanceDistribution
+	Progress    time.Duration // How often progress is printed, 0 for never
+}
+
+// SeedResult summarizes a seeding
+type SeedResult struct {
+	Created      int
+	Failed       int
+	TotalBalance float64
+	Elapsed      time.Duration
+}
+
+// Seed creates cfg.Accounts accounts, cfg.Concurrency at a time, and writes every
+// account to the dataset as soon as it exists, so an interrupted seeding still leaves
+// a usable dataset. It stops when ctx is done or after maxSeedFailures failures.
+func Seed(ctx context.Context, client *mybankclient.Client, cfg SeedConfig, dataset *DatasetWriter) (SeedResult, error) {
+	seedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// One generator draws every balance, so the distribution doesn't depend on the
+	// number of workers
+	balances := make(chan float64)
+	go func() {
+		defer close(balances)
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for i := 0; i < cfg.Accounts; i++ {
+			select {
+			case balances <- cfg.Balances.Sample(rng):
+			case <-seedCtx.Done():
+				return
+			}
+		}
+	}()
+
+	type seeded struct {
+		account DatasetAccount
+		err     error
+	}
+	results := make(chan seeded, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < max(cfg.Concurrency, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for balance := range balances {
+				account, err := client.CreateAccount(seedCtx, balance)
+				if err != nil {
+					results <- seeded{err: err}
+					continue
+				}
+				results <- seeded{account: DatasetAccount{ID: account.ID, Balance: balance}}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var ticks <-chan time.Time
+	if cfg.Progress > 0 {
+		ticker := time.NewTicker(cfg.Progress)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	started := time.Now()
+	var result SeedResult
+	var lastErr, writeErr error
+	for done := false; !done; {
+		select {
+		case r, ok := <-results:
+			switch {
+			case !ok:
+				done = true
+			case r.err != nil:
+				if seedCtx.Err() != nil {
+					continue // Cut short by the cancellation, not a failure of the bank
+				}
+				result.Failed++
+				lastErr = r.err
+				if result.Failed == maxSeedFailures {
+					cancel()
+				}
+			default:
+				result.Created++
+				result.TotalBalance += r.account.Balance
+				if err := dataset.Write(r.account); err != nil && writeErr == nil {
+					writeErr = err
+					cancel()
+				}
+			}
+		case <-ticks:
+			if err := dataset.Flush(); err != nil && writeErr == nil {
+				writeErr = err
+				cancel()
+			}
+			result.Elapsed = time.Since(started)
+			printSeedProgress(result, cfg.Accounts)
+		}
+	}
+	result.Elapsed = time.Since(started)
+
+	if err := dataset.Flush(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	switch {
+	case writeErr != nil:
+		return result, fmt.Errorf("failed to write dataset: %w", writeErr)
+	case result.Failed >= maxSeedFailures:
+		return result, fmt.Errorf("stopped after %d failed account creations, the last: %w", result.Failed, lastErr)
+	case ctx.Err() != nil:
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// SeedDataset seeds accounts into a new dataset file at path, printing the progress
+func SeedDataset(ctx context.Context, client *mybankclient.Client, path string, cfg SeedConfig) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create dataset: %w", err)
+	}
+	defer file.Close()
+	dataset, err := NewDatasetWriter(file)
+	if err != nil {
+		return fmt.Errorf("failed to write dataset: %w", err)
+	}
+
+	fmt.Printf("Seeding %d accounts into %s, %d at a time\n", cfg.Accounts, client.BaseURL(), cfg.Concurrency)
+	fmt.Printf("Balances: %s\n", cfg.Balances)
+	fmt.Printf("Press Ctrl+C to stop, the accounts created so far are kept\n\n")
+
+	result, err := Seed(ctx, client, cfg, dataset)
+	printSeedProgress(result, cfg.Accounts)
+	if result.Created > 0 {
+		fmt.Printf("Dataset of %d accounts (total balance %.2f) written to %s\n", result.Created, result.TotalBalance, path)
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("Seeding interrupted\n")
+		return nil
+	}
+	return err
+}
+
+// printSeedProgress prints how far a seeding got
+func printSeedProgress(result SeedResult, accounts int) {
+	rate := 0.0
+	if secs := result.Elapsed.Seconds(); secs > 0 {
+		rate = float64(result.Created) / secs
+	}
+	fmt.Printf("   %8s %9d / %d accounts (%5.1f%%) %8.1f/s %6d failed\n",
+		result.Elapsed.Round(100*time.Millisecond), result.Created, accounts,
+		float64(result.Created)/float64(max(accounts, 1))*100, rate, result.Failed)
+}
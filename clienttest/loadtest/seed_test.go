@@ -0,0 +1,207 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/mybankclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedBank opens accounts, failing every creation while failing is set, and tracks how
+// many creations run at the same time. Reads answer 1 less than an account was opened
+// with, as if an earlier run had moved money.
+type seedBank struct {
+	mu           sync.Mutex
+	balances     map[string]float64
+	active, peak int
+	failing      atomic.Bool
+}
+
+func newSeedBank(t *testing.T) (*seedBank, *mybankclient.Client) {
+	bank := &seedBank{balances: map[string]float64{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /accounts", func(w http.ResponseWriter, r *http.Request) {
+		bank.mu.Lock()
+		bank.active++
+		bank.peak = max(bank.peak, bank.active)
+		bank.mu.Unlock()
+		time.Sleep(time.Millisecond)
+		defer func() {
+			bank.mu.Lock()
+			bank.active--
+			bank.mu.Unlock()
+		}()
+		if bank.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var req mybankclient.CreateAccountRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		bank.mu.Lock()
+		defer bank.mu.Unlock()
+		id := fmt.Sprintf("acc-%d", len(bank.balances))
+		bank.balances[id] = req.InitialBalance
+		json.NewEncoder(w).Encode(mybankclient.Account{ID: id, Balance: req.InitialBalance})
+	})
+	mux.HandleFunc("GET /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		bank.mu.Lock()
+		defer bank.mu.Unlock()
+		json.NewEncoder(w).Encode(mybankclient.Account{ID: r.PathValue("id"), Balance: bank.balances[r.PathValue("id")] - 1})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return bank, mybankclient.New(server.URL)
+}
+
+func TestParseBalanceDistribution(t *testing.T) {
+	for input, want := range map[string]string{
+		"fixed:100":           "fixed:100",
+		"uniform:10, 1000":    "uniform:10,1000",
+		"lognormal:500,1.5":   "lognormal:500,1.5",
+		" uniform:0.5,0.5  ":  "uniform:0.5,0.5",
+		"fixed:100,200":       "",
+		"uniform:1000,10":     "",
+		"uniform:0,10":        "",
+		"pareto:1,2":          "",
+		"fixed:-1":            "",
+		"fixed":               "",
+		"lognormal:500,sigma": "",
+	} {
+		dist, err := ParseBalanceDistribution(input)
+		if want == "" {
+			assert.Error(t, err, input)
+			continue
+		}
+		require.NoError(t, err, input)
+		assert.Equal(t, want, dist.String())
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	uniform := BalanceDistribution{Kind: UniformBalance, A: 10, B: 20}
+	lognormal := BalanceDistribution{Kind: LognormalBalance, A: 500, B: 1}
+	var samples []float64
+	for i := 0; i < 1000; i++ {
+		balance := uniform.Sample(rng)
+		assert.True(t, balance >= 10 && balance <= 20, balance)
+		assert.Equal(t, balance, float64(int(balance*100+0.5))/100, "rounded to cents")
+		samples = append(samples, lognormal.Sample(rng))
+	}
+	sort.Float64s(samples)
+	assert.InDelta(t, 500, samples[500], 100, "median")
+	assert.Greater(t, samples[990], 5000.0, "long tail")
+	assert.Equal(t, 0.01, BalanceDistribution{Kind: FixedBalance, A: 0.001}.Sample(rng), "never below a cent")
+}
+
+func TestSeed(t *testing.T) {
+	bank, client := newSeedBank(t)
+	var buf bytes.Buffer
+	dataset, err := NewDatasetWriter(&buf)
+	require.NoError(t, err)
+
+	cfg := SeedConfig{Accounts: 200, Concurrency: 4, Balances: BalanceDistribution{Kind: UniformBalance, A: 100, B: 200}}
+	result, err := Seed(context.Background(), client, cfg, dataset)
+	require.NoError(t, err)
+	assert.Equal(t, 200, result.Created)
+	assert.Zero(t, result.Failed)
+	assert.LessOrEqual(t, bank.peak, 4, "bounded parallelism")
+	assert.Greater(t, bank.peak, 1, "concurrent")
+
+	accounts, err := ReadDataset(&buf)
+	require.NoError(t, err)
+	require.Len(t, accounts, 200)
+	total := 0.0
+	for _, account := range accounts {
+		assert.Equal(t, bank.balances[account.ID], account.Balance)
+		total += account.Balance
+	}
+	assert.InDelta(t, result.TotalBalance, total, 1e-6)
+}
+
+func TestSeedStops(t *testing.T) {
+	bank, client := newSeedBank(t)
+	bank.failing.Store(true)
+	dataset, err := NewDatasetWriter(&bytes.Buffer{})
+	require.NoError(t, err)
+	cfg := SeedConfig{Accounts: 1000, Concurrency: 8, Balances: DefaultBalanceDistribution}
+
+	result, err := Seed(context.Background(), client, cfg, dataset)
+	assert.ErrorContains(t, err, "stopped after 100 failed account creations")
+	assert.ErrorIs(t, err, mybankclient.ErrServer)
+	assert.Equal(t, maxSeedFailures, result.Failed, "in-flight creations cut short don't count")
+
+	bank.failing.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	cfg.Accounts = 1_000_000
+	result, err = Seed(ctx, client, cfg, dataset)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Positive(t, result.Created)
+	assert.Zero(t, result.Failed)
+}
+
+func TestDatasetCustomers(t *testing.T) {
+	bank, client := newSeedBank(t)
+	var file strings.Builder
+	dataset, err := NewDatasetWriter(&file)
+	require.NoError(t, err)
+	for i, balance := range []float64{100, 50, 500, 5, 1000} {
+		id := fmt.Sprintf("acc-%d", i)
+		bank.balances[id] = balance
+		require.NoError(t, dataset.Write(DatasetAccount{ID: id, Balance: balance}))
+	}
+	require.NoError(t, dataset.Flush())
+	accounts, err := ReadDataset(strings.NewReader(file.String()))
+	require.NoError(t, err)
+	ds := newDataset("dataset.csv", accounts)
+
+	rich, err := ds.Customers(context.Background(), client, "source", 2, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "acc-2", rich[0].GetAccountID(), "acc-0 holds 99 now")
+	assert.Equal(t, "acc-4", rich[1].GetAccountID())
+	assert.Equal(t, "source-1", rich[1].GetName())
+	assert.Equal(t, 999.0, rich[1].ExpectedBalance(), "the balance the account holds now")
+
+	rest, err := ds.Customers(context.Background(), client, "dest", 2, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"acc-1", "acc-3"}, []string{rest[0].GetAccountID(), rest[1].GetAccountID()}, "skipped accounts stay available")
+	assert.Equal(t, 53.0, totalExpectedBalance(rest))
+
+	_, err = ds.Customers(context.Background(), client, "more", 1, 1)
+	assert.ErrorContains(t, err, "ran out of accounts")
+
+	_, err = ReadDataset(strings.NewReader("id,balance\nacc-1,ten\n"))
+	assert.ErrorContains(t, err, "invalid account on line 2")
+}
+
+func TestDefaultSeedServesEveryScenario(t *testing.T) {
+	_, client := newSeedBank(t)
+	var file bytes.Buffer
+	dataset, err := NewDatasetWriter(&file)
+	require.NoError(t, err)
+	_, err = Seed(context.Background(), client, SeedConfig{Accounts: 200, Concurrency: 8, Balances: DefaultBalanceDistribution}, dataset)
+	require.NoError(t, err)
+	accounts, err := ReadDataset(&file)
+	require.NoError(t, err)
+	ds := newDataset("dataset.csv", accounts)
+
+	// The most settlement ever asks for, then staleness
+	_, err = ds.Customers(context.Background(), client, "shard", 4*DefaultShards*accountsPerShard, pairInitialBalance)
+	require.NoError(t, err)
+	_, err = ds.Customers(context.Background(), client, "staleness", 20, pairInitialBalance)
+	require.NoError(t, err)
+	_, err = ds.Customers(context.Background(), client, "source", 10, 100)
+	require.NoError(t, err)
+}
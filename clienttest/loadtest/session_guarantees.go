@@ -0,0 +1,34 @@
+package loadtest
+
+import (
+	"fmt"
+
+	"com.ndnhuy.mybank/domain"
+)
+
+// sessionGuarantees sums up the session guarantee counts of every customer
+func sessionGuarantees(customers []*domain.Customer) domain.SessionGuarantees {
+	var total domain.SessionGuarantees
+	for _, customer := range customers {
+		total.Merge(customer.SessionGuarantees())
+	}
+	return total
+}
+
+// printSessionGuarantees prints the read-your-writes and monotonic-read violations
+// of the customers' balance reads
+func printSessionGuarantees(g domain.SessionGuarantees) {
+	fmt.Println("\n🔁 SESSION GUARANTEES:")
+	fmt.Printf("   Balance Reads:         %d\n", g.Reads)
+	fmt.Printf("   Read-Your-Writes:      %d violations\n", g.ReadYourWritesViolations)
+	fmt.Printf("   Monotonic Reads:       %d violations\n", g.MonotonicReadViolations)
+	if g.ReadYourWritesViolations > 0 {
+		fmt.Printf("   Staleness:             mean %v, max %v\n", g.MeanStaleness(), g.MaxStaleness)
+	}
+	if g.UnknownReads > 0 {
+		fmt.Printf("   ⚠️  %d reads matched no balance the client recorded\n", g.UnknownReads)
+	}
+	if g.Violations() > 0 {
+		fmt.Printf("   ❌ Reads served stale balances, a replica or cache lags behind the writes\n")
+	}
+}
@@ -0,0 +1,115 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/shard"
+	"com.ndnhuy.mybank/utils"
+)
+
+// AttackSettlement transfers between pairs of accounts on different shards of
+// ShardMapper and follows every transfer until it settles, flagging transfers stuck
+// pending, debits without credits and failed compensations
+func AttackSettlement(testDuration int) {
+	const numPairs = 10
+
+	mapper := ShardMapper
+	if mapper == nil {
+		ring, err := shard.NewRing(DefaultShards, shard.DefaultVirtualNodes)
+		if err != nil {
+			fmt.Printf("Failed to create shard ring: %v\n", err)
+			return
+		}
+		mapper = ring
+	}
+
+	fmt.Printf("Starting settlement verification: %d cross-shard account pairs for %d seconds\n", numPairs, testDuration)
+	fmt.Printf("Polling balances every %v\n", SettlementPollInterval)
+	fmt.Printf("Setting up test customers...\n")
+
+	customers, err := createShardCustomers(mapper, pairInitialBalance)
+	if err != nil {
+		fmt.Printf("Failed to setup customers: %v\n", err)
+		return
+	}
+	defer cleanupTransferCustomers(customers)
+
+	pairs := crossShardPairs(groupByShard(customers, mapper), numPairs)
+	if len(pairs) == 0 {
+		fmt.Printf("Failed to setup customers: no accounts on two different shards\n")
+		return
+	}
+
+	fmt.Printf("Created %d customers, %d cross-shard pairs\n", len(customers), len(pairs))
+	fmt.Printf("Target: %s\n", utils.BASE_URL)
+	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
+
+	verifier := NewSettlementVerifier(SettlementPollInterval, settlementTimeout)
+	pr := newPairRun("settlement", customers, testDuration)
+	pr.run.SetParam("poll_interval", SettlementPollInterval.String())
+	pr.run.SetParam("pairs", fmt.Sprint(len(pairs)))
+	pr.run.SetParam("shards", fmt.Sprint(mapper.Shards()))
+	fmt.Printf("Settlement verification in progress...\n")
+
+	pr.drive(pairs, func(from, to *domain.Customer, results chan<- labeledResult) {
+		settleTransfer(verifier, from, to, results)
+	})
+	settlement := verifier.Report()
+	printSettlementReport(settlement)
+
+	pr.saveReports("settlement_attack_report", "Settlement Verification", func(w io.Writer) {
+		fmt.Fprintf(w, "Transfers: %d, Anomalies: %d\n", settlement.Transfers, settlement.Anomalies)
+		for _, count := range settlement.States {
+			fmt.Fprintf(w, "%s: %d\n", count.State, count.Count)
+		}
+	}, func(report *QueueReport) {
+		report.Settlement = &settlement
+	})
+}
+
+// crossShardPairs pairs up to n accounts of different shards, each account in one pair
+func crossShardPairs(byShard [][]*domain.Customer, n int) [][2]*domain.Customer {
+	remaining := make([][]*domain.Customer, len(byShard))
+	copy(remaining, byShard)
+
+	var pairs [][2]*domain.Customer
+	for len(pairs) < n {
+		// The two shards with the most accounts left, so small shards don't run out first
+		first, second := -1, -1
+		for s := range remaining {
+			switch {
+			case len(remaining[s]) == 0:
+			case first < 0 || len(remaining[s]) > len(remaining[first]):
+				first, second = s, first
+			case second < 0 || len(remaining[s]) > len(remaining[second]):
+				second = s
+			}
+		}
+		if second < 0 {
+			break
+		}
+		pairs = append(pairs, [2]*domain.Customer{remaining[first][0], remaining[second][0]})
+		remaining[first], remaining[second] = remaining[first][1:], remaining[second][1:]
+	}
+	return pairs
+}
+
+// settleTransfer transfers 1 between the customers and awaits its settlement
+func settleTransfer(verifier *SettlementVerifier, from, to *domain.Customer, results chan<- labeledResult) {
+	started := time.Now()
+	transfer := from.SubmitTransfer(to, 1)
+	results <- labeledResult{label: "transfer", res: operationResult("POST", "/accounts/transfer", started, time.Since(started), transfer.Err)}
+
+	read := func(customer *domain.Customer) (float64, error) {
+		started := time.Now()
+		balance, err := customer.PollBalance()
+		results <- labeledResult{label: "poll", res: operationResult("GET", "/accounts/{id}", started, time.Since(started), err)}
+		return balance, err
+	}
+	if state := verifier.Await(transfer, read); state.Anomaly() {
+		fmt.Printf("⚠️  %s -> %s: %s\n", from.GetName(), to.GetName(), state)
+	}
+}
@@ -0,0 +1,184 @@
+package loadtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+)
+
+// SettlementPollInterval is how often the settlement verifier reads both balances of a
+// pending transfer
+var SettlementPollInterval = 10 * time.Millisecond
+
+const (
+	// settlementTimeout is how long a transfer may stay pending before it is settled as
+	// it stands, an anomaly unless it committed or failed cleanly
+	settlementTimeout = 10 * time.Second
+	// compensationGrace is how long a failed transfer must show no change before it
+	// counts as compensated. The failure may be a lost response of a transfer that
+	// still commits.
+	compensationGrace = 500 * time.Millisecond
+	// maxSettlementRecords bounds the anomalies a report lists
+	maxSettlementRecords = 50
+)
+
+// SettlementRecord is the lifecycle of one transfer from the client's view
+type SettlementRecord struct {
+	From         string                 `json:"from"`
+	To           string                 `json:"to"`
+	Amount       float64                `json:"amount"`
+	Acknowledged bool                   `json:"acknowledged"`
+	Error        string                 `json:"error,omitempty"`
+	State        domain.SettlementState `json:"state"`
+	Submitted    time.Time              `json:"submitted"`
+	TimeToSettle time.Duration          `json:"time_to_settle"`
+	Polls        int                    `json:"polls"`
+}
+
+// SettlementCount is how many transfers settled in a state
+type SettlementCount struct {
+	State domain.SettlementState `json:"state"`
+	Count uint64                 `json:"count"`
+}
+
+// SettlementReport is the outcome of the settlement verifier
+type SettlementReport struct {
+	PollInterval time.Duration      `json:"poll_interval"`
+	Timeout      time.Duration      `json:"timeout"`
+	Transfers    uint64             `json:"transfers"`
+	Anomalies    uint64             `json:"anomalies"`
+	States       []SettlementCount  `json:"states"`
+	TimeToSettle []PercentileEntry  `json:"time_to_settle"`    // Committed transfers only
+	Records      []SettlementRecord `json:"records,omitempty"` // The first anomalies
+}
+
+// SettlementVerifier follows submitted transfers until their outcome is known and
+// flags the ones that settled in an anomalous state
+type SettlementVerifier struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+
+	mu        sync.Mutex // Transfers are awaited concurrently
+	tts       *HDRHistogram
+	transfers uint64
+	states    map[domain.SettlementState]uint64
+	anomalies uint64
+	records   []SettlementRecord
+}
+
+// NewSettlementVerifier creates a verifier polling at the given interval
+func NewSettlementVerifier(pollInterval, timeout time.Duration) *SettlementVerifier {
+	return &SettlementVerifier{
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+		tts:          NewHDRHistogram(3),
+		states:       map[domain.SettlementState]uint64{},
+	}
+}
+
+// Await polls both balances of a pending transfer through read until it committed, it
+// failed without changing them for compensationGrace or the verifier's timeout passed,
+// then settles it. Failed reads count as polls that saw nothing.
+func (v *SettlementVerifier) Await(transfer *domain.PendingTransfer, read func(*domain.Customer) (float64, error)) domain.SettlementState {
+	var debited, credited bool
+	polls := 0
+	for {
+		fromBalance, fromErr := read(transfer.From)
+		toBalance, toErr := read(transfer.To)
+		polls++
+		if fromErr == nil && toErr == nil {
+			debited, credited = transfer.Applied(fromBalance, toBalance)
+		}
+		waited := time.Since(transfer.Submitted)
+		if debited && credited {
+			break
+		}
+		if !transfer.Acknowledged && !debited && !credited && waited > compensationGrace {
+			break
+		}
+		if waited > v.Timeout {
+			break
+		}
+		time.Sleep(v.PollInterval)
+	}
+
+	state := transfer.Settle(debited, credited)
+	record := SettlementRecord{
+		From:         transfer.From.GetName(),
+		To:           transfer.To.GetName(),
+		Amount:       transfer.Amount,
+		Acknowledged: transfer.Acknowledged,
+		State:        state,
+		Submitted:    transfer.Submitted,
+		TimeToSettle: transfer.TimeToSettle(),
+		Polls:        polls,
+	}
+	if transfer.Err != nil {
+		record.Error = transfer.Err.Error()
+	}
+	v.record(record)
+	return state
+}
+
+func (v *SettlementVerifier) record(record SettlementRecord) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.transfers++
+	v.states[record.State]++
+	if record.State == domain.SettlementCommitted {
+		v.tts.Record(record.TimeToSettle)
+	}
+	if record.State.Anomaly() {
+		v.anomalies++
+		if len(v.records) < maxSettlementRecords {
+			v.records = append(v.records, record)
+		}
+	}
+}
+
+// Report returns the verifier's outcome
+func (v *SettlementVerifier) Report() SettlementReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	report := SettlementReport{
+		PollInterval: v.PollInterval,
+		Timeout:      v.Timeout,
+		Transfers:    v.transfers,
+		Anomalies:    v.anomalies,
+		TimeToSettle: v.tts.Percentiles(),
+		Records:      append([]SettlementRecord(nil), v.records...),
+	}
+	for _, state := range domain.SettlementStates {
+		if count := v.states[state]; count > 0 {
+			report.States = append(report.States, SettlementCount{State: state, Count: count})
+		}
+	}
+	return report
+}
+
+// printSettlementReport prints how the transfers settled and the anomalies
+func printSettlementReport(report SettlementReport) {
+	fmt.Println("\n⚖️  SETTLEMENT:")
+	fmt.Printf("   Transfers Followed:    %d (polling every %v, timeout %v)\n", report.Transfers, report.PollInterval, report.Timeout)
+	for _, count := range report.States {
+		fmt.Printf("   %-22s %d\n", string(count.State)+":", count.Count)
+	}
+	fmt.Println("   Time to settle (committed):")
+	for _, entry := range report.TimeToSettle {
+		fmt.Printf("      %-8s %12v\n", percentileName(entry.Quantile), entry.Latency)
+	}
+	if report.Anomalies == 0 {
+		fmt.Println("   ✅ Every transfer committed or failed cleanly")
+		return
+	}
+	fmt.Printf("   ❌ %d transfers settled in an anomalous state:\n", report.Anomalies)
+	for _, record := range report.Records {
+		fmt.Printf("      %s %s -> %s %.2f after %v (%s)\n", record.Submitted.Format("15:04:05.000"),
+			record.From, record.To, record.Amount, record.TimeToSettle.Round(time.Millisecond), record.State)
+	}
+	if uint64(len(report.Records)) < report.Anomalies {
+		fmt.Printf("      ... and %d more\n", report.Anomalies-uint64(len(report.Records)))
+	}
+}
@@ -0,0 +1,125 @@
+package loadtest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sagaBank debits right away and credits after creditDelay, unless creditDelay is
+// negative. When err is set it answers the transfer with it and, if compensate is set,
+// restores the debit.
+type sagaBank struct {
+	mu          sync.Mutex
+	balances    map[string]float64
+	creditDelay time.Duration
+	err         error
+	compensate  bool
+}
+
+type sagaOperator struct {
+	bank *sagaBank
+	id   string
+}
+
+func (b *sagaBank) open(id string, balance float64) *domain.Customer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balances[id] = balance
+	return domain.NewCustomerWithOperator(&sagaOperator{bank: b, id: id}, balance)
+}
+
+func (b *sagaBank) apply(id string, change float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balances[id] += change
+}
+
+func (o *sagaOperator) GetAccount(accountID string) (*domain.AccountInfo, error) {
+	o.bank.mu.Lock()
+	defer o.bank.mu.Unlock()
+	return &domain.AccountInfo{ID: accountID, Balance: o.bank.balances[accountID]}, nil
+}
+
+func (o *sagaOperator) GetAccountBalance() (float64, error) {
+	account, err := o.GetAccount(o.id)
+	return account.Balance, err
+}
+
+func (o *sagaOperator) CreateAccount() (*domain.AccountInfo, error) {
+	return &domain.AccountInfo{ID: o.id}, nil
+}
+
+func (o *sagaOperator) TransferTo(toUser domain.BankOperator, amount float64) error {
+	o.bank.apply(o.id, -amount)
+	if o.bank.err != nil {
+		if o.bank.compensate {
+			o.bank.apply(o.id, amount)
+		}
+		return o.bank.err
+	}
+	if o.bank.creditDelay >= 0 {
+		time.AfterFunc(o.bank.creditDelay, func() { o.bank.apply(toUser.GetAccountId(), amount) })
+	}
+	return nil
+}
+
+func (o *sagaOperator) GetAccountId() string { return o.id }
+func (o *sagaOperator) GetName() string      { return o.id }
+
+func TestSettlementVerifier(t *testing.T) {
+	verifier := NewSettlementVerifier(time.Millisecond, 100*time.Millisecond)
+	read := func(customer *domain.Customer) (float64, error) { return customer.PollBalance() }
+	failed := errors.New("transfer failed with status: 500")
+
+	for _, tc := range []struct {
+		bank *sagaBank
+		want domain.SettlementState
+	}{
+		{&sagaBank{creditDelay: 20 * time.Millisecond}, domain.SettlementCommitted},
+		{&sagaBank{creditDelay: -1}, domain.SettlementDebitWithoutCredit},
+		{&sagaBank{err: failed, compensate: true}, domain.SettlementCompensated},
+		{&sagaBank{err: failed}, domain.SettlementCompensationFailed},
+	} {
+		tc.bank.balances = map[string]float64{}
+		from, to := tc.bank.open("from", 100), tc.bank.open("to", 100)
+		assert.Equal(t, tc.want, verifier.Await(from.SubmitTransfer(to, 1), read))
+		assert.NoError(t, from.VerifyBalance(), "the ledger follows the bank")
+	}
+
+	report := verifier.Report()
+	assert.Equal(t, uint64(4), report.Transfers)
+	assert.Equal(t, uint64(2), report.Anomalies)
+	require.Len(t, report.Records, 2)
+	assert.Equal(t, domain.SettlementDebitWithoutCredit, report.Records[0].State)
+	assert.GreaterOrEqual(t, report.Records[0].TimeToSettle, 100*time.Millisecond, "waited for the timeout")
+	assert.Equal(t, "transfer failed with status: 500", report.Records[1].Error)
+	assert.Len(t, report.States, 4)
+	assert.NotEmpty(t, report.TimeToSettle)
+	assert.GreaterOrEqual(t, report.TimeToSettle[0].Latency, 20*time.Millisecond)
+}
+
+func TestCrossShardPairs(t *testing.T) {
+	byShard := [][]*domain.Customer{
+		shardCustomers("0-a", "0-b", "0-c"),
+		shardCustomers("1-a"),
+		nil,
+		shardCustomers("3-a", "3-b"),
+	}
+	pairs := crossShardPairs(byShard, 10)
+
+	require.Len(t, pairs, 3)
+	seen := map[string]bool{}
+	for _, pair := range pairs {
+		from, to := pair[0].GetAccountID(), pair[1].GetAccountID()
+		assert.NotEqual(t, from[:1], to[:1], "accounts of different shards")
+		assert.False(t, seen[from] || seen[to], "every account in one pair")
+		seen[from], seen[to] = true, true
+	}
+	assert.Len(t, crossShardPairs(byShard, 1), 1)
+}
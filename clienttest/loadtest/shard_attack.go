@@ -0,0 +1,341 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/shard"
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// ShardMapper assigns the accounts of the shard scenario to shards, nil for a ring of
+// DefaultShards shards with shard.DefaultVirtualNodes virtual nodes each
+var ShardMapper shard.Mapper
+
+// DefaultShards is the shard count of the default ShardMapper, the upper end of the
+// roadmap's 2-4 database instances
+const DefaultShards = 4
+
+// Labels a shard transfer is broken down by
+const (
+	SameShard  = "same-shard"
+	CrossShard = "cross-shard"
+)
+
+const (
+	accountsPerShard    = 5   // Accounts created per shard on average
+	minAccountsInShard  = 2   // Needed for transfers within a shard
+	shardTransferAmount = 1.0 // Amount moved by every generated transfer
+)
+
+// ShardLoad is the traffic one shard received
+type ShardLoad struct {
+	Shard     int     `json:"shard"`
+	Accounts  int     `json:"accounts"`
+	Transfers uint64  `json:"transfers"` // Transfers touching the shard, cross-shard ones count on both
+	Failed    uint64  `json:"failed"`
+	Share     float64 `json:"share"` // Of all transfer touches
+}
+
+// ShardImbalance summarizes how the transfers spread over the shards
+type ShardImbalance struct {
+	CrossShardRatio float64     `json:"cross_shard_ratio"` // Requested
+	CrossShardShare float64     `json:"cross_shard_share"` // Generated
+	Shards          []ShardLoad `json:"shards"`
+	MaxToMean       float64     `json:"max_to_mean"` // Transfers of the busiest shard over the mean, 1 is even
+}
+
+// ShardTransferTargeter generates transfers within a shard and across shards in a set
+// ratio and keeps the customers' ledgers in sync with the transfers the server accepted
+type ShardTransferTargeter struct {
+	mapper     shard.Mapper
+	byShard    [][]*domain.Customer
+	crossRatio float64
+	breakdown  *MetricsBreakdown
+	pending    *pendingTransfers
+	loads      []ShardLoad
+	observed   uint64 // Transfers observed
+	cross      uint64 // Cross-shard transfers observed
+
+	mu        sync.Mutex // Guards rng, the targeter is called from many workers
+	rng       *rand.Rand
+	local     []int // Shards that hold enough accounts for a same-shard transfer
+	populated []int // Shards that hold an account
+}
+
+// NewShardTransferTargeter creates a targeter over the customers, crossRatio of whose
+// transfers go across shards
+func NewShardTransferTargeter(customers []*domain.Customer, mapper shard.Mapper, crossRatio float64) (*ShardTransferTargeter, error) {
+	if crossRatio < 0 || crossRatio > 1 {
+		return nil, fmt.Errorf("cross-shard ratio must be between 0 and 1, got %v", crossRatio)
+	}
+	tt := &ShardTransferTargeter{
+		mapper:     mapper,
+		byShard:    groupByShard(customers, mapper),
+		crossRatio: crossRatio,
+		breakdown:  NewMetricsBreakdownWithHistogram(LatencyBuckets),
+		pending:    newPendingTransfers(),
+		loads:      make([]ShardLoad, mapper.Shards()),
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for s, accounts := range tt.byShard {
+		tt.loads[s] = ShardLoad{Shard: s, Accounts: len(accounts)}
+		if len(accounts) >= minAccountsInShard {
+			tt.local = append(tt.local, s)
+		}
+		if len(accounts) > 0 {
+			tt.populated = append(tt.populated, s)
+		}
+	}
+	if crossRatio < 1 && len(tt.local) == 0 {
+		return nil, fmt.Errorf("no shard holds %d accounts for same-shard transfers", minAccountsInShard)
+	}
+	if crossRatio > 0 && len(tt.populated) < 2 {
+		return nil, fmt.Errorf("cross-shard transfers need accounts on 2 shards, got %d", len(tt.populated))
+	}
+	return tt, nil
+}
+
+// groupByShard returns the customers of every shard
+func groupByShard(customers []*domain.Customer, mapper shard.Mapper) [][]*domain.Customer {
+	byShard := make([][]*domain.Customer, mapper.Shards())
+	for _, customer := range customers {
+		s := mapper.Shard(customer.GetAccountID())
+		byShard[s] = append(byShard[s], customer)
+	}
+	return byShard
+}
+
+// Targeter returns the vegeta.Targeter producing the transfer requests
+func (tt *ShardTransferTargeter) Targeter() vegeta.Targeter {
+	return func(t *vegeta.Target) error {
+		*t = tt.generateTarget()
+		return nil
+	}
+}
+
+// generateTarget picks two accounts on one shard or on two different shards
+func (tt *ShardTransferTargeter) generateTarget() vegeta.Target {
+	tt.mu.Lock()
+	transfer := plannedTransfer{amount: shardTransferAmount}
+	if tt.rng.Float64() < tt.crossRatio {
+		picked := tt.rng.Perm(len(tt.populated))
+		from, to := tt.byShard[tt.populated[picked[0]]], tt.byShard[tt.populated[picked[1]]]
+		transfer.label = CrossShard
+		transfer.from, transfer.to = from[tt.rng.Intn(len(from))], to[tt.rng.Intn(len(to))]
+	} else {
+		accounts := tt.byShard[tt.local[tt.rng.Intn(len(tt.local))]]
+		picked := tt.rng.Perm(len(accounts))
+		transfer.label = SameShard
+		transfer.from, transfer.to = accounts[picked[0]], accounts[picked[1]]
+	}
+	tt.mu.Unlock()
+
+	body, _ := json.Marshal(domain.TransferRequest{
+		FromAccountID: transfer.from.GetAccountID(),
+		ToAccountID:   transfer.to.GetAccountID(),
+		Amount:        transfer.amount,
+	})
+
+	return vegeta.Target{
+		Method: "POST",
+		URL:    tagURL(utils.BASE_URL+"/accounts/transfer", tt.pending.add(transfer)),
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body: body,
+	}
+}
+
+// Observe records a result as same-shard or cross-shard and on the shards it touched,
+// unless it belongs to the warm-up, and, if the server accepted the transfer, applies it
+// to the customers' ledgers
+func (tt *ShardTransferTargeter) Observe(res *vegeta.Result) {
+	transfer, ok := tt.pending.settle(res)
+	if !ok || isWarmup(res) {
+		return
+	}
+	tt.breakdown.Add(transfer.label, res)
+	tt.observed++
+
+	shards := []int{tt.mapper.Shard(transfer.from.GetAccountID())}
+	if transfer.label == CrossShard {
+		tt.cross++
+		shards = append(shards, tt.mapper.Shard(transfer.to.GetAccountID()))
+	}
+	for _, s := range shards {
+		tt.loads[s].Transfers++
+		if res.Code != http.StatusOK {
+			tt.loads[s].Failed++
+		}
+	}
+}
+
+// Breakdown returns the metrics split into same-shard and cross-shard transfers
+func (tt *ShardTransferTargeter) Breakdown() *MetricsBreakdown {
+	return tt.breakdown
+}
+
+// Imbalance returns how the observed transfers spread over the shards
+func (tt *ShardTransferTargeter) Imbalance() ShardImbalance {
+	imbalance := ShardImbalance{CrossShardRatio: tt.crossRatio, Shards: append([]ShardLoad(nil), tt.loads...)}
+	var touches, busiest uint64
+	for _, load := range imbalance.Shards {
+		touches += load.Transfers
+		busiest = max(busiest, load.Transfers)
+	}
+	if touches == 0 {
+		return imbalance
+	}
+	for i := range imbalance.Shards {
+		imbalance.Shards[i].Share = float64(imbalance.Shards[i].Transfers) / float64(touches)
+	}
+	imbalance.MaxToMean = float64(busiest) / (float64(touches) / float64(len(imbalance.Shards)))
+	imbalance.CrossShardShare = float64(tt.cross) / float64(tt.observed)
+	return imbalance
+}
+
+// createShardCustomers creates accounts until every shard holds minAccountsInShard of
+// them, giving up after four times the expected number
+func createShardCustomers(mapper shard.Mapper, initialBalance float64) ([]*domain.Customer, error) {
+	target := mapper.Shards() * accountsPerShard
+	customers, err := createCustomers("shard", target, initialBalance)
+	if err != nil {
+		return nil, err
+	}
+	for round := 1; !everyShardHolds(groupByShard(customers, mapper), minAccountsInShard); round++ {
+		if len(customers) >= 4*target {
+			return customers, nil // The targeter uses the shards that got enough accounts
+		}
+		more, err := createCustomers(fmt.Sprintf("shard-%d", round), mapper.Shards(), initialBalance)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, more...)
+	}
+	return customers, nil
+}
+
+func everyShardHolds(byShard [][]*domain.Customer, n int) bool {
+	for _, accounts := range byShard {
+		if len(accounts) < n {
+			return false
+		}
+	}
+	return true
+}
+
+// printShardImbalance prints the traffic of every shard
+func printShardImbalance(imbalance ShardImbalance) {
+	fmt.Println("\n🧩 SHARD IMBALANCE:")
+	fmt.Printf("   Cross-Shard Transfers: %.2f%% (requested %.2f%%)\n", imbalance.CrossShardShare*100, imbalance.CrossShardRatio*100)
+	fmt.Printf("   Busiest / Mean Shard:  %.2f\n", imbalance.MaxToMean)
+	fmt.Printf("   %-8s %9s %10s %8s %8s\n", "Shard", "Accounts", "Transfers", "Failed", "Share")
+	for _, load := range imbalance.Shards {
+		fmt.Printf("   %-8d %9d %10d %8d %7.2f%%\n", load.Shard, load.Accounts, load.Transfers, load.Failed, load.Share*100)
+	}
+}
+
+// AttackShards fires transfers within and across the shards of ShardMapper, crossRatio
+// of them across, to compare both kinds once the bank is sharded
+func AttackShards(rps, testDuration int, crossRatio float64) {
+	const initialBalance = 100.0
+
+	mapper := ShardMapper
+	if mapper == nil {
+		ring, err := shard.NewRing(DefaultShards, shard.DefaultVirtualNodes)
+		if err != nil {
+			fmt.Printf("Failed to create shard ring: %v\n", err)
+			return
+		}
+		mapper = ring
+	}
+
+	fmt.Printf("Starting shard attack: %d RPS for %d seconds\n", rps, testDuration)
+	fmt.Printf("Shards: %d, cross-shard ratio: %.2f\n", mapper.Shards(), crossRatio)
+	fmt.Printf("Setting up test customers...\n")
+
+	customers, err := createShardCustomers(mapper, initialBalance)
+	if err != nil {
+		fmt.Printf("Failed to setup customers: %v\n", err)
+		return
+	}
+	defer cleanupTransferCustomers(customers)
+	initialTotal := totalExpectedBalance(customers)
+
+	shardTargeter, err := NewShardTransferTargeter(customers, mapper, crossRatio)
+	if err != nil {
+		fmt.Printf("Failed to create targeter: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Created %d customers\n", len(customers))
+	fmt.Printf("Total initial balance: %.2f\n", initialTotal)
+	fmt.Printf("Target URL: %s/accounts/transfer\n", utils.BASE_URL)
+	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
+
+	queueMetrics := NewQueueMetrics()
+	run := newRun("shards")
+	run.SetParam("shards", fmt.Sprint(mapper.Shards()))
+	run.SetParam("cross_shard_ratio", fmt.Sprint(crossRatio))
+	fmt.Printf("Shard attack in progress...\n")
+
+	attacker := &Attacker{
+		targeter: shardTargeter.Targeter(),
+		rate:     vegeta.Rate{Freq: rps, Per: time.Second},
+		duration: time.Duration(testDuration) * time.Second,
+		attacker: newVegetaAttacker(),
+		metrics:  queueMetrics,
+	}
+	attacker.OnResult(shardTargeter.Observe)
+
+	run.Attach(attacker)
+	attacker.Attack()
+	queueMetrics.Close()
+	shardTargeter.Breakdown().Close()
+	fmt.Printf("Attack completed!\n\n")
+
+	verification := verifyTotalBalance(customers, initialTotal)
+	run.RecordVerification(verification)
+	run.Finish()
+
+	imbalance := shardTargeter.Imbalance()
+	queueMetrics.PrintReport()
+	shardTargeter.Breakdown().PrintReport("SAME-SHARD VS CROSS-SHARD")
+	printShardImbalance(imbalance)
+
+	fmt.Printf("\n=== Detailed Report ===\n")
+	reportFile, err := os.OpenFile("shard_attack_report.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	timestamp := fmt.Sprintf("==== Shard Attack Run at %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
+	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, queueMetrics)
+	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, verification.FinalTotal))
+
+	for _, label := range shardTargeter.Breakdown().Labels() {
+		reportFile.WriteString(fmt.Sprintf("--- %s (timeouts: %d)\n", label, shardTargeter.Breakdown().Timeouts(label)))
+		vegeta.NewTextReporter(shardTargeter.Breakdown().Get(label))(reportFile)
+	}
+	for _, load := range imbalance.Shards {
+		reportFile.WriteString(fmt.Sprintf("Shard %d: %d accounts, %d transfers, %d failed\n", load.Shard, load.Accounts, load.Transfers, load.Failed))
+	}
+	reportFile.WriteString("\n\n")
+	fmt.Printf("Report appended to shard_attack_report.txt\n")
+
+	report := run.Report(queueMetrics)
+	report.Breakdowns = map[string][]BreakdownEntry{"shard_locality": shardTargeter.Breakdown().Entries()}
+	report.Shards = &imbalance
+	saveStructuredReport("shard_attack_report", report)
+}
@@ -0,0 +1,102 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"com.ndnhuy.mybank/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// prefixMapper places account "<shard>-<n>" on shard <shard>
+type prefixMapper int
+
+func (m prefixMapper) Shard(accountID string) int {
+	shard, _ := strconv.Atoi(accountID[:1])
+	return shard
+}
+
+func (m prefixMapper) Shards() int { return int(m) }
+
+func shardCustomers(ids ...string) []*domain.Customer {
+	var customers []*domain.Customer
+	for _, id := range ids {
+		customers = append(customers, domain.NewCustomerWithOperator(accountOperator{id}, 100))
+	}
+	return customers
+}
+
+func TestShardTransferRatio(t *testing.T) {
+	mapper := prefixMapper(3)
+	// Shard 2 has a single account, so it only takes part in cross-shard transfers
+	customers := shardCustomers("0-a", "0-b", "1-a", "1-b", "1-c", "2-a")
+	tt, err := NewShardTransferTargeter(customers, mapper, 0.25)
+	require.NoError(t, err)
+
+	targeter := tt.Targeter()
+	cross := 0
+	for i := 0; i < 4000; i++ {
+		var target vegeta.Target
+		require.NoError(t, targeter(&target))
+		var transfer domain.TransferRequest
+		require.NoError(t, json.Unmarshal(target.Body, &transfer))
+
+		from, to := mapper.Shard(transfer.FromAccountID), mapper.Shard(transfer.ToAccountID)
+		if from != to {
+			cross++
+		} else {
+			assert.NotEqual(t, transfer.FromAccountID, transfer.ToAccountID)
+			assert.NotEqual(t, 2, from)
+		}
+		tt.Observe(&vegeta.Result{Code: 200, URL: target.URL})
+	}
+	assert.InDelta(t, 1000, cross, 150)
+
+	tt.Breakdown().Close()
+	assert.Equal(t, []string{CrossShard, SameShard}, tt.Breakdown().Labels())
+	assert.Equal(t, uint64(cross), tt.Breakdown().Get(CrossShard).Requests)
+
+	imbalance := tt.Imbalance()
+	require.Len(t, imbalance.Shards, 3)
+	assert.Equal(t, []int{2, 3, 1}, []int{imbalance.Shards[0].Accounts, imbalance.Shards[1].Accounts, imbalance.Shards[2].Accounts})
+	assert.InDelta(t, float64(cross)/4000, imbalance.CrossShardShare, 1e-9)
+	assert.Equal(t, uint64(4000+cross), imbalance.Shards[0].Transfers+imbalance.Shards[1].Transfers+imbalance.Shards[2].Transfers)
+	assert.Greater(t, imbalance.MaxToMean, 1.0)
+
+	// Every accepted transfer was recorded, so no money was created
+	var total float64
+	for _, customer := range customers {
+		total += customer.ExpectedBalance()
+	}
+	assert.Equal(t, 600.0, total)
+}
+
+func TestShardTransferFailuresCountOnTheirShards(t *testing.T) {
+	customers := shardCustomers("0-a", "1-a")
+	tt, err := NewShardTransferTargeter(customers, prefixMapper(2), 1)
+	require.NoError(t, err)
+
+	var target vegeta.Target
+	require.NoError(t, tt.Targeter()(&target))
+	tt.Observe(&vegeta.Result{Code: 500, URL: target.URL})
+
+	imbalance := tt.Imbalance()
+	for _, load := range imbalance.Shards {
+		assert.Equal(t, uint64(1), load.Failed)
+	}
+	assert.Equal(t, 100.0, customers[0].ExpectedBalance(), "rejected transfers are not recorded")
+}
+
+func TestNewShardTransferTargeterNeedsAccounts(t *testing.T) {
+	_, err := NewShardTransferTargeter(shardCustomers("0-a", "1-a"), prefixMapper(2), 0.5)
+	assert.Error(t, err, "no shard for same-shard transfers")
+	_, err = NewShardTransferTargeter(shardCustomers("0-a", "0-b"), prefixMapper(2), 0.5)
+	assert.Error(t, err, "one shard for cross-shard transfers")
+	_, err = NewShardTransferTargeter(shardCustomers("0-a", "0-b"), prefixMapper(2), 0)
+	assert.NoError(t, err)
+	_, err = NewShardTransferTargeter(shardCustomers("0-a", "0-b"), prefixMapper(2), 1.5)
+	assert.Error(t, err)
+}
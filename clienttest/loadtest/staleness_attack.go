@@ -0,0 +1,93 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/utils"
+)
+
+// AttackStaleness transfers between pairs of accounts and polls both balances right
+// after every acknowledged transfer, measuring how long reads keep serving the
+// balance from before it
+func AttackStaleness(testDuration int) {
+	const numPairs = 10
+
+	fmt.Printf("Starting staleness probe: %d account pairs for %d seconds\n", numPairs, testDuration)
+	fmt.Printf("Polling balances every %v\n", StalenessPollInterval)
+	fmt.Printf("Setting up test customers...\n")
+
+	customers, err := createCustomers("staleness", 2*numPairs, pairInitialBalance)
+	if err != nil {
+		fmt.Printf("Failed to setup customers: %v\n", err)
+		return
+	}
+	defer cleanupTransferCustomers(customers)
+	// Transfers always go the same way within a pair, so a balance never returns to an
+	// earlier value a stale read could be mistaken for
+	pairs := make([][2]*domain.Customer, numPairs)
+	for i := range pairs {
+		pairs[i] = [2]*domain.Customer{customers[2*i], customers[2*i+1]}
+	}
+
+	fmt.Printf("Created %d customers\n", len(customers))
+	fmt.Printf("Target: %s\n", utils.BASE_URL)
+	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
+
+	probe := NewStalenessProbe(StalenessPollInterval, stalenessTimeout)
+	pr := newPairRun("staleness", customers, testDuration)
+	pr.run.SetParam("poll_interval", StalenessPollInterval.String())
+	pr.run.SetParam("pairs", fmt.Sprint(numPairs))
+	fmt.Printf("Staleness probe in progress...\n")
+
+	pr.drive(pairs, func(from, to *domain.Customer, results chan<- labeledResult) {
+		probeTransfer(probe, from, to, results)
+	})
+	staleness := probe.Report()
+	printStalenessReport(staleness)
+	pr.run.RecordSessionGuarantees(customers)
+
+	pr.saveReports("staleness_attack_report", "Staleness Probe", func(w io.Writer) {
+		fmt.Fprintf(w, "Poll Interval: %v\n", StalenessPollInterval)
+		fmt.Fprintf(w, "Writes: %d, Stale Reads: %d of %d (%.2f%%), Timeouts: %d\n",
+			staleness.Writes, staleness.StaleReads, staleness.Reads, staleness.StaleFraction*100, staleness.Timeouts)
+		for _, entry := range staleness.TimeToConsistency {
+			fmt.Fprintf(w, "Time to consistency %s: %v\n", percentileName(entry.Quantile), entry.Latency)
+		}
+	}, func(report *QueueReport) {
+		report.Staleness = &staleness
+	})
+}
+
+// probeTransfer transfers 1 between the customers and awaits both new balances
+func probeTransfer(probe *StalenessProbe, from, to *domain.Customer, results chan<- labeledResult) {
+	started := time.Now()
+	err := from.TransferMoney(to, 1)
+	acked := time.Now()
+	results <- labeledResult{label: "transfer", res: operationResult("POST", "/accounts/transfer", started, acked.Sub(started), err)}
+	if err != nil {
+		time.Sleep(probe.PollInterval)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, customer := range []*domain.Customer{from, to} {
+		wg.Add(1)
+		go func(customer *domain.Customer) {
+			defer wg.Done()
+			read := func() (float64, error) {
+				started := time.Now()
+				balance, err := customer.GetCurrentBalance()
+				results <- labeledResult{label: "poll", res: operationResult("GET", "/accounts/{id}", started, time.Since(started), err)}
+				return balance, err
+			}
+			if err := probe.Await(customer.GetName(), customer.ExpectedBalance(), acked, read); err != nil {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		}(customer)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,176 @@
+package loadtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StalenessPollInterval is how often the staleness probe reads a balance back
+var StalenessPollInterval = 2 * time.Millisecond
+
+// stalenessTimeout is how long a write may stay invisible before it counts as never read back
+const stalenessTimeout = 5 * time.Second
+
+// AccountStaleness is how fast one account's writes became visible to reads
+type AccountStaleness struct {
+	Account               string        `json:"account"`
+	Writes                uint64        `json:"writes"` // Transfers the account took part in
+	Reads                 uint64        `json:"reads"`
+	StaleReads            uint64        `json:"stale_reads"`
+	Timeouts              uint64        `json:"timeouts"` // Writes not read back within the timeout
+	MeanTimeToConsistency time.Duration `json:"mean_time_to_consistency"`
+	MaxTimeToConsistency  time.Duration `json:"max_time_to_consistency"`
+
+	consistent             uint64
+	totalTimeToConsistency time.Duration
+}
+
+// StalenessReport is the outcome of a staleness probe
+type StalenessReport struct {
+	PollInterval      time.Duration      `json:"poll_interval"`
+	Writes            uint64             `json:"writes"`
+	Reads             uint64             `json:"reads"`
+	StaleReads        uint64             `json:"stale_reads"`
+	StaleFraction     float64            `json:"stale_fraction"`
+	Timeouts          uint64             `json:"timeouts"`
+	TimeToConsistency []PercentileEntry  `json:"time_to_consistency"`
+	Distribution      []HistogramBin     `json:"distribution"` // Every time to consistency at HDR resolution
+	Accounts          []AccountStaleness `json:"accounts"`
+}
+
+// StalenessProbe measures how long balance reads keep returning the state from before
+// an acknowledged write. Its resolution is the poll interval plus one read.
+type StalenessProbe struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+
+	mu       sync.Mutex // Accounts are awaited concurrently
+	ttc      *HDRHistogram
+	accounts map[string]*AccountStaleness
+	order    []string
+}
+
+// NewStalenessProbe creates a probe polling at the given interval
+func NewStalenessProbe(pollInterval, timeout time.Duration) *StalenessProbe {
+	return &StalenessProbe{
+		PollInterval: pollInterval,
+		Timeout:      timeout,
+		ttc:          NewHDRHistogram(3),
+		accounts:     map[string]*AccountStaleness{},
+	}
+}
+
+// Await polls read until it returns the expected balance of an account and records
+// the time to consistency: from the write's acknowledgement to the start of the first
+// read reflecting it. It gives up after the probe's timeout, or on a failed read.
+func (p *StalenessProbe) Await(account string, expected float64, acked time.Time, read func() (float64, error)) error {
+	var reads, stale uint64
+	defer func() { p.record(account, reads, stale) }()
+
+	for {
+		started := time.Now()
+		balance, err := read()
+		if err != nil {
+			return err
+		}
+		reads++
+		if abs(balance-expected) < 0.005 {
+			p.recordConsistent(account, started.Sub(acked))
+			return nil
+		}
+		stale++
+		if time.Since(acked) > p.Timeout {
+			p.recordTimeout(account)
+			return fmt.Errorf("%s still at %.2f instead of %.2f after %v", account, balance, expected, p.Timeout)
+		}
+		time.Sleep(p.PollInterval)
+	}
+}
+
+// account returns the stats of an account, p.mu must be held
+func (p *StalenessProbe) account(name string) *AccountStaleness {
+	stats, ok := p.accounts[name]
+	if !ok {
+		stats = &AccountStaleness{Account: name}
+		p.accounts[name] = stats
+		p.order = append(p.order, name)
+	}
+	return stats
+}
+
+func (p *StalenessProbe) record(account string, reads, stale uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.account(account)
+	stats.Writes++
+	stats.Reads += reads
+	stats.StaleReads += stale
+}
+
+func (p *StalenessProbe) recordConsistent(account string, ttc time.Duration) {
+	if ttc < 0 {
+		ttc = 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ttc.Record(ttc)
+	stats := p.account(account)
+	stats.consistent++
+	stats.totalTimeToConsistency += ttc
+	if ttc > stats.MaxTimeToConsistency {
+		stats.MaxTimeToConsistency = ttc
+	}
+}
+
+func (p *StalenessProbe) recordTimeout(account string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.account(account).Timeouts++
+}
+
+// Report returns the probe's outcome, overall and per account
+func (p *StalenessProbe) Report() StalenessReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	report := StalenessReport{
+		PollInterval:      p.PollInterval,
+		TimeToConsistency: p.ttc.Percentiles(),
+		Distribution:      p.ttc.Bins(),
+	}
+	for _, name := range p.order {
+		stats := *p.accounts[name]
+		if stats.consistent > 0 {
+			stats.MeanTimeToConsistency = stats.totalTimeToConsistency / time.Duration(stats.consistent)
+		}
+		report.Writes += stats.Writes
+		report.Reads += stats.Reads
+		report.StaleReads += stats.StaleReads
+		report.Timeouts += stats.Timeouts
+		report.Accounts = append(report.Accounts, stats)
+	}
+	if report.Reads > 0 {
+		report.StaleFraction = float64(report.StaleReads) / float64(report.Reads)
+	}
+	return report
+}
+
+// printStalenessReport prints the time to consistency distribution and the accounts
+func printStalenessReport(report StalenessReport) {
+	fmt.Println("\n🕰️  CACHE STALENESS:")
+	fmt.Printf("   Writes Probed:         %d (polling every %v)\n", report.Writes, report.PollInterval)
+	fmt.Printf("   Stale Reads:           %d of %d (%.2f%%)\n", report.StaleReads, report.Reads, report.StaleFraction*100)
+	if report.Timeouts > 0 {
+		fmt.Printf("   ❌ %d writes were not read back within %v\n", report.Timeouts, stalenessTimeout)
+	}
+	fmt.Println("   Time to consistency:")
+	for _, entry := range report.TimeToConsistency {
+		fmt.Printf("      %-8s %12v\n", percentileName(entry.Quantile), entry.Latency)
+	}
+
+	fmt.Printf("   %-24s %7s %8s %8s %9s %12s %12s\n", "", "Writes", "Reads", "Stale", "Timeouts", "Mean TTC", "Max TTC")
+	for _, account := range report.Accounts {
+		fmt.Printf("   %-24s %7d %8d %8d %9d %12v %12v\n", account.Account, account.Writes, account.Reads,
+			account.StaleReads, account.Timeouts, account.MeanTimeToConsistency, account.MaxTimeToConsistency)
+	}
+}
@@ -0,0 +1,62 @@
+package loadtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// laggingRead serves the balance before the write until lag after acked
+func laggingRead(acked time.Time, lag time.Duration, before, after float64) func() (float64, error) {
+	return func() (float64, error) {
+		if time.Since(acked) < lag {
+			return before, nil
+		}
+		return after, nil
+	}
+}
+
+func TestStalenessProbeMeasuresTimeToConsistency(t *testing.T) {
+	probe := NewStalenessProbe(time.Millisecond, time.Second)
+	acked := time.Now()
+	require.NoError(t, probe.Await("alice", 99, acked, laggingRead(acked, 30*time.Millisecond, 100, 99)))
+	require.NoError(t, probe.Await("bob", 101, time.Now(), laggingRead(time.Now(), 0, 100, 101)))
+
+	report := probe.Report()
+	assert.Equal(t, uint64(2), report.Writes)
+	assert.Greater(t, report.StaleReads, uint64(5))
+	assert.InDelta(t, float64(report.StaleReads)/float64(report.Reads), report.StaleFraction, 1e-9)
+
+	alice, bob := report.Accounts[0], report.Accounts[1]
+	assert.GreaterOrEqual(t, alice.MaxTimeToConsistency, 30*time.Millisecond)
+	assert.Less(t, alice.MaxTimeToConsistency, 60*time.Millisecond)
+	assert.Zero(t, bob.StaleReads)
+	assert.Less(t, bob.MaxTimeToConsistency, 5*time.Millisecond)
+
+	// The slow account dominates the tail of the distribution
+	p99 := report.TimeToConsistency[len(report.TimeToConsistency)-1].Latency
+	assert.InDelta(t, alice.MaxTimeToConsistency, p99, float64(time.Millisecond))
+}
+
+func TestStalenessProbeTimesOut(t *testing.T) {
+	probe := NewStalenessProbe(time.Millisecond, 10*time.Millisecond)
+	acked := time.Now()
+	err := probe.Await("alice", 99, acked, laggingRead(acked, time.Hour, 100, 99))
+	require.Error(t, err)
+
+	report := probe.Report()
+	assert.Equal(t, uint64(1), report.Timeouts)
+	assert.Equal(t, report.Reads, report.StaleReads)
+	assert.Zero(t, report.Accounts[0].MeanTimeToConsistency)
+}
+
+func TestStalenessProbeStopsOnFailedRead(t *testing.T) {
+	probe := NewStalenessProbe(time.Millisecond, time.Second)
+	failed := errors.New("connection refused")
+	err := probe.Await("alice", 99, time.Now(), func() (float64, error) { return 0, failed })
+	assert.ErrorIs(t, err, failed)
+	assert.Zero(t, probe.Report().Reads)
+}
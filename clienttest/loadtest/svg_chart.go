@@ -0,0 +1,187 @@
+package loadtest
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Chart geometry shared by every chart of the HTML report, in SVG user units
+const (
+	chartWidth  = 760
+	chartHeight = 260
+	chartLeft   = 64 // Room for the y axis labels
+	chartRight  = 16
+	chartTop    = 28 // Room for the legend
+	chartBottom = 40 // Room for the x axis labels
+)
+
+// chartSeries is one named line or stack of a chart
+type chartSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+// svgChart accumulates the elements of one chart
+type svgChart struct {
+	b    strings.Builder
+	yMax float64
+}
+
+func newSVGChart(yMax float64) *svgChart {
+	c := &svgChart{yMax: niceCeiling(yMax)}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart">`, chartWidth, chartHeight)
+	return c
+}
+
+func (c *svgChart) plotWidth() float64  { return chartWidth - chartLeft - chartRight }
+func (c *svgChart) plotHeight() float64 { return chartHeight - chartTop - chartBottom }
+
+// y maps a value to its vertical position
+func (c *svgChart) y(v float64) float64 {
+	return chartTop + c.plotHeight()*(1-v/c.yMax)
+}
+
+// yAxis draws horizontal grid lines with their values
+func (c *svgChart) yAxis(unit string) {
+	const ticks = 4
+	for i := 0; i <= ticks; i++ {
+		v := c.yMax * float64(i) / ticks
+		y := c.y(v)
+		fmt.Fprintf(&c.b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(&c.b, `<text x="%d" y="%.1f" class="tick" text-anchor="end">%s</text>`, chartLeft-6, y+4, formatTick(v))
+	}
+	fmt.Fprintf(&c.b, `<text x="12" y="%d" class="axis" transform="rotate(-90 12 %d)" text-anchor="middle">%s</text>`,
+		chartTop+int(c.plotHeight()/2), chartTop+int(c.plotHeight()/2), template.HTMLEscapeString(unit))
+}
+
+// xLabels draws up to eight labels evenly spread over count slots centered at x(i)
+func (c *svgChart) xLabels(labels []string, x func(i int) float64, title string) {
+	step := 1
+	if len(labels) > 8 {
+		step = (len(labels) + 7) / 8
+	}
+	for i := 0; i < len(labels); i += step {
+		fmt.Fprintf(&c.b, `<text x="%.1f" y="%d" class="tick" text-anchor="middle">%s</text>`,
+			x(i), chartHeight-chartBottom+16, template.HTMLEscapeString(labels[i]))
+	}
+	fmt.Fprintf(&c.b, `<text x="%d" y="%d" class="axis" text-anchor="middle">%s</text>`,
+		chartLeft+int(c.plotWidth()/2), chartHeight-6, template.HTMLEscapeString(title))
+}
+
+// legend draws the series names along the top
+func (c *svgChart) legend(series []chartSeries) {
+	x := chartLeft
+	for _, s := range series {
+		fmt.Fprintf(&c.b, `<rect x="%d" y="8" width="10" height="10" fill="%s"/>`, x, s.Color)
+		fmt.Fprintf(&c.b, `<text x="%d" y="17" class="tick">%s</text>`, x+14, template.HTMLEscapeString(s.Name))
+		x += 24 + 7*len(s.Name)
+	}
+}
+
+func (c *svgChart) html() template.HTML {
+	c.b.WriteString(`</svg>`)
+	return template.HTML(c.b.String())
+}
+
+// lineChart draws one line per series over the x labels
+func lineChart(labels []string, xTitle, yUnit string, series []chartSeries) template.HTML {
+	if len(labels) == 0 {
+		return emptyChart()
+	}
+	c := newSVGChart(seriesMax(series, false))
+	x := func(i int) float64 {
+		if len(labels) == 1 {
+			return chartLeft + c.plotWidth()/2
+		}
+		return chartLeft + c.plotWidth()*float64(i)/float64(len(labels)-1)
+	}
+	c.yAxis(yUnit)
+	c.xLabels(labels, x, xTitle)
+	for _, s := range series {
+		points := make([]string, len(s.Values))
+		for i, v := range s.Values {
+			points[i] = fmt.Sprintf("%.1f,%.1f", x(i), c.y(v))
+		}
+		fmt.Fprintf(&c.b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"><title>%s</title></polyline>`,
+			strings.Join(points, " "), s.Color, template.HTMLEscapeString(s.Name))
+	}
+	c.legend(series)
+	return c.html()
+}
+
+// barChart draws one bar per label, stacking the series on top of each other
+func barChart(labels []string, xTitle, yUnit string, series []chartSeries) template.HTML {
+	if len(labels) == 0 {
+		return emptyChart()
+	}
+	c := newSVGChart(seriesMax(series, true))
+	slot := c.plotWidth() / float64(len(labels))
+	x := func(i int) float64 { return chartLeft + slot*(float64(i)+0.5) }
+	c.yAxis(yUnit)
+	c.xLabels(labels, x, xTitle)
+	for i, label := range labels {
+		base := 0.0
+		for _, s := range series {
+			v := s.Values[i]
+			if v <= 0 {
+				continue
+			}
+			top := c.y(base + v)
+			fmt.Fprintf(&c.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s %s: %s</title></rect>`,
+				x(i)-slot*0.4, top, slot*0.8, c.y(base)-top, s.Color,
+				template.HTMLEscapeString(label), template.HTMLEscapeString(s.Name), formatTick(v))
+			base += v
+		}
+	}
+	if len(series) > 1 {
+		c.legend(series)
+	}
+	return c.html()
+}
+
+func emptyChart() template.HTML {
+	return template.HTML(`<p class="muted">No data</p>`)
+}
+
+// seriesMax returns the largest value of the series, or of their sums when stacked
+func seriesMax(series []chartSeries, stacked bool) float64 {
+	largest := 0.0
+	if len(series) == 0 {
+		return largest
+	}
+	for i := range series[0].Values {
+		sum := 0.0
+		for _, s := range series {
+			if stacked {
+				sum += s.Values[i]
+			} else {
+				sum = math.Max(sum, s.Values[i])
+			}
+		}
+		largest = math.Max(largest, sum)
+	}
+	return largest
+}
+
+// niceCeiling rounds v up to 1, 2 or 5 times a power of ten, so axis ticks read well
+func niceCeiling(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// formatTick renders an axis value without trailing zeros
+func formatTick(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,22 @@
+package loadtest
+
+import (
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// tagURL attaches a client-side tag to a target URL as a fragment.
+// Fragments are never sent to the server, but vegeta copies the target URL
+// into every Result, so a result can be traced back to the request that produced it.
+func tagURL(rawURL, tag string) string {
+	return rawURL + "#" + tag
+}
+
+// resultTag returns the tag attached by tagURL, or "" if the result is untagged
+func resultTag(res *vegeta.Result) string {
+	if i := strings.IndexByte(res.URL, '#'); i >= 0 {
+		return res.URL[i+1:]
+	}
+	return ""
+}
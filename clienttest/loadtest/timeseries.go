@@ -0,0 +1,185 @@
+package loadtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// ReportInterval is the width of every time series interval, and how often
+// server metrics are scraped during an attack
+var ReportInterval = time.Second
+
+// ServerMetricsURL is the server's Prometheus endpoint scraped around every attack, "" to disable
+var ServerMetricsURL = utils.METRICS_URL
+
+// IntervalSnapshot summarizes one interval of an attack
+type IntervalSnapshot struct {
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Elapsed      time.Duration     `json:"elapsed"`       // Since the beginning of the attack, at the end of the interval
+	Sent         uint64            `json:"sent"`          // Requests handed to the server
+	Completed    uint64            `json:"completed"`     // Responses received (or failed)
+	OfferedRate  float64           `json:"offered_rate"`  // Requests sent per second
+	Throughput   float64           `json:"throughput"`    // Successful responses per second
+	SuccessRatio float64           `json:"success_ratio"` // Of the responses received in the interval
+	P50          time.Duration     `json:"p50"`
+	P90          time.Duration     `json:"p90"`
+	P99          time.Duration     `json:"p99"`
+	Max          time.Duration     `json:"max"`
+	InFlight     int64             `json:"in_flight"` // Requests awaiting a response at the end of the interval
+	BytesIn      uint64            `json:"bytes_in"`
+	MeanBytesIn  float64           `json:"mean_bytes_in"` // Mean size of the responses received in the interval
+	StatusCodes  map[string]uint64 `json:"status_codes"`  // Responses per StatusClass, e.g. "2xx"
+}
+
+// TimeSeries cuts an attack into fixed intervals. Results are attributed to the
+// interval in which their response arrived.
+type TimeSeries struct {
+	interval  time.Duration
+	start     time.Time
+	current   IntervalSnapshot
+	latencies *HDRHistogram
+	success   uint64
+	snapshots []IntervalSnapshot
+}
+
+// NewTimeSeries creates a time series with the given interval width
+func NewTimeSeries(interval time.Duration) *TimeSeries {
+	return &TimeSeries{
+		interval:  interval,
+		latencies: NewHDRHistogram(2),
+	}
+}
+
+// Interval returns the width of every interval
+func (ts *TimeSeries) Interval() time.Duration {
+	return ts.interval
+}
+
+// Begin marks the start of the attack and of the first interval
+func (ts *TimeSeries) Begin(now time.Time) {
+	ts.start = now
+	ts.current = IntervalSnapshot{Start: now}
+}
+
+// Add records a response in the current interval
+func (ts *TimeSeries) Add(res *vegeta.Result) {
+	if ts.start.IsZero() {
+		ts.Begin(res.Timestamp)
+	}
+	ts.current.Completed++
+	if ts.current.StatusCodes == nil {
+		ts.current.StatusCodes = map[string]uint64{}
+	}
+	ts.current.StatusCodes[StatusClass(res)]++
+	ts.current.BytesIn += res.BytesIn
+	if res.Code >= 200 && res.Code < 400 {
+		ts.success++
+	}
+	ts.latencies.Record(res.Latency)
+}
+
+// Snapshot closes the current interval at now, given how many requests were
+// sent during it and how many are still in flight, and starts the next one
+func (ts *TimeSeries) Snapshot(now time.Time, sent uint64, inFlight int64) IntervalSnapshot {
+	if ts.start.IsZero() {
+		ts.Begin(now.Add(-ts.interval))
+	}
+
+	snap := ts.current
+	snap.End = now
+	snap.Elapsed = now.Sub(ts.start)
+	snap.Sent = sent
+	snap.InFlight = inFlight
+	if secs := now.Sub(snap.Start).Seconds(); secs > 0 {
+		snap.OfferedRate = float64(sent) / secs
+		snap.Throughput = float64(ts.success) / secs
+	}
+	if snap.Completed > 0 {
+		snap.SuccessRatio = float64(ts.success) / float64(snap.Completed)
+		snap.MeanBytesIn = float64(snap.BytesIn) / float64(snap.Completed)
+		snap.P50 = ts.latencies.Quantile(0.5)
+		snap.P90 = ts.latencies.Quantile(0.9)
+		snap.P99 = ts.latencies.Quantile(0.99)
+		snap.Max = ts.latencies.Max()
+	}
+	ts.snapshots = append(ts.snapshots, snap)
+
+	ts.current = IntervalSnapshot{Start: now}
+	ts.latencies = NewHDRHistogram(2)
+	ts.success = 0
+	return snap
+}
+
+// Snapshots returns every closed interval in order
+func (ts *TimeSeries) Snapshots() []IntervalSnapshot {
+	return ts.snapshots
+}
+
+// PrintProgressHeader prints the header of the live progress table
+func PrintProgressHeader() {
+	fmt.Printf("   %8s %8s %8s %8s %10s %10s %10s %9s\n",
+		"Elapsed", "Offered", "Thruput", "Success", "P50", "P99", "Max", "InFlight")
+}
+
+// PrintProgressRow prints one interval as a row of the live progress table
+func PrintProgressRow(snap IntervalSnapshot) {
+	fmt.Printf("   %8s %8.1f %8.1f %7.1f%% %10v %10v %10v %9d\n",
+		snap.Elapsed.Round(100*time.Millisecond), snap.OfferedRate, snap.Throughput, snap.SuccessRatio*100,
+		snap.P50, snap.P99, snap.Max, snap.InFlight)
+}
+
+// WriteTimeSeriesCSV writes the snapshots as CSV, one row per interval
+func WriteTimeSeriesCSV(w io.Writer, snapshots []IntervalSnapshot) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"start", "elapsed_seconds", "sent", "completed", "offered_rate", "throughput",
+		"success_ratio", "p50_ms", "p90_ms", "p99_ms", "max_ms", "in_flight", "bytes_in", "mean_bytes_in"})
+	for _, snap := range snapshots {
+		cw.Write([]string{
+			snap.Start.Format(time.RFC3339Nano),
+			strconv.FormatFloat(snap.Elapsed.Seconds(), 'f', 3, 64),
+			strconv.FormatUint(snap.Sent, 10),
+			strconv.FormatUint(snap.Completed, 10),
+			strconv.FormatFloat(snap.OfferedRate, 'f', 2, 64),
+			strconv.FormatFloat(snap.Throughput, 'f', 2, 64),
+			strconv.FormatFloat(snap.SuccessRatio, 'f', 4, 64),
+			strconv.FormatFloat(milliseconds(snap.P50), 'f', 3, 64),
+			strconv.FormatFloat(milliseconds(snap.P90), 'f', 3, 64),
+			strconv.FormatFloat(milliseconds(snap.P99), 'f', 3, 64),
+			strconv.FormatFloat(milliseconds(snap.Max), 'f', 3, 64),
+			strconv.FormatInt(snap.InFlight, 10),
+			strconv.FormatUint(snap.BytesIn, 10),
+			strconv.FormatFloat(snap.MeanBytesIn, 'f', 1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// saveTimeSeriesCSV writes the snapshots to path, replacing the previous run's file
+func saveTimeSeriesCSV(path string, snapshots []IntervalSnapshot) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Failed to create time series file: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := WriteTimeSeriesCSV(file, snapshots); err != nil {
+		fmt.Printf("Failed to write time series: %v\n", err)
+		return
+	}
+	fmt.Printf("Time series written to %s\n", path)
+}
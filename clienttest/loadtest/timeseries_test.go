@@ -0,0 +1,111 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestTimeSeriesSnapshot(t *testing.T) {
+	ts := NewTimeSeries(time.Second)
+	start := time.Now()
+	ts.Begin(start)
+
+	for i := 0; i < 8; i++ {
+		ts.Add(&vegeta.Result{Code: 200, Latency: 10 * time.Millisecond, BytesIn: 100, Timestamp: start})
+	}
+	ts.Add(&vegeta.Result{Code: 500, Latency: 200 * time.Millisecond, Timestamp: start})
+	ts.Add(&vegeta.Result{Code: 0, Latency: time.Second, Timestamp: start})
+
+	first := ts.Snapshot(start.Add(time.Second), 12, 2)
+	assert.Equal(t, uint64(10), first.Completed)
+	assert.InDelta(t, 12.0, first.OfferedRate, 1e-9)
+	assert.InDelta(t, 8.0, first.Throughput, 1e-9)
+	assert.InDelta(t, 0.8, first.SuccessRatio, 1e-9)
+	assert.InDelta(t, float64(10*time.Millisecond), float64(first.P50), float64(100*time.Microsecond))
+	assert.Equal(t, time.Second, first.Max)
+	assert.Equal(t, int64(2), first.InFlight)
+	assert.Equal(t, time.Second, first.Elapsed)
+	assert.InDelta(t, 80.0, first.MeanBytesIn, 1e-9)
+
+	// Intervals are independent of each other
+	second := ts.Snapshot(start.Add(2*time.Second), 0, 0)
+	assert.Equal(t, uint64(0), second.Completed)
+	assert.Equal(t, time.Duration(0), second.Max)
+	assert.Len(t, ts.Snapshots(), 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTimeSeriesCSV(&buf, ts.Snapshots()))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "12", rows[1][2])
+	assert.Equal(t, "80.0", rows[1][13])
+}
+
+func TestAttackerRecordsTimeSeries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	defer func(interval time.Duration, metricsURL string) {
+		ReportInterval, ServerMetricsURL = interval, metricsURL
+	}(ReportInterval, ServerMetricsURL)
+	ReportInterval = 200 * time.Millisecond
+	ServerMetricsURL = ""
+
+	qm := NewQueueMetrics()
+	attacker := NewAttacker(server.URL+"/accounts", "GET", 50, 1, qm)
+	attacker.Attack()
+	qm.Close()
+
+	snapshots := qm.TimeSeries().Snapshots()
+	require.NotEmpty(t, snapshots)
+
+	var sent, completed uint64
+	for _, snap := range snapshots {
+		sent += snap.Sent
+		completed += snap.Completed
+	}
+	assert.Equal(t, qm.Requests, sent)
+	assert.Equal(t, qm.Requests, completed)
+	assert.Equal(t, int64(0), snapshots[len(snapshots)-1].InFlight)
+	assert.Equal(t, 1.0, qm.Success)
+	assert.Nil(t, qm.ServerMetrics())
+}
+
+func TestAttackerAttachesServerMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	var scrapes atomic.Int64
+	metricsStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "transfers_submitted_total %d\n", 5*scrapes.Add(1))
+		fmt.Fprintf(w, "transfers_queue_length 2\n")
+	}))
+	defer metricsStub.Close()
+
+	defer func(metricsURL string) { ServerMetricsURL = metricsURL }(ServerMetricsURL)
+	ServerMetricsURL = metricsStub.URL
+
+	qm := NewQueueMetrics()
+	NewAttacker(server.URL+"/accounts", "GET", 20, 1, qm).Attack()
+	qm.Close()
+
+	require.NotNil(t, qm.ServerMetrics())
+	assert.Greater(t, qm.ServerMetrics().Submitted, 0.0)
+	assert.Equal(t, 2.0, qm.ServerMetrics().MaxQueueLength)
+	assert.Same(t, qm.ServerMetrics(), qm.Report("test").Server)
+}
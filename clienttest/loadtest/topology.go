@@ -0,0 +1,277 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	"com.ndnhuy.mybank/utils"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// TransferTopology describes the shape of a group of transfers fired back to back.
+// Each shape targets a different part of the server's lock ordering
+// (LocalLockService and OrderedKeyDataFetcher).
+type TransferTopology string
+
+const (
+	TopologyCross  TransferTopology = "cross"   // A→B and B→A at the same time
+	TopologyCycle  TransferTopology = "cycle"   // A→B→C→A
+	TopologyFanIn  TransferTopology = "fan-in"  // several accounts → one account
+	TopologyFanOut TransferTopology = "fan-out" // one account → several accounts
+	TopologySelf   TransferTopology = "self"    // A→A
+)
+
+// AllTopologies lists every supported topology in the order they are generated
+var AllTopologies = []TransferTopology{TopologyCross, TopologyCycle, TopologyFanIn, TopologyFanOut, TopologySelf}
+
+const (
+	cycleLength            = 3   // Accounts in a cycle
+	fanWidth               = 4   // Accounts on the "many" side of fan-in/fan-out
+	topologyTransferAmount = 1.0 // Amount moved by every generated transfer
+)
+
+// ParseTopologies parses a comma separated list of topology names, e.g. "cross,cycle"
+func ParseTopologies(s string) ([]TransferTopology, error) {
+	var topologies []TransferTopology
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, t := range AllTopologies {
+			if string(t) == name {
+				topologies = append(topologies, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown transfer topology: %q", name)
+		}
+	}
+	if len(topologies) == 0 {
+		return nil, fmt.Errorf("no transfer topology given")
+	}
+	return topologies, nil
+}
+
+// customersNeeded returns how many distinct accounts one round of the topology uses
+func customersNeeded(topology TransferTopology) int {
+	switch topology {
+	case TopologyCross:
+		return 2
+	case TopologyCycle:
+		return cycleLength
+	case TopologyFanIn, TopologyFanOut:
+		return fanWidth + 1
+	default:
+		return 1
+	}
+}
+
+// transferEdge is a transfer between two customers, identified by their index
+type transferEdge struct {
+	from, to int
+}
+
+// planRound returns the transfers of one round of the topology over distinct,
+// randomly picked customers out of numCustomers
+func planRound(topology TransferTopology, numCustomers int, rng *rand.Rand) []transferEdge {
+	picked := rng.Perm(numCustomers)[:customersNeeded(topology)]
+
+	var edges []transferEdge
+	switch topology {
+	case TopologyCross:
+		edges = append(edges, transferEdge{picked[0], picked[1]}, transferEdge{picked[1], picked[0]})
+	case TopologyCycle:
+		for i := range picked {
+			edges = append(edges, transferEdge{picked[i], picked[(i+1)%len(picked)]})
+		}
+	case TopologyFanIn:
+		for _, from := range picked[1:] {
+			edges = append(edges, transferEdge{from, picked[0]})
+		}
+	case TopologyFanOut:
+		for _, to := range picked[1:] {
+			edges = append(edges, transferEdge{picked[0], to})
+		}
+	case TopologySelf:
+		edges = append(edges, transferEdge{picked[0], picked[0]})
+	}
+	return edges
+}
+
+// TopologyTransferTargeter generates transfers in adversarial topologies and keeps
+// the customers' ledgers in sync with the transfers the server accepted
+type TopologyTransferTargeter struct {
+	customers  []*domain.Customer
+	topologies []TransferTopology
+	breakdown  *MetricsBreakdown
+	pending    *pendingTransfers
+
+	mu    sync.Mutex // Guards the fields below, the targeter is called from many workers
+	rng   *rand.Rand
+	next  int               // Index of the next topology to generate
+	queue []plannedTransfer // Remaining transfers of the current round
+}
+
+// NewTopologyTransferTargeter creates a targeter cycling through the given topologies
+func NewTopologyTransferTargeter(customers []*domain.Customer, topologies []TransferTopology) (*TopologyTransferTargeter, error) {
+	for _, topology := range topologies {
+		if needed := customersNeeded(topology); len(customers) < needed {
+			return nil, fmt.Errorf("topology %s needs at least %d customers, got %d", topology, needed, len(customers))
+		}
+	}
+
+	return &TopologyTransferTargeter{
+		customers:  customers,
+		topologies: topologies,
+		breakdown:  NewMetricsBreakdown(),
+		pending:    newPendingTransfers(),
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Targeter returns the vegeta.Targeter producing the transfer requests
+func (tt *TopologyTransferTargeter) Targeter() vegeta.Targeter {
+	return func(t *vegeta.Target) error {
+		*t = tt.generateTarget()
+		return nil
+	}
+}
+
+// generateTarget pops the next transfer of the current round, planning a new round when needed
+func (tt *TopologyTransferTargeter) generateTarget() vegeta.Target {
+	tt.mu.Lock()
+	if len(tt.queue) == 0 {
+		topology := tt.topologies[tt.next%len(tt.topologies)]
+		tt.next++
+		for _, edge := range planRound(topology, len(tt.customers), tt.rng) {
+			tt.queue = append(tt.queue, plannedTransfer{
+				label:  string(topology),
+				from:   tt.customers[edge.from],
+				to:     tt.customers[edge.to],
+				amount: topologyTransferAmount,
+			})
+		}
+	}
+	transfer := tt.queue[0]
+	tt.queue = tt.queue[1:]
+	tt.mu.Unlock()
+
+	body, _ := json.Marshal(domain.TransferRequest{
+		FromAccountID: transfer.from.GetAccountID(),
+		ToAccountID:   transfer.to.GetAccountID(),
+		Amount:        transfer.amount,
+	})
+
+	return vegeta.Target{
+		Method: "POST",
+		URL:    tagURL(utils.BASE_URL+"/accounts/transfer", tt.pending.add(transfer)),
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body: body,
+	}
+}
+
+// Observe records a result under its topology, unless it belongs to the warm-up, and,
+// if the server accepted the transfer, applies it to the customers' ledgers
+func (tt *TopologyTransferTargeter) Observe(res *vegeta.Result) {
+	if transfer, ok := tt.pending.settle(res); ok && !isWarmup(res) {
+		tt.breakdown.Add(transfer.label, res)
+	}
+}
+
+// Breakdown returns the metrics split per topology
+func (tt *TopologyTransferTargeter) Breakdown() *MetricsBreakdown {
+	return tt.breakdown
+}
+
+// AttackTopologies fires transfers shaped as the given topologies to provoke lock ordering problems
+func AttackTopologies(rps, testDuration int, topologies []TransferTopology) {
+	const numCustomers = 12
+	const initialBalance = 100.0
+
+	fmt.Printf("Starting topology attack: %d RPS for %d seconds\n", rps, testDuration)
+	fmt.Printf("Topologies: %v\n", topologies)
+	fmt.Printf("Setting up test customers...\n")
+
+	customers, err := createCustomers("topology", numCustomers, initialBalance)
+	if err != nil {
+		fmt.Printf("Failed to setup customers: %v\n", err)
+		return
+	}
+	defer cleanupTransferCustomers(customers)
+	initialTotal := totalExpectedBalance(customers)
+
+	topologyTargeter, err := NewTopologyTransferTargeter(customers, topologies)
+	if err != nil {
+		fmt.Printf("Failed to create targeter: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Created %d customers\n", len(customers))
+	fmt.Printf("Total initial balance: %.2f\n", initialTotal)
+	fmt.Printf("Target URL: %s/accounts/transfer\n", utils.BASE_URL)
+	fmt.Printf("Press Ctrl+C to stop early if needed\n\n")
+
+	queueMetrics := NewQueueMetrics()
+	run := newRun("topologies")
+	run.SetParam("topologies", fmt.Sprint(topologies))
+	fmt.Printf("Topology attack in progress...\n")
+
+	attacker := &Attacker{
+		targeter: topologyTargeter.Targeter(),
+		rate:     vegeta.Rate{Freq: rps, Per: time.Second},
+		duration: time.Duration(testDuration) * time.Second,
+		attacker: newVegetaAttacker(),
+		metrics:  queueMetrics,
+	}
+	attacker.OnResult(topologyTargeter.Observe)
+
+	run.Attach(attacker)
+	attacker.Attack()
+	queueMetrics.Close()
+	topologyTargeter.Breakdown().Close()
+	fmt.Printf("Attack completed!\n\n")
+
+	verification := verifyTotalBalance(customers, initialTotal)
+	run.RecordVerification(verification)
+	run.Finish()
+
+	queueMetrics.PrintReport()
+	topologyTargeter.Breakdown().PrintReport("PER-TOPOLOGY RESULTS")
+
+	fmt.Printf("\n=== Detailed Report ===\n")
+	reportFile, err := os.OpenFile("topology_attack_report.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("Failed to open report file: %v\n", err)
+		return
+	}
+	defer reportFile.Close()
+
+	timestamp := fmt.Sprintf("==== Topology Attack Run at %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
+	reportFile.WriteString(timestamp)
+	writeInterruption(reportFile, queueMetrics)
+	reportFile.WriteString(fmt.Sprintf("Initial Balance: %.2f, Final Balance: %.2f\n", initialTotal, verification.FinalTotal))
+
+	for _, label := range topologyTargeter.Breakdown().Labels() {
+		reportFile.WriteString(fmt.Sprintf("--- Topology: %s (timeouts: %d)\n", label, topologyTargeter.Breakdown().Timeouts(label)))
+		vegeta.NewTextReporter(topologyTargeter.Breakdown().Get(label))(reportFile)
+	}
+	reportFile.WriteString("\n\n")
+	fmt.Printf("Report appended to topology_attack_report.txt\n")
+
+	report := run.Report(queueMetrics)
+	report.Breakdowns = map[string][]BreakdownEntry{"topologies": topologyTargeter.Breakdown().Entries()}
+	saveStructuredReport("topology_attack_report", report)
+}
@@ -0,0 +1,58 @@
+package loadtest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestPlanRoundShapes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	cross := planRound(TopologyCross, 10, rng)
+	require.Len(t, cross, 2)
+	assert.Equal(t, cross[0].from, cross[1].to)
+	assert.Equal(t, cross[0].to, cross[1].from)
+
+	cycle := planRound(TopologyCycle, 10, rng)
+	require.Len(t, cycle, cycleLength)
+	for i, edge := range cycle {
+		assert.Equal(t, edge.to, cycle[(i+1)%len(cycle)].from, "cycle must be closed")
+	}
+
+	fanIn := planRound(TopologyFanIn, 10, rng)
+	require.Len(t, fanIn, fanWidth)
+	for _, edge := range fanIn {
+		assert.Equal(t, fanIn[0].to, edge.to)
+		assert.NotEqual(t, edge.from, edge.to)
+	}
+
+	fanOut := planRound(TopologyFanOut, 10, rng)
+	require.Len(t, fanOut, fanWidth)
+	for _, edge := range fanOut {
+		assert.Equal(t, fanOut[0].from, edge.from)
+		assert.NotEqual(t, edge.from, edge.to)
+	}
+
+	self := planRound(TopologySelf, 10, rng)
+	require.Len(t, self, 1)
+	assert.Equal(t, self[0].from, self[0].to)
+}
+
+func TestParseTopologies(t *testing.T) {
+	topologies, err := ParseTopologies("cross, self")
+	require.NoError(t, err)
+	assert.Equal(t, []TransferTopology{TopologyCross, TopologySelf}, topologies)
+
+	_, err = ParseTopologies("cross,star")
+	assert.Error(t, err)
+}
+
+func TestResultTag(t *testing.T) {
+	res := &vegeta.Result{URL: tagURL("http://localhost:8080/accounts/transfer", "cycle/42")}
+	assert.Equal(t, "cycle/42", resultTag(res))
+	assert.Equal(t, "", resultTag(&vegeta.Result{URL: "http://localhost:8080/accounts"}))
+}
@@ -0,0 +1,19 @@
+package loadtest
+
+// CustomerBalance is the verification of one customer's balance against its ledger
+type CustomerBalance struct {
+	Name      string  `json:"name"`
+	AccountID string  `json:"account_id"`
+	Expected  float64 `json:"expected"` // Initial balance plus every recorded transfer
+	Actual    float64 `json:"actual"`   // As reported by the bank
+	Passed    bool    `json:"passed"`
+	Error     string  `json:"error,omitempty"` // Set when the balance could not be read
+}
+
+// BalanceVerification is the outcome of verifying the customers' balances after a run
+type BalanceVerification struct {
+	InitialTotal float64           `json:"initial_total"`
+	FinalTotal   float64           `json:"final_total"`
+	Passed       bool              `json:"passed"`
+	Customers    []CustomerBalance `json:"customers"`
+}
@@ -0,0 +1,207 @@
+package loadtest
+
+import (
+	"fmt"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// WarmupDuration is how long an attack sends requests before it starts measuring, to
+// warm the server's JIT compiler and connection pools, 0 for no warm-up. With
+// SteadyState it is the longest warm-up.
+var WarmupDuration time.Duration
+
+// WarmupRPS is the rate of the warm-up, 0 for the rate of the attack
+var WarmupRPS int
+
+// SteadyState ends the warm-up as soon as the latency stabilized
+var SteadyState bool
+
+// SteadyStateTolerance is how far apart the median latencies of the last
+// steadyStateWindows intervals may be, relative to the lowest, for latency to be stable
+var SteadyStateTolerance = 0.1
+
+const (
+	// WarmupAttack names the results of the warm-up, so observers can tell them apart
+	WarmupAttack = "Warm-up"
+	// MeasuredAttack names the results of the measured attack
+	MeasuredAttack = "Load Test"
+
+	steadyStateWindows = 3
+	// steadyStateTimeout is the longest warm-up with SteadyState and no WarmupDuration
+	steadyStateTimeout = time.Minute
+)
+
+// WarmupReport summarizes the warm-up of an attack, whose results the metrics exclude
+type WarmupReport struct {
+	RPS         int                `json:"rps"`
+	Planned     time.Duration      `json:"planned"`      // Longest warm-up
+	Duration    time.Duration      `json:"duration"`     // Until the last response
+	SteadyState bool               `json:"steady_state"` // Waited for latency to stabilize
+	Steady      bool               `json:"steady"`       // Latency stabilized before Planned
+	Requests    uint64             `json:"requests"`
+	Success     float64            `json:"success"`
+	Mean        time.Duration      `json:"mean"`
+	Percentiles []PercentileEntry  `json:"percentiles"`
+	TimeSeries  []IntervalSnapshot `json:"time_series"`
+}
+
+// isWarmup tells whether a result belongs to the warm-up
+func isWarmup(res *vegeta.Result) bool {
+	return res.Attack == WarmupAttack
+}
+
+// SteadyStateDetector tells when the median latency stopped moving: when the medians of
+// the last Windows intervals are within Tolerance of each other
+type SteadyStateDetector struct {
+	Windows   int
+	Tolerance float64
+
+	current *HDRHistogram
+	medians []time.Duration
+}
+
+// NewSteadyStateDetector creates a detector over the given number of intervals
+func NewSteadyStateDetector(windows int, tolerance float64) *SteadyStateDetector {
+	return &SteadyStateDetector{
+		Windows:   windows,
+		Tolerance: tolerance,
+		current:   NewHDRHistogram(2),
+	}
+}
+
+// Add records a latency in the current interval
+func (d *SteadyStateDetector) Add(latency time.Duration) {
+	d.current.Record(latency)
+}
+
+// CloseInterval ends the current interval and tells whether latency is steady. An
+// interval without responses starts over.
+func (d *SteadyStateDetector) CloseInterval() bool {
+	if d.current.Total() == 0 {
+		d.medians = d.medians[:0]
+		return false
+	}
+	d.medians = append(d.medians, d.current.Quantile(0.5))
+	d.current = NewHDRHistogram(2)
+	if len(d.medians) > d.Windows {
+		d.medians = d.medians[1:]
+	}
+	if len(d.medians) < d.Windows {
+		return false
+	}
+
+	lowest, highest := d.medians[0], d.medians[0]
+	for _, median := range d.medians {
+		lowest, highest = min(lowest, median), max(highest, median)
+	}
+	return float64(highest-lowest) <= d.Tolerance*float64(lowest)
+}
+
+// warmup sends requests at WarmupRPS for WarmupDuration, or until latency is steady with
+// SteadyState, nil when there is no warm-up. Its results reach the observers, which
+// keep the customers' ledgers, but not the metrics. An interrupt ends it early.
+func (a *Attacker) warmup() *WarmupReport {
+	if !interrupted().IsZero() {
+		return nil
+	}
+	planned := WarmupDuration
+	if planned <= 0 {
+		if !SteadyState {
+			return nil
+		}
+		planned = steadyStateTimeout
+	}
+	rate := a.rate
+	if WarmupRPS > 0 {
+		rate = vegeta.Rate{Freq: WarmupRPS, Per: time.Second}
+	}
+	report := &WarmupReport{RPS: rate.Freq, Planned: planned, SteadyState: SteadyState}
+	if SteadyState {
+		fmt.Printf("Warming up at %d req/s until latency is steady, %v at most...\n", rate.Freq, planned)
+	} else {
+		fmt.Printf("Warming up at %d req/s for %v...\n", rate.Freq, planned)
+	}
+
+	a.metrics.warming.Store(true)
+	defer a.metrics.warming.Store(false)
+	metrics := &vegeta.Metrics{}
+	hdr := NewHDRHistogram(3)
+	series := NewTimeSeries(a.metrics.TimeSeries().Interval())
+	detector := NewSteadyStateDetector(steadyStateWindows, SteadyStateTolerance)
+	ticker := time.NewTicker(series.Interval())
+	defer ticker.Stop()
+
+	// Every attack stops its vegeta attacker for good, so the warm-up gets its own
+	attacker := newVegetaAttacker()
+	PrintProgressHeader()
+	began := time.Now()
+	series.Begin(began)
+	results := attacker.Attack(a.countingTargeter(), rate, planned, WarmupAttack)
+	interrupts := interruptContext().Done()
+	for done := false; !done; {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				PrintProgressRow(series.Snapshot(time.Now(), a.sent.Swap(0), a.inFlight.Load()))
+				done = true
+				continue
+			}
+			a.inFlight.Add(-1)
+			metrics.Add(res)
+			hdr.Record(res.Latency)
+			series.Add(res)
+			detector.Add(res.Latency)
+			for _, observe := range a.observers {
+				observe(res)
+			}
+		case now := <-ticker.C:
+			PrintProgressRow(series.Snapshot(now, a.sent.Swap(0), a.inFlight.Load()))
+			if SteadyState && detector.CloseInterval() && !report.Steady {
+				report.Steady = true
+				attacker.Stop() // Results of the requests in flight still arrive
+			}
+		case <-interrupts:
+			attacker.Stop()
+			interrupts = nil
+		}
+	}
+	metrics.Close()
+
+	report.Duration = time.Since(began)
+	report.Requests = metrics.Requests
+	report.Success = metrics.Success
+	report.Mean = metrics.Latencies.Mean
+	report.Percentiles = hdr.Percentiles()
+	report.TimeSeries = series.Snapshots()
+	fmt.Printf("Warm-up completed after %v", report.Duration.Round(100*time.Millisecond))
+	if report.Steady {
+		fmt.Printf(", latency steady")
+	} else if report.SteadyState {
+		fmt.Printf(", latency not steady yet")
+	}
+	fmt.Printf("\n\n")
+	return report
+}
+
+// printWarmupReport prints the summary of the warm-up
+func printWarmupReport(report *WarmupReport) {
+	if report == nil {
+		return
+	}
+	fmt.Println("\n🔥 WARM-UP (excluded from the results above):")
+	fmt.Printf("   Rate:                  %d req/s for %v (planned %v)\n", report.RPS, report.Duration.Round(100*time.Millisecond), report.Planned)
+	if report.SteadyState {
+		steady := "✅ latency steady"
+		if !report.Steady {
+			steady = "⚠️  latency never steady, measuring anyway"
+		}
+		fmt.Printf("   Steady State:          %s\n", steady)
+	}
+	fmt.Printf("   Requests:              %d (%.2f%% success)\n", report.Requests, report.Success*100)
+	fmt.Printf("   Mean Latency:          %v\n", report.Mean)
+	for _, entry := range report.Percentiles {
+		fmt.Printf("      %-8s %12v\n", percentileName(entry.Quantile), entry.Latency)
+	}
+}
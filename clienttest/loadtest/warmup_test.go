@@ -0,0 +1,103 @@
+package loadtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func TestSteadyStateDetector(t *testing.T) {
+	detector := NewSteadyStateDetector(3, 0.1)
+	interval := func(latency time.Duration) bool {
+		for i := 0; i < 10; i++ {
+			detector.Add(latency)
+		}
+		return detector.CloseInterval()
+	}
+
+	assert.False(t, interval(50*time.Millisecond), "cold")
+	assert.False(t, interval(20*time.Millisecond))
+	assert.False(t, interval(10*time.Millisecond), "still falling")
+	assert.False(t, interval(10*time.Millisecond))
+	assert.True(t, interval(10500*time.Microsecond), "three medians within 10%")
+	assert.False(t, detector.CloseInterval(), "an empty interval starts over")
+	assert.False(t, interval(10*time.Millisecond))
+	assert.False(t, interval(10*time.Millisecond))
+	assert.True(t, interval(10*time.Millisecond))
+}
+
+// withWarmup configures a warm-up for one test
+func withWarmup(t *testing.T, duration time.Duration, rps int, steadyState bool) {
+	interval, metricsURL := ReportInterval, ServerMetricsURL
+	warmupDuration, warmupRPS, steady, tolerance := WarmupDuration, WarmupRPS, SteadyState, SteadyStateTolerance
+	t.Cleanup(func() {
+		ReportInterval, ServerMetricsURL = interval, metricsURL
+		WarmupDuration, WarmupRPS, SteadyState, SteadyStateTolerance = warmupDuration, warmupRPS, steady, tolerance
+	})
+	ReportInterval, ServerMetricsURL = 100*time.Millisecond, ""
+	WarmupDuration, WarmupRPS, SteadyState = duration, rps, steadyState
+}
+
+func TestAttackerWarmup(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.Header.Get("X-Vegeta-Attack")]++
+		mu.Unlock()
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	withWarmup(t, 500*time.Millisecond, 40, false)
+
+	qm := NewQueueMetrics()
+	attacker := NewAttacker(server.URL+"/accounts", "GET", 20, 1, qm)
+	observed := map[string]int{}
+	attacker.OnResult(func(res *vegeta.Result) { observed[res.Attack]++ })
+	attacker.Attack()
+	qm.Close()
+
+	warmup := qm.Warmup()
+	require.NotNil(t, warmup)
+	assert.InDelta(t, 20, warmup.Requests, 3, "40 req/s for 500ms")
+	assert.Equal(t, 1.0, warmup.Success)
+	assert.NotEmpty(t, warmup.Percentiles)
+	assert.NotEmpty(t, warmup.TimeSeries)
+	assert.False(t, warmup.Steady)
+
+	assert.InDelta(t, 20, qm.Requests, 2, "only the measured attack")
+	assert.Equal(t, received[MeasuredAttack], int(qm.Requests))
+	assert.Equal(t, received[WarmupAttack], int(warmup.Requests))
+	assert.Equal(t, map[string]int{WarmupAttack: int(warmup.Requests), MeasuredAttack: int(qm.Requests)}, observed,
+		"observers keeping ledgers see the warm-up too")
+	for _, snap := range qm.TimeSeries().Snapshots() {
+		assert.Positive(t, snap.Elapsed)
+	}
+	assert.Equal(t, warmup, qm.Report("warmup").Warmup)
+}
+
+func TestAttackerWarmupUntilSteady(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	}))
+	defer server.Close()
+	withWarmup(t, 10*time.Second, 0, true)
+	SteadyStateTolerance = 10 // Any three intervals in a row are steady
+
+	qm := NewQueueMetrics()
+	NewAttacker(server.URL+"/accounts", "GET", 50, 1, qm).Attack()
+	qm.Close()
+
+	warmup := qm.Warmup()
+	require.NotNil(t, warmup)
+	assert.True(t, warmup.Steady)
+	assert.Equal(t, 50, warmup.RPS, "the attack's rate")
+	assert.Less(t, warmup.Duration, time.Second, "ended once steady, long before 10s")
+	assert.Positive(t, qm.Requests)
+}
@@ -0,0 +1,223 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"com.ndnhuy.mybank/domain"
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// ServeWorker serves assignments of a coordinator on addr, e.g. ":7070", until it fails
+func ServeWorker(addr string) error {
+	fmt.Printf("Worker listening on %s\n", addr)
+	return http.ListenAndServe(addr, NewWorkerHandler())
+}
+
+// NewWorkerHandler returns the handler of a worker. POST /run takes an Assignment, waits
+// for its start and streams one JSON line per result, then one with the ledger.
+// POST /stop ends the running assignment early, still streaming the results of the
+// requests in flight and the ledger. A worker runs one assignment at a time.
+func NewWorkerHandler() http.Handler {
+	var busy atomic.Bool
+	var stop atomic.Pointer[chan struct{}] // Closed to stop the running assignment
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /stop", func(w http.ResponseWriter, r *http.Request) {
+		if stopped := stop.Swap(nil); stopped != nil {
+			close(*stopped)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /run", func(w http.ResponseWriter, r *http.Request) {
+		var assignment Assignment
+		if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+			http.Error(w, fmt.Sprintf("invalid assignment: %v", err), http.StatusBadRequest)
+			return
+		}
+		targeter, ledger, err := assignmentTargeter(assignment)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !busy.CompareAndSwap(false, true) {
+			http.Error(w, "worker is running another assignment", http.StatusConflict)
+			return
+		}
+		defer busy.Store(false)
+		stopped := make(chan struct{})
+		stop.Store(&stopped)
+		defer stop.Store(nil)
+
+		fmt.Printf("Run %s: %s at %d RPS for %v, starting at %s\n", assignment.RunID, assignment.Scenario,
+			assignment.RPS, assignment.Duration, assignment.StartAt.Format("15:04:05.000"))
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		stream := newWorkerStream(w)
+
+		select {
+		case <-time.After(time.Until(assignment.StartAt)):
+		case <-stopped:
+			stream.send(workerMessage{Ledger: ledger.entries()})
+			fmt.Printf("Run %s stopped before it started\n", assignment.RunID)
+			return
+		case <-r.Context().Done():
+			return
+		}
+		attacker := newVegetaAttacker()
+		rate := vegeta.Rate{Freq: assignment.RPS, Per: time.Second}
+		results := attacker.Attack(targeter, rate, assignment.Duration, assignment.RunID)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-stopped:
+				attacker.Stop() // Results of the requests in flight still arrive
+			case <-done:
+			}
+		}()
+		for res := range results {
+			ledger.settle(res)
+			res.Body = nil // The coordinator only needs the outcome
+			if err := stream.send(workerMessage{Result: res}); err != nil {
+				// The coordinator is gone, nobody will read the rest
+				attacker.Stop()
+				for range results {
+				}
+				fmt.Printf("Run %s aborted: %v\n", assignment.RunID, err)
+				return
+			}
+		}
+		stream.send(workerMessage{Ledger: ledger.entries()})
+		fmt.Printf("Run %s completed\n", assignment.RunID)
+	})
+	return mux
+}
+
+// assignmentTargeter returns the targeter of an assignment's scenario and the ledger
+// its transfers are tallied in
+func assignmentTargeter(assignment Assignment) (vegeta.Targeter, *workerLedger, error) {
+	if assignment.RPS <= 0 || assignment.Duration <= 0 {
+		return nil, nil, fmt.Errorf("rps and duration must be positive")
+	}
+	ledger := newWorkerLedger()
+	switch assignment.Scenario {
+	case DistributedAccounts:
+		return vegeta.NewStaticTargeter(vegeta.Target{Method: "GET", URL: assignment.TargetURL + "/accounts"}), ledger, nil
+	case DistributedTransfers:
+		if len(assignment.Sources) == 0 || len(assignment.Dests) == 0 {
+			return nil, nil, fmt.Errorf("transfers need source and destination accounts")
+		}
+		return transferTargeter(assignment, ledger), ledger, nil
+	}
+	return nil, nil, fmt.Errorf("unknown scenario %q", assignment.Scenario)
+}
+
+// transferTargeter transfers 1 from a random source to a random destination, tagging
+// every transfer so the ledger tallies it once the bank accepted it
+func transferTargeter(assignment Assignment, ledger *workerLedger) vegeta.Targeter {
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	return func(t *vegeta.Target) error {
+		transferReq := domain.TransferRequest{
+			FromAccountID: assignment.Sources[rand.Intn(len(assignment.Sources))],
+			ToAccountID:   assignment.Dests[rand.Intn(len(assignment.Dests))],
+			Amount:        1,
+		}
+		body, err := json.Marshal(transferReq)
+		if err != nil {
+			return err
+		}
+		*t = vegeta.Target{
+			Method: "POST",
+			URL:    tagURL(assignment.TargetURL+"/accounts/transfer", ledger.plan(transferReq)),
+			Header: header,
+			Body:   body,
+		}
+		return nil
+	}
+}
+
+// workerLedger tallies the transfers of an assignment the bank accepted, per pair of
+// accounts, like pendingTransfers keeps the customers' ledgers
+type workerLedger struct {
+	mu      sync.Mutex
+	totals  map[[2]string]*LedgerEntry
+	nextID  uint64
+	pending map[uint64]domain.TransferRequest // Sent, result not known yet
+}
+
+func newWorkerLedger() *workerLedger {
+	return &workerLedger{totals: map[[2]string]*LedgerEntry{}, pending: map[uint64]domain.TransferRequest{}}
+}
+
+// plan registers a transfer about to be sent and returns the tag to attach to its URL
+func (l *workerLedger) plan(transfer domain.TransferRequest) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	id := l.nextID
+	l.nextID++
+	l.pending[id] = transfer
+	return fmt.Sprintf("transfer/%d", id)
+}
+
+// settle tallies the transfer behind a result if the bank accepted it
+func (l *workerLedger) settle(res *vegeta.Result) {
+	_, id, ok := splitTag(resultTag(res))
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	transfer, ok := l.pending[id]
+	delete(l.pending, id)
+	l.mu.Unlock()
+	if ok && res.Code >= 200 && res.Code < 300 {
+		l.record(transfer.FromAccountID, transfer.ToAccountID, transfer.Amount)
+	}
+}
+
+func (l *workerLedger) record(from, to string, amount float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.totals[[2]string{from, to}]
+	if entry == nil {
+		entry = &LedgerEntry{From: from, To: to}
+		l.totals[[2]string{from, to}] = entry
+	}
+	entry.Amount += amount
+	entry.Count++
+}
+
+func (l *workerLedger) entries() []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]LedgerEntry, 0, len(l.totals))
+	for _, entry := range l.totals {
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// workerStream writes one JSON message per line and flushes it right away
+type workerStream struct {
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+func newWorkerStream(w http.ResponseWriter) *workerStream {
+	flusher, _ := w.(http.Flusher)
+	return &workerStream{encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+func (s *workerStream) send(msg workerMessage) error {
+	if err := s.encoder.Encode(msg); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
+	"time"
 
+	"com.ndnhuy.mybank/balancer"
+	"com.ndnhuy.mybank/domain"
 	"com.ndnhuy.mybank/loadtest"
+	"com.ndnhuy.mybank/mybankclient"
+	"com.ndnhuy.mybank/shard"
+	"com.ndnhuy.mybank/utils"
 )
 
 const (
@@ -32,14 +42,379 @@ func getConfigFromEnv() (int, int) {
 	return rps, duration
 }
 
+// runCompare implements `compare [flags] <report.jsonl[#run-id] | run-id>...`, exiting with
+// status 1 when a candidate run regressed against the first (baseline) run
+func runCompare(args []string) {
+	tolerances := loadtest.DefaultTolerances
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	flags.Float64Var(&tolerances.Throughput, "throughput", tolerances.Throughput, "largest accepted relative throughput drop")
+	flags.Float64Var(&tolerances.Latency, "latency", tolerances.Latency, "largest accepted relative latency increase")
+	flags.Float64Var(&tolerances.Success, "success", tolerances.Success, "largest accepted absolute success ratio drop")
+	flags.Float64Var(&tolerances.Alpha, "alpha", tolerances.Alpha, "significance level of the Mann-Whitney test")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go run main.go compare [flags] <report.jsonl[#run-id] | run-id>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	regressed, err := loadtest.Compare(flags.Args(), tolerances)
+	if err != nil {
+		fmt.Printf("Compare failed: %v\n", err)
+		os.Exit(1)
+	}
+	if regressed {
+		os.Exit(1)
+	}
+}
+
+// runHistory implements `history [flags]`, listing or trending the recorded runs
+func runHistory(args []string) {
+	var filter loadtest.HistoryFilter
+	var since, verified, trend string
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	flags.StringVar(&filter.Scenario, "scenario", "", "only runs of this scenario, e.g. transfers")
+	flags.IntVar(&filter.Last, "last", 20, "only the most recent runs, 0 for all")
+	flags.StringVar(&since, "since", "", "only runs of the last duration, e.g. 24h")
+	flags.StringVar(&verified, "verified", "", "only runs whose verification \"passed\" or \"failed\"")
+	flags.StringVar(&trend, "trend", "", "trend one metric: p50, p99, mean, throughput, success or bytes-in")
+	flags.Parse(args)
+
+	if since != "" {
+		window, err := time.ParseDuration(since)
+		if err != nil {
+			fmt.Printf("Invalid -since: %v\n", err)
+			os.Exit(1)
+		}
+		filter.Since = time.Now().Add(-window)
+	}
+	switch verified {
+	case "":
+	case "passed", "failed":
+		passed := verified == "passed"
+		filter.Verified = &passed
+	default:
+		fmt.Printf("Invalid -verified: %q\n", verified)
+		os.Exit(1)
+	}
+
+	if err := loadtest.ShowHistory(filter, trend); err != nil {
+		fmt.Printf("History failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runWorker implements `worker [flags]`, serving a coordinator's assignments
+func runWorker(args []string) {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	listen := flags.String("listen", ":7070", "address to serve assignments on")
+	flags.Parse(args)
+
+	if err := loadtest.ServeWorker(*listen); err != nil {
+		fmt.Printf("Worker failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runSeed implements `seed [flags]`, creating accounts into a dataset file the load
+// tests can take their customers from with DATASET
+func runSeed(args []string) {
+	cfg := loadtest.SeedConfig{Balances: loadtest.DefaultBalanceDistribution, Progress: time.Second}
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	flags.IntVar(&cfg.Accounts, "accounts", 1000, "number of accounts to create")
+	flags.IntVar(&cfg.Concurrency, "concurrency", loadtest.DefaultSeedConcurrency, "accounts created at the same time")
+	balances := flags.String("balance", cfg.Balances.String(), "balance distribution: fixed:<amount>, uniform:<min>,<max> or lognormal:<median>,<sigma>")
+	out := flags.String("out", loadtest.DefaultDatasetPath, "dataset file to write")
+	flags.Parse(args)
+
+	if cfg.Accounts <= 0 || cfg.Concurrency <= 0 {
+		fmt.Printf("Invalid -accounts or -concurrency, both must be positive\n")
+		os.Exit(1)
+	}
+	dist, err := loadtest.ParseBalanceDistribution(*balances)
+	if err != nil {
+		fmt.Printf("Invalid -balance: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Balances = dist
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client := mybankclient.New(utils.BASE_URL, mybankclient.WithHTTPClient(domain.HTTPClient))
+	if err := loadtest.SeedDataset(ctx, client, *out, cfg); err != nil {
+		fmt.Printf("Seed failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func main() {
+	// HISTORY_DIR overrides where runs are recorded, "off" disables the history
+	if envHistory := os.Getenv("HISTORY_DIR"); envHistory == "off" {
+		loadtest.HistoryDir = ""
+	} else if envHistory != "" {
+		loadtest.HistoryDir = envHistory
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		}
+	}
+
 	rps, testDuration := getConfigFromEnv()
 
-	// Check if ATTACK_TYPE is set to "transfers"
+	// HIST_BUCKETS overrides the latency histogram buckets, e.g. "0,5ms,10ms,50ms,100ms,1s"
+	if envBuckets := os.Getenv("HIST_BUCKETS"); envBuckets != "" {
+		buckets, err := loadtest.ParseLatencyBuckets(envBuckets)
+		if err != nil {
+			fmt.Printf("Invalid HIST_BUCKETS: %v\n", err)
+			os.Exit(1)
+		}
+		loadtest.LatencyBuckets = buckets
+	}
+
+	// INTERVAL sets the width of the live progress / time series intervals, e.g. "500ms"
+	if envInterval := os.Getenv("INTERVAL"); envInterval != "" {
+		interval, err := time.ParseDuration(envInterval)
+		if err != nil || interval <= 0 {
+			fmt.Printf("Invalid INTERVAL: %q\n", envInterval)
+			os.Exit(1)
+		}
+		loadtest.ReportInterval = interval
+	}
+
+	// WARMUP sends requests for a while before measuring, e.g. "30s", at WARMUP_RPS (default
+	// RPS); STEADY_STATE=true ends it once latency is steady, WARMUP (default 1m) at the latest
+	if envWarmup := os.Getenv("WARMUP"); envWarmup != "" {
+		warmup, err := time.ParseDuration(envWarmup)
+		if err != nil || warmup < 0 {
+			fmt.Printf("Invalid WARMUP: %q\n", envWarmup)
+			os.Exit(1)
+		}
+		loadtest.WarmupDuration = warmup
+	}
+	if envWarmupRPS := os.Getenv("WARMUP_RPS"); envWarmupRPS != "" {
+		if parsed, err := strconv.Atoi(envWarmupRPS); err == nil && parsed > 0 {
+			loadtest.WarmupRPS = parsed
+		}
+	}
+	if envSteady := os.Getenv("STEADY_STATE"); envSteady != "" {
+		steady, err := strconv.ParseBool(envSteady)
+		if err != nil {
+			fmt.Printf("Invalid STEADY_STATE: %q\n", envSteady)
+			os.Exit(1)
+		}
+		loadtest.SteadyState = steady
+	}
+
+	// SERVER_METRICS_URL overrides the scraped Prometheus endpoint, "off" disables scraping
+	if envMetricsURL := os.Getenv("SERVER_METRICS_URL"); envMetricsURL == "off" {
+		loadtest.ServerMetricsURL = ""
+	} else if envMetricsURL != "" {
+		loadtest.ServerMetricsURL = envMetricsURL
+	}
+
+	// REMOTE_WRITE_URL streams client metrics to Prometheus, e.g. http://localhost:9090/api/v1/write
+	loadtest.RemoteWriteURL = os.Getenv("REMOTE_WRITE_URL")
+
+	// SERVERS sets the number of parallel servers (c) of the queueing models
+	if envServers := os.Getenv("SERVERS"); envServers != "" {
+		if parsed, err := strconv.Atoi(envServers); err == nil && parsed > 0 {
+			loadtest.Servers = parsed
+		}
+	}
+
+	// TARGETS spreads requests over several app instances instead of utils.BASE_URL, e.g.
+	// "http://localhost:8081,http://localhost:8082", with LB_POLICY picking the instance
+	if envTargets := os.Getenv("TARGETS"); envTargets != "" {
+		policy := balancer.RoundRobin
+		if envPolicy := os.Getenv("LB_POLICY"); envPolicy != "" {
+			parsed, err := balancer.ParsePolicy(envPolicy)
+			if err != nil {
+				fmt.Printf("Invalid LB_POLICY: %v\n", err)
+				os.Exit(1)
+			}
+			policy = parsed
+		}
+		b, err := balancer.New(balancer.ParseTargets(envTargets), policy)
+		if err != nil {
+			fmt.Printf("Invalid TARGETS: %v\n", err)
+			os.Exit(1)
+		}
+		loadtest.Balancer = b
+		domain.HTTPClient = b.Client()
+		fmt.Printf("Spreading requests over %v (%s)\n", b.Targets(), policy)
+
+		// HEALTH_URLS lists the instances' readiness endpoints in TARGETS order, by default
+		// port 9001 of each target's host; HEALTH_INTERVAL sets the probe period, "off" disables
+		if envInterval := os.Getenv("HEALTH_INTERVAL"); envInterval != "off" {
+			interval := time.Second
+			if envInterval != "" {
+				parsed, err := time.ParseDuration(envInterval)
+				if err != nil || parsed <= 0 {
+					fmt.Printf("Invalid HEALTH_INTERVAL: %q\n", envInterval)
+					os.Exit(1)
+				}
+				interval = parsed
+			}
+			var healthURLs []string
+			if envHealth := os.Getenv("HEALTH_URLS"); envHealth != "" {
+				healthURLs = balancer.ParseTargets(envHealth)
+			} else {
+				for _, target := range b.Targets() {
+					healthURLs = append(healthURLs, balancer.DefaultHealthURL(target))
+				}
+			}
+			if err := b.StartHealthChecks(context.Background(), interval, healthURLs); err != nil {
+				fmt.Printf("Invalid HEALTH_URLS: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Probing %v every %v\n", healthURLs, interval)
+		}
+	}
+
+	// A worker attacks with the client configuration above, TARGETS included, and takes
+	// everything else from the coordinator's assignments; seeding sends through TARGETS too
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "worker":
+			runWorker(os.Args[2:])
+			return
+		case "seed":
+			runSeed(os.Args[2:])
+			return
+		}
+	}
+
+	// DATASET makes the scenarios take their customers from a file written by the seed
+	// command instead of creating accounts
+	if envDataset := os.Getenv("DATASET"); envDataset != "" {
+		dataset, err := loadtest.LoadDataset(envDataset)
+		if err != nil {
+			fmt.Printf("Invalid DATASET: %v\n", err)
+			os.Exit(1)
+		}
+		loadtest.AccountDataset = dataset
+		fmt.Printf("Taking customers from %d seeded accounts of %s\n", dataset.Len(), envDataset)
+	}
+
+	// ATTACK_TYPE selects the scenario, GET /accounts is the default
 	attackType := os.Getenv("ATTACK_TYPE")
-	if attackType == "transfers" {
+	// The first Ctrl+C stops the scenario with partial reports, the second exits
+	loadtest.HandleInterrupts()
+	switch attackType {
+	case "transfers":
 		loadtest.AttackTransfers(rps, testDuration)
-	} else {
+	case "topologies":
+		topologies := loadtest.AllTopologies
+		if envTopologies := os.Getenv("TOPOLOGIES"); envTopologies != "" {
+			parsed, err := loadtest.ParseTopologies(envTopologies)
+			if err != nil {
+				fmt.Printf("Invalid TOPOLOGIES: %v\n", err)
+				os.Exit(1)
+			}
+			topologies = parsed
+		}
+		loadtest.AttackTopologies(rps, testDuration, topologies)
+	case "mixed":
+		mix := loadtest.DefaultWorkloadMix
+		if envMix := os.Getenv("MIX"); envMix != "" {
+			parsed, err := loadtest.ParseWorkloadMix(envMix)
+			if err != nil {
+				fmt.Printf("Invalid MIX: %v\n", err)
+				os.Exit(1)
+			}
+			mix = parsed
+		}
+		loadtest.AttackMixed(rps, testDuration, mix)
+	case "journeys":
+		// WORKERS sets how many journeys run concurrently, RPS doesn't apply
+		workers := 10
+		if envWorkers := os.Getenv("WORKERS"); envWorkers != "" {
+			if parsed, err := strconv.Atoi(envWorkers); err == nil && parsed > 0 {
+				workers = parsed
+			}
+		}
+		loadtest.AttackJourneys(workers, testDuration)
+	case "staleness":
+		// POLL_INTERVAL sets how often balances are read back after a transfer, e.g. "1ms"
+		if envPoll := os.Getenv("POLL_INTERVAL"); envPoll != "" {
+			interval, err := time.ParseDuration(envPoll)
+			if err != nil || interval <= 0 {
+				fmt.Printf("Invalid POLL_INTERVAL: %q\n", envPoll)
+				os.Exit(1)
+			}
+			loadtest.StalenessPollInterval = interval
+		}
+		loadtest.AttackStaleness(testDuration)
+	case "shards":
+		// SHARDS and VNODES shape the consistent hash ring, CROSS_SHARD the share of
+		// transfers between accounts on different shards
+		shards, virtualNodes, crossRatio := loadtest.DefaultShards, shard.DefaultVirtualNodes, 0.5
+		if envShards := os.Getenv("SHARDS"); envShards != "" {
+			if parsed, err := strconv.Atoi(envShards); err == nil && parsed > 0 {
+				shards = parsed
+			}
+		}
+		if envNodes := os.Getenv("VNODES"); envNodes != "" {
+			if parsed, err := strconv.Atoi(envNodes); err == nil && parsed > 0 {
+				virtualNodes = parsed
+			}
+		}
+		if envCross := os.Getenv("CROSS_SHARD"); envCross != "" {
+			parsed, err := strconv.ParseFloat(envCross, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				fmt.Printf("Invalid CROSS_SHARD: %q, expected a ratio between 0 and 1\n", envCross)
+				os.Exit(1)
+			}
+			crossRatio = parsed
+		}
+		ring, err := shard.NewRing(shards, virtualNodes)
+		if err != nil {
+			fmt.Printf("Invalid shard ring: %v\n", err)
+			os.Exit(1)
+		}
+		loadtest.ShardMapper = ring
+		loadtest.AttackShards(rps, testDuration, crossRatio)
+	case "settlement":
+		// POLL_INTERVAL sets how often both balances of a pending transfer are read, SHARDS
+		// how many shards the pairs' accounts are spread over
+		if envPoll := os.Getenv("POLL_INTERVAL"); envPoll != "" {
+			interval, err := time.ParseDuration(envPoll)
+			if err != nil || interval <= 0 {
+				fmt.Printf("Invalid POLL_INTERVAL: %q\n", envPoll)
+				os.Exit(1)
+			}
+			loadtest.SettlementPollInterval = interval
+		}
+		if envShards := os.Getenv("SHARDS"); envShards != "" {
+			if parsed, err := strconv.Atoi(envShards); err == nil && parsed > 0 {
+				ring, err := shard.NewRing(parsed, shard.DefaultVirtualNodes)
+				if err != nil {
+					fmt.Printf("Invalid shard ring: %v\n", err)
+					os.Exit(1)
+				}
+				loadtest.ShardMapper = ring
+			}
+		}
+		loadtest.AttackSettlement(testDuration)
+	case "distributed":
+		// WORKER_URLS lists the workers, SCENARIO what they run: transfers (default) or accounts
+		workers := balancer.ParseTargets(os.Getenv("WORKER_URLS"))
+		if len(workers) == 0 {
+			fmt.Printf("WORKER_URLS is required, e.g. http://localhost:7071,http://localhost:7072\n")
+			os.Exit(1)
+		}
+		scenario := loadtest.DistributedTransfers
+		if envScenario := os.Getenv("SCENARIO"); envScenario != "" {
+			scenario = envScenario
+		}
+		loadtest.AttackDistributed(workers, scenario, rps, testDuration)
+	default:
 		loadtest.AttackGetAccounts(rps, testDuration)
 	}
 }
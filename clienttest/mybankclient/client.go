@@ -0,0 +1,183 @@
+// Package mybankclient is a Go client for the MyBank API. Every call takes a context
+// and fails with a *StatusError when the bank answers with an unexpected status.
+//
+//	client := mybankclient.New("http://localhost:8080")
+//	account, err := client.CreateAccount(ctx, 100)
+//	...
+//	err = client.Transfer(ctx, account.ID, other.ID, 10)
+//	if errors.Is(err, mybankclient.ErrServer) { ... }
+package mybankclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is where a bank runs locally
+const DefaultBaseURL = "http://localhost:8080"
+
+// Account is an account and its balance
+type Account struct {
+	ID      string  `json:"id"`
+	Balance float64 `json:"balance"`
+}
+
+// CreateAccountRequest is the body of POST /accounts
+type CreateAccountRequest struct {
+	InitialBalance float64 `json:"initialBalance"`
+}
+
+// TransferRequest is the body of POST /accounts/transfer
+type TransferRequest struct {
+	FromAccountID string  `json:"fromAccountId"`
+	ToAccountID   string  `json:"toAccountId"`
+	Amount        float64 `json:"amount"`
+}
+
+// Client calls the API of one bank. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends the requests through an HTTP client, e.g. one with a timeout or
+// a load balancing transport. The default is http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client of the bank at baseURL, e.g. DefaultBaseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the URL of the bank
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// CreateAccount opens an account holding initialBalance
+func (c *Client) CreateAccount(ctx context.Context, initialBalance float64) (*Account, error) {
+	var account Account
+	if err := c.do(ctx, "create account", http.MethodPost, "/accounts", CreateAccountRequest{InitialBalance: initialBalance}, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetAccount returns an account with its current balance
+func (c *Client) GetAccount(ctx context.Context, accountID string) (*Account, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("%w: empty account ID", ErrInvalidRequest)
+	}
+	var account Account
+	if err := c.do(ctx, "get account", http.MethodGet, "/accounts/"+url.PathEscape(accountID), nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListAccounts returns every account of the bank. Use StreamAccounts to go through a
+// large bank without holding every account in memory.
+func (c *Client) ListAccounts(ctx context.Context) ([]Account, error) {
+	accounts := []Account{}
+	err := c.StreamAccounts(ctx, 0, func(account Account) error {
+		accounts = append(accounts, account)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// Transfer moves amount from one account to another
+func (c *Client) Transfer(ctx context.Context, fromAccountID, toAccountID string, amount float64) error {
+	if fromAccountID == "" || toAccountID == "" {
+		return fmt.Errorf("%w: empty account ID", ErrInvalidRequest)
+	}
+	transfer := TransferRequest{FromAccountID: fromAccountID, ToAccountID: toAccountID, Amount: amount}
+	return c.do(ctx, "transfer", http.MethodPost, "/accounts/transfer", transfer, nil)
+}
+
+// do sends a request with body encoded as JSON, unless nil, and decodes the response
+// into out, unless nil
+func (c *Client) do(ctx context.Context, op, method, path string, body, out any) error {
+	var read func(io.Reader) error
+	if out != nil {
+		read = func(respBody io.Reader) error {
+			if err := json.NewDecoder(respBody).Decode(out); err != nil {
+				return fmt.Errorf("failed to decode %s response: %w", op, err)
+			}
+			return nil
+		}
+	}
+	return c.send(ctx, op, method, path, body, read)
+}
+
+// send sends a request with body encoded as JSON, unless nil, and hands a successful
+// response body to read, unless nil
+func (c *Client) send(ctx context.Context, op, method, path string, body any, read func(io.Reader) error) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s request: %w", op, err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", op, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(op, resp)
+	}
+	if read != nil {
+		if err := read(resp.Body); err != nil {
+			return err
+		}
+	}
+	io.Copy(io.Discard, resp.Body) // Reuse the connection
+	return nil
+}
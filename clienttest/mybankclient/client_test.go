@@ -0,0 +1,152 @@
+package mybankclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBank is an in-memory bank answering like the Spring Boot app, errors included
+type fakeBank struct {
+	mu        sync.Mutex
+	accounts  map[string]float64
+	userAgent string
+}
+
+func newFakeBank(t *testing.T) (*fakeBank, *Client) {
+	bank := &fakeBank{accounts: map[string]float64{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /accounts", func(w http.ResponseWriter, r *http.Request) {
+		var req CreateAccountRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		bank.mu.Lock()
+		defer bank.mu.Unlock()
+		bank.userAgent = r.UserAgent()
+		id := fmt.Sprintf("acc-%d", len(bank.accounts)+1)
+		bank.accounts[id] = req.InitialBalance
+		json.NewEncoder(w).Encode(Account{ID: id, Balance: req.InitialBalance})
+	})
+	mux.HandleFunc("GET /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		bank.mu.Lock()
+		defer bank.mu.Unlock()
+		balance, ok := bank.accounts[r.PathValue("id")]
+		if !ok {
+			springError(w, http.StatusNotFound, "")
+			return
+		}
+		json.NewEncoder(w).Encode(Account{ID: r.PathValue("id"), Balance: balance})
+	})
+	mux.HandleFunc("GET /accounts", func(w http.ResponseWriter, r *http.Request) {
+		bank.mu.Lock()
+		defer bank.mu.Unlock()
+		accounts := []Account{}
+		for id, balance := range bank.accounts {
+			accounts = append(accounts, Account{ID: id, Balance: balance})
+		}
+		sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+		json.NewEncoder(w).Encode(accounts)
+	})
+	mux.HandleFunc("POST /accounts/transfer", func(w http.ResponseWriter, r *http.Request) {
+		var req TransferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+			springError(w, http.StatusBadRequest, "")
+			return
+		}
+		bank.mu.Lock()
+		defer bank.mu.Unlock()
+		if bank.accounts[req.FromAccountID] < req.Amount {
+			springError(w, http.StatusInternalServerError, "insufficient balance")
+			return
+		}
+		bank.accounts[req.FromAccountID] -= req.Amount
+		bank.accounts[req.ToAccountID] += req.Amount
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return bank, New(server.URL+"/", WithUserAgent("mybank-test"))
+}
+
+func springError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"status": status, "error": http.StatusText(status), "message": message})
+}
+
+func TestAccountsAndTransfers(t *testing.T) {
+	bank, client := newFakeBank(t)
+	ctx := context.Background()
+
+	a, err := client.CreateAccount(ctx, 100)
+	require.NoError(t, err)
+	b, err := client.CreateAccount(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, Account{ID: "acc-1", Balance: 100}, *a)
+	assert.Equal(t, "mybank-test", bank.userAgent)
+
+	require.NoError(t, client.Transfer(ctx, a.ID, b.ID, 30))
+	account, err := client.GetAccount(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 35.0, account.Balance)
+
+	accounts, err := client.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Account{{ID: "acc-1", Balance: 70}, {ID: "acc-2", Balance: 35}}, accounts)
+}
+
+func TestTypedErrors(t *testing.T) {
+	_, client := newFakeBank(t)
+	ctx := context.Background()
+	a, err := client.CreateAccount(ctx, 10)
+	require.NoError(t, err)
+
+	_, err = client.GetAccount(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, "get account", statusErr.Op)
+	assert.Equal(t, "get account failed with status: 404 (Not Found)", err.Error())
+
+	err = client.Transfer(ctx, a.ID, "other", 20)
+	assert.ErrorIs(t, err, ErrServer)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "transfer failed with status: 500 (insufficient balance)", err.Error())
+
+	assert.ErrorIs(t, client.Transfer(ctx, a.ID, "other", -1), ErrInvalidRequest)
+	assert.ErrorIs(t, client.Transfer(ctx, "", "other", 1), ErrInvalidRequest)
+	_, err = client.GetAccount(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, err = New("http://127.0.0.1:1").ListAccounts(ctx)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, new(*StatusError)), "connection errors are not status errors")
+}
+
+func TestContextAndHTTPClient(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := New(slow.URL).ListAccounts(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	client := New(slow.URL, WithHTTPClient(&http.Client{Timeout: 20 * time.Millisecond}))
+	_, err = client.GetAccount(context.Background(), "acc-1")
+	assert.ErrorContains(t, err, "failed to get account")
+	assert.Equal(t, slow.URL, client.BaseURL())
+}
@@ -0,0 +1,59 @@
+package mybankclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors a StatusError matches with errors.Is, by the class of its status code
+var (
+	ErrInvalidRequest = errors.New("mybank: invalid request") // 400, or rejected before sending
+	ErrNotFound       = errors.New("mybank: not found")       // 404
+	ErrServer         = errors.New("mybank: server error")    // 5xx
+)
+
+// StatusError is a response of the bank with an unexpected status code
+type StatusError struct {
+	Op         string // "create account", "get account", "list accounts" or "transfer"
+	StatusCode int
+	Message    string // From the error body, empty when it had none
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s failed with status: %d", e.Op, e.StatusCode)
+	}
+	return fmt.Sprintf("%s failed with status: %d (%s)", e.Op, e.StatusCode, e.Message)
+}
+
+// Is matches the sentinel error of the status code's class
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// newStatusError reads the message of a Spring Boot error body,
+// {"status":500,"error":"Internal Server Error","message":"..."}, when there is one
+func newStatusError(op string, resp *http.Response) *StatusError {
+	statusErr := &StatusError{Op: op, StatusCode: resp.StatusCode}
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		statusErr.Message = body.Message
+		if statusErr.Message == "" {
+			statusErr.Message = body.Error
+		}
+	}
+	return statusErr
+}
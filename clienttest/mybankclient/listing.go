@@ -0,0 +1,164 @@
+package mybankclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListOptions selects a page of accounts. A bank that doesn't paginate ignores them and
+// answers with every account as a single page.
+type ListOptions struct {
+	Limit  int    // Accounts per page, 0 for the bank's default
+	Cursor string // Where the page starts, "" for the first page
+}
+
+// AccountPage is one page of accounts. A paginating bank answers GET /accounts with
+// {"accounts": [...], "nextCursor": "..."}, the current one with a plain array.
+type AccountPage struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"nextCursor,omitempty"` // "" on the last page
+}
+
+// ListAccountsPage returns one page of accounts
+func (c *Client) ListAccountsPage(ctx context.Context, opts ListOptions) (*AccountPage, error) {
+	page := &AccountPage{}
+	next, err := c.streamPage(ctx, opts, func(account Account) error {
+		page.Accounts = append(page.Accounts, account)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next
+	return page, nil
+}
+
+// StreamAccounts calls fn with every account of the bank, page by page of limit
+// accounts (0 for the bank's default). Accounts are decoded one at a time as they
+// arrive, so a listing of any size is never held in memory. An error of fn stops the
+// stream and is returned.
+func (c *Client) StreamAccounts(ctx context.Context, limit int, fn func(Account) error) error {
+	opts := ListOptions{Limit: limit}
+	for {
+		next, err := c.streamPage(ctx, opts, fn)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		if next == opts.Cursor {
+			return fmt.Errorf("list accounts returned the same cursor %q again", next)
+		}
+		opts.Cursor = next
+	}
+}
+
+// streamPage requests a page and calls fn with each of its accounts, returning the
+// cursor of the next page
+func (c *Client) streamPage(ctx context.Context, opts ListOptions, fn func(Account) error) (string, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	path := "/accounts"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var next string
+	err := c.send(ctx, "list accounts", http.MethodGet, path, nil, func(body io.Reader) error {
+		var err error
+		next, err = decodeAccounts(json.NewDecoder(body), fn)
+		var stopped *callbackError
+		if errors.As(err, &stopped) {
+			return stopped.err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode list accounts response: %w", err)
+		}
+		return nil
+	})
+	return next, err
+}
+
+// decodeAccounts decodes a plain array of accounts or a page object, calling fn with
+// every account as soon as it is decoded
+func decodeAccounts(dec *json.Decoder, fn func(Account) error) (nextCursor string, err error) {
+	token, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	switch token {
+	case json.Delim('['):
+		return "", decodeArray(dec, fn)
+	case json.Delim('{'):
+	default:
+		return "", fmt.Errorf("unexpected %v, expected an array or a page", token)
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case "accounts":
+			if token, err := dec.Token(); err != nil {
+				return "", err
+			} else if token == nil {
+				continue // "accounts": null
+			} else if token != json.Delim('[') {
+				return "", fmt.Errorf("unexpected %v, expected the accounts array", token)
+			}
+			if err := decodeArray(dec, fn); err != nil {
+				return "", err
+			}
+		case "nextCursor":
+			var cursor *string
+			if err := dec.Decode(&cursor); err != nil {
+				return "", err
+			}
+			if cursor != nil {
+				nextCursor = *cursor
+			}
+		default:
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return "", err
+			}
+		}
+	}
+	_, err = dec.Token() // }
+	return nextCursor, err
+}
+
+// decodeArray decodes the elements of an array whose '[' was read, and its ']'
+func decodeArray(dec *json.Decoder, fn func(Account) error) error {
+	for dec.More() {
+		var account Account
+		if err := dec.Decode(&account); err != nil {
+			return err
+		}
+		if err := fn(account); err != nil {
+			return &callbackError{err}
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// callbackError carries an error of a StreamAccounts callback through the decoding,
+// so it is returned as it is rather than as a decoding failure
+type callbackError struct{ err error }
+
+func (e *callbackError) Error() string { return e.err.Error() }
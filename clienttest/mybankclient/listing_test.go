@@ -0,0 +1,144 @@
+package mybankclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPaginatedBank serves n accounts in pages of limit accounts, 2 by default, each
+// starting after the account ID of its cursor
+func newPaginatedBank(t *testing.T, n int) (*Client, *[]string) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RawQuery)
+		mu.Unlock()
+		limit := 2
+		if r.URL.Query().Has("limit") {
+			limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+		}
+		start := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			fmt.Sscanf(cursor, "acc-%d", &start)
+		}
+		accounts := []Account{}
+		for i := start + 1; i <= n && len(accounts) < limit; i++ {
+			accounts = append(accounts, Account{ID: fmt.Sprintf("acc-%d", i), Balance: float64(i)})
+		}
+		page := map[string]any{"total": n, "accounts": accounts} // total is skipped
+		if len(accounts) > 0 && start+len(accounts) < n {
+			page["nextCursor"] = accounts[len(accounts)-1].ID
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(server.Close)
+	return New(server.URL), &requests
+}
+
+func TestPaginatedListing(t *testing.T) {
+	client, requests := newPaginatedBank(t, 5)
+	ctx := context.Background()
+
+	page, err := client.ListAccountsPage(ctx, ListOptions{Limit: 3})
+	require.NoError(t, err)
+	assert.Len(t, page.Accounts, 3)
+	assert.Equal(t, "acc-3", page.NextCursor)
+	page, err = client.ListAccountsPage(ctx, ListOptions{Limit: 3, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []Account{{ID: "acc-4", Balance: 4}, {ID: "acc-5", Balance: 5}}, page.Accounts)
+	assert.Empty(t, page.NextCursor, "last page")
+
+	*requests = nil
+	accounts, err := client.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, accounts, 5)
+	assert.Equal(t, []string{"", "cursor=acc-2", "cursor=acc-4"}, *requests, "followed the cursors")
+
+	var ids []string
+	stop := errors.New("stop")
+	err = client.StreamAccounts(ctx, 2, func(account Account) error {
+		ids = append(ids, account.ID)
+		if len(ids) == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Same(t, stop, err, "the callback's error as it is")
+	assert.Equal(t, []string{"acc-1", "acc-2", "acc-3"}, ids)
+}
+
+func TestUnpaginatedListing(t *testing.T) {
+	_, client := newFakeBank(t)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := client.CreateAccount(ctx, 10)
+		require.NoError(t, err)
+	}
+
+	page, err := client.ListAccountsPage(ctx, ListOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, page.Accounts, 3, "the whole array is the only page")
+	assert.Empty(t, page.NextCursor)
+
+	count := 0
+	require.NoError(t, client.StreamAccounts(ctx, 1, func(Account) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 3, count)
+}
+
+func TestStreamAccountsDecodesAsItReads(t *testing.T) {
+	decoded := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"acc-1","balance":1}`)
+		w.(http.Flusher).Flush()
+		<-decoded // The rest is only sent once the first account was handed over
+		fmt.Fprint(w, `,{"id":"acc-2","balance":2}]`)
+	}))
+	defer server.Close()
+
+	var ids []string
+	err := New(server.URL).StreamAccounts(context.Background(), 0, func(account Account) error {
+		ids = append(ids, account.ID)
+		if account.ID == "acc-1" {
+			close(decoded)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acc-1", "acc-2"}, ids)
+}
+
+func TestListingErrors(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"accounts": 5}`:        "expected the accounts array",
+		`"accounts"`:             "expected an array or a page",
+		`[{"id":"acc-1"}, {"id"`: "failed to decode list accounts response",
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		_, err := New(server.URL).ListAccounts(context.Background())
+		assert.ErrorContains(t, err, want, body)
+		server.Close()
+	}
+
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"accounts": [], "nextCursor": "same"}`)
+	}))
+	defer loop.Close()
+	_, err := New(loop.URL).ListAccounts(context.Background())
+	assert.ErrorContains(t, err, `same cursor "same"`)
+}
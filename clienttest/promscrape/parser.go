@@ -0,0 +1,165 @@
+package promscrape
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Sample is one line of the Prometheus text exposition format
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Samples is the parsed content of a scrape
+type Samples []Sample
+
+// Parse reads metrics in the Prometheus text exposition format (version 0.0.4).
+// HELP and TYPE comments are skipped, timestamps are ignored.
+func Parse(r io.Reader) (Samples, error) {
+	var samples Samples
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return samples, nil
+}
+
+// parseLine parses `name{label="value",...} value [timestamp]`
+func parseLine(line string) (Sample, error) {
+	sample := Sample{Labels: map[string]string{}}
+
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return sample, fmt.Errorf("missing metric name or value: %q", line)
+	}
+	sample.Name = line[:nameEnd]
+	rest := line[nameEnd:]
+
+	if rest[0] == '{' {
+		end, err := parseLabels(rest, sample.Labels)
+		if err != nil {
+			return sample, err
+		}
+		rest = rest[end:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("expected a value and an optional timestamp: %q", line)
+	}
+	value, err := parseValue(fields[0])
+	if err != nil {
+		return sample, err
+	}
+	sample.Value = value
+	return sample, nil
+}
+
+// parseLabels parses a `{a="x",b="y"}` block into labels and returns the index
+// right after the closing brace
+func parseLabels(s string, labels map[string]string) (int, error) {
+	i := 1 // skip '{'
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return 0, fmt.Errorf("unterminated label set: %q", s)
+		}
+		if s[i] == '}' {
+			return i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return 0, fmt.Errorf("label without value: %q", s)
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return 0, fmt.Errorf("label value of %s must be quoted: %q", name, s)
+		}
+		i++
+
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return 0, fmt.Errorf("unterminated label value of %s: %q", name, s)
+		}
+		i++ // skip closing quote
+		labels[name] = value.String()
+	}
+}
+
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample value %q", s)
+	}
+	return value, nil
+}
+
+// Sum returns the sum of every sample with the given name, across all label
+// sets, and whether any sample was found
+func (samples Samples) Sum(name string) (float64, bool) {
+	sum, found := 0.0, false
+	for _, sample := range samples {
+		if sample.Name == name {
+			sum += sample.Value
+			found = true
+		}
+	}
+	return sum, found
+}
+
+// Max returns the largest value of the samples with the given name
+func (samples Samples) Max(name string) (float64, bool) {
+	max, found := math.Inf(-1), false
+	for _, sample := range samples {
+		if sample.Name == name && sample.Value > max {
+			max = sample.Value
+			found = true
+		}
+	}
+	return max, found
+}
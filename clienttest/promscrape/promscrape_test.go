@@ -0,0 +1,121 @@
+package promscrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exposition = `# HELP transfers_submitted_total  
+# TYPE transfers_submitted_total counter
+transfers_submitted_total 120.0
+# HELP transfers_wait_time_seconds  
+# TYPE transfers_wait_time_seconds summary
+transfers_wait_time_seconds_count 100.0
+transfers_wait_time_seconds_sum 5.0
+transfers_wait_time_seconds_max 0.25
+# TYPE http_server_requests_seconds summary
+http_server_requests_seconds_count{error="none",method="GET",outcome="SUCCESS",status="200",uri="/accounts/{accountId}"} 7 1718000000000
+http_server_requests_seconds_count{error="none",method="POST",outcome="SUCCESS",status="200",uri="/accounts"} 3
+jvm_info{version="21.0.2 \"LTS\"",runtime="OpenJDK\\Runtime"} 1.0
+jvm_gc_pause_seconds_max +Inf
+`
+
+func TestParse(t *testing.T) {
+	samples, err := Parse(strings.NewReader(exposition))
+	require.NoError(t, err)
+	require.Len(t, samples, 8)
+
+	submitted, ok := samples.Sum("transfers_submitted_total")
+	assert.True(t, ok)
+	assert.Equal(t, 120.0, submitted)
+
+	requests, ok := samples.Sum("http_server_requests_seconds_count")
+	assert.True(t, ok)
+	assert.Equal(t, 10.0, requests)
+
+	assert.Equal(t, "/accounts/{accountId}", samples[4].Labels["uri"])
+	assert.Equal(t, `21.0.2 "LTS"`, samples[6].Labels["version"])
+	assert.Equal(t, `OpenJDK\Runtime`, samples[6].Labels["runtime"])
+
+	_, ok = samples.Sum("missing_metric")
+	assert.False(t, ok)
+}
+
+func TestParseRejectsMalformedLines(t *testing.T) {
+	for _, line := range []string{
+		"no_value",
+		`bad_labels{a=b} 1`,
+		`unterminated{a="b" 1`,
+		"bad_value abc",
+	} {
+		_, err := Parse(strings.NewReader(line))
+		assert.Error(t, err, line)
+	}
+}
+
+// serverStub exposes the server's transfer queue metrics, advancing them on every scrape
+// as if 10 transfers per scrape were served in 20ms each after waiting 5ms
+func serverStub(t *testing.T) *httptest.Server {
+	var scrapes atomic.Int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := float64(scrapes.Add(1) - 1)
+		fmt.Fprintf(w, "transfers_submitted_total %g\n", 10*n)
+		fmt.Fprintf(w, "transfers_completed_total %g\n", 10*n)
+		fmt.Fprintf(w, "transfers_wait_time_seconds_count %g\n", 10*n)
+		fmt.Fprintf(w, "transfers_wait_time_seconds_sum %g\n", 10*n*0.005)
+		fmt.Fprintf(w, "transfers_service_time_seconds_count %g\n", 10*n)
+		fmt.Fprintf(w, "transfers_service_time_seconds_sum %g\n", 10*n*0.020)
+		fmt.Fprintf(w, "transfers_queue_length %g\n", n)
+	}))
+}
+
+func TestMonitorReportsServerQueue(t *testing.T) {
+	server := serverStub(t)
+	defer server.Close()
+
+	monitor := NewMonitor(NewScraper(server.URL), 20*time.Millisecond)
+	require.NoError(t, monitor.Start(context.Background()))
+	time.Sleep(110 * time.Millisecond)
+	report, err := monitor.Stop(context.Background())
+	require.NoError(t, err)
+
+	scrapes := float64(report.Scrapes)
+	assert.GreaterOrEqual(t, report.Scrapes, 4)
+	assert.Equal(t, 10*(scrapes-1), report.Submitted)
+	assert.InDelta(t, float64(5*time.Millisecond), float64(report.MeanWaitTime), float64(time.Microsecond))
+	assert.InDelta(t, float64(20*time.Millisecond), float64(report.MeanServiceTime), float64(time.Microsecond))
+	assert.InDelta(t, 50.0, report.ServiceRate, 1e-6)
+	assert.InDelta(t, report.ArrivalRate/50.0, report.Utilization, 1e-9)
+	assert.Equal(t, scrapes-1, report.MaxQueueLength)
+	assert.InDelta(t, (scrapes-1)/2, report.MeanQueueLength, 1e-9)
+	assert.Empty(t, report.MissingMetrics)
+	assert.Nil(t, report.ReportedUtilization)
+}
+
+func TestNewServerQueueReportHandlesCounterReset(t *testing.T) {
+	before := Snapshot{Time: time.Unix(0, 0), Samples: Samples{{Name: MetricSubmitted, Value: 500}}}
+	after := Snapshot{Time: time.Unix(10, 0), Samples: Samples{{Name: MetricSubmitted, Value: 30}}}
+
+	report := NewServerQueueReport(before, nil, after)
+	assert.Equal(t, 30.0, report.Submitted)
+	assert.Equal(t, 3.0, report.ArrivalRate)
+	assert.Contains(t, report.MissingMetrics, MetricQueueLength)
+	assert.Contains(t, report.MissingMetrics, MetricServiceTimeSum)
+}
+
+func TestMonitorStartFailsWhenUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	monitor := NewMonitor(NewScraper(server.URL), time.Second)
+	assert.Error(t, monitor.Start(context.Background()))
+}
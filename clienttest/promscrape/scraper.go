@@ -0,0 +1,137 @@
+package promscrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Snapshot is the result of one scrape
+type Snapshot struct {
+	Time    time.Time
+	Samples Samples
+}
+
+// Scraper fetches metrics from a Prometheus endpoint such as /actuator/prometheus
+type Scraper struct {
+	url    string
+	client *http.Client
+}
+
+// NewScraper creates a scraper for the given endpoint URL
+func NewScraper(url string) *Scraper {
+	return &Scraper{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// URL returns the scraped endpoint
+func (s *Scraper) URL() string {
+	return s.url
+}
+
+// Scrape fetches and parses the endpoint once
+func (s *Scraper) Scrape(ctx context.Context) (*Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scrape request: %w", err)
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape of %s failed with status: %d", s.url, resp.StatusCode)
+	}
+
+	samples, err := Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics from %s: %w", s.url, err)
+	}
+	return &Snapshot{Time: time.Now(), Samples: samples}, nil
+}
+
+// Monitor scrapes an endpoint before, periodically during, and after a run
+type Monitor struct {
+	scraper  *Scraper
+	interval time.Duration
+
+	before *Snapshot
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex // Guards during, appended by the scraping goroutine
+	during []Snapshot
+	errors int
+}
+
+// NewMonitor creates a monitor scraping every interval while running
+func NewMonitor(scraper *Scraper, interval time.Duration) *Monitor {
+	return &Monitor{
+		scraper:  scraper,
+		interval: interval,
+	}
+}
+
+// Start takes the "before" scrape and starts scraping in the background.
+// It fails if the endpoint cannot be scraped at all.
+func (m *Monitor) Start(ctx context.Context) error {
+	before, err := m.scraper.Scrape(ctx)
+	if err != nil {
+		return err
+	}
+	m.before = before
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go m.run(ctx)
+	return nil
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	defer close(m.done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := m.scraper.Scrape(ctx)
+			m.mu.Lock()
+			if err != nil {
+				m.errors++
+			} else {
+				m.during = append(m.during, *snapshot)
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// Stop stops background scraping, takes the "after" scrape and summarizes the run
+func (m *Monitor) Stop(ctx context.Context) (*ServerQueueReport, error) {
+	if m.cancel == nil {
+		return nil, fmt.Errorf("monitor was not started")
+	}
+	m.cancel()
+	<-m.done
+
+	after, err := m.scraper.Scrape(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report := NewServerQueueReport(*m.before, m.during, *after)
+	report.FailedScrapes = m.errors
+	return report, nil
+}
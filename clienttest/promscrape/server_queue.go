@@ -0,0 +1,103 @@
+package promscrape
+
+import (
+	"time"
+)
+
+// Metric names as exported by Micrometer for the server's transfer QueueMetrics
+const (
+	MetricSubmitted        = "transfers_submitted_total"
+	MetricCompleted        = "transfers_completed_total"
+	MetricWaitTimeSum      = "transfers_wait_time_seconds_sum"
+	MetricWaitTimeCount    = "transfers_wait_time_seconds_count"
+	MetricServiceTimeSum   = "transfers_service_time_seconds_sum"
+	MetricServiceTimeCount = "transfers_service_time_seconds_count"
+	MetricQueueLength      = "transfers_queue_length"
+	MetricUtilization      = "system_utilization"
+)
+
+// ServerQueueReport is the server-measured view of the transfer queue over a run
+type ServerQueueReport struct {
+	Window              time.Duration `json:"window"`
+	Submitted           float64       `json:"submitted"`
+	Completed           float64       `json:"completed"`
+	ArrivalRate         float64       `json:"arrival_rate"`    // λ, transfers submitted per second
+	CompletionRate      float64       `json:"completion_rate"` // Transfers completed per second
+	MeanWaitTime        time.Duration `json:"mean_wait_time"`
+	MeanServiceTime     time.Duration `json:"mean_service_time"`
+	ServiceRate         float64       `json:"service_rate"`         // μ = 1 / mean service time
+	Utilization         float64       `json:"utilization"`          // ρ = λ / μ
+	ReportedUtilization *float64      `json:"reported_utilization"` // The server's own system_utilization gauge, if exported
+	MeanQueueLength     float64       `json:"mean_queue_length"`    // Averaged over every scrape
+	MaxQueueLength      float64       `json:"max_queue_length"`
+	Scrapes             int           `json:"scrapes"`
+	FailedScrapes       int           `json:"failed_scrapes"`
+	MissingMetrics      []string      `json:"missing_metrics,omitempty"`
+}
+
+// NewServerQueueReport computes the server's queue figures from the scrapes taken
+// before, during and after a run. Counters are differenced between before and after.
+func NewServerQueueReport(before Snapshot, during []Snapshot, after Snapshot) *ServerQueueReport {
+	report := &ServerQueueReport{
+		Window:  after.Time.Sub(before.Time),
+		Scrapes: len(during) + 2,
+	}
+
+	delta := func(name string) float64 {
+		afterValue, ok := after.Samples.Sum(name)
+		if !ok {
+			report.MissingMetrics = append(report.MissingMetrics, name)
+			return 0
+		}
+		beforeValue, _ := before.Samples.Sum(name)
+		if afterValue < beforeValue {
+			// The counter was reset during the run (restart or QueueMetrics.reset)
+			return afterValue
+		}
+		return afterValue - beforeValue
+	}
+
+	report.Submitted = delta(MetricSubmitted)
+	report.Completed = delta(MetricCompleted)
+	waitSum, waitCount := delta(MetricWaitTimeSum), delta(MetricWaitTimeCount)
+	serviceSum, serviceCount := delta(MetricServiceTimeSum), delta(MetricServiceTimeCount)
+
+	if secs := report.Window.Seconds(); secs > 0 {
+		report.ArrivalRate = report.Submitted / secs
+		report.CompletionRate = report.Completed / secs
+	}
+	if waitCount > 0 {
+		report.MeanWaitTime = seconds(waitSum / waitCount)
+	}
+	if serviceCount > 0 && serviceSum > 0 {
+		report.MeanServiceTime = seconds(serviceSum / serviceCount)
+		report.ServiceRate = serviceCount / serviceSum
+		report.Utilization = report.ArrivalRate / report.ServiceRate
+	}
+	if utilization, ok := after.Samples.Sum(MetricUtilization); ok {
+		report.ReportedUtilization = &utilization
+	}
+
+	all := append(append([]Snapshot{before}, during...), after)
+	sum, count := 0.0, 0
+	for _, snapshot := range all {
+		if length, ok := snapshot.Samples.Sum(MetricQueueLength); ok {
+			sum += length
+			count++
+			if length > report.MaxQueueLength {
+				report.MaxQueueLength = length
+			}
+		}
+	}
+	if count > 0 {
+		report.MeanQueueLength = sum / float64(count)
+	} else {
+		report.MissingMetrics = append(report.MissingMetrics, MetricQueueLength)
+	}
+
+	return report
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,140 @@
+package remotewrite
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeSnappyLiterals decodes snappy blocks made of literal chunks, which is all EncodeSnappy emits
+func decodeSnappyLiterals(t *testing.T, src []byte) []byte {
+	length, n := binary.Uvarint(src)
+	require.Greater(t, n, 0)
+	src = src[n:]
+
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		require.Equal(t, byte(0), tag&3, "only literal chunks are expected")
+		size := int(tag >> 2)
+		src = src[1:]
+		switch size {
+		case 60:
+			size, src = int(src[0]), src[1:]
+		case 61:
+			size, src = int(src[0])|int(src[1])<<8, src[2:]
+		}
+		size++
+		dst = append(dst, src[:size]...)
+		src = src[size:]
+	}
+	require.Equal(t, int(length), len(dst))
+	return dst
+}
+
+// protoField is one decoded protobuf field
+type protoField struct {
+	num   int
+	bytes []byte
+	fixed uint64
+	value uint64
+}
+
+func decodeProto(t *testing.T, b []byte) []protoField {
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		require.Greater(t, n, 0)
+		b = b[n:]
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.value, n = binary.Uvarint(b)
+			b = b[n:]
+		case 1:
+			f.fixed, b = binary.LittleEndian.Uint64(b), b[8:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			f.bytes, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// decodeWriteRequest turns a WriteRequest into "name{labels} value@ts" strings
+func decodeWriteRequest(t *testing.T, b []byte) []string {
+	var out []string
+	for _, ts := range decodeProto(t, b) {
+		require.Equal(t, 1, ts.num)
+		var labels []string
+		var samples []string
+		for _, f := range decodeProto(t, ts.bytes) {
+			parts := decodeProto(t, f.bytes)
+			switch f.num {
+			case 1:
+				labels = append(labels, fmt.Sprintf("%s=%s", parts[0].bytes, parts[1].bytes))
+			case 2:
+				samples = append(samples, fmt.Sprintf("%g@%d", math.Float64frombits(parts[0].fixed), parts[1].value))
+			}
+		}
+		out = append(out, strings.Join(labels, ",")+" "+strings.Join(samples, ","))
+	}
+	return out
+}
+
+func TestEncodeSnappyLongInput(t *testing.T) {
+	src := []byte(strings.Repeat("0123456789", 20000))
+	assert.Equal(t, src, decodeSnappyLiterals(t, EncodeSnappy(src)))
+	assert.Equal(t, []byte("abc"), decodeSnappyLiterals(t, EncodeSnappy([]byte("abc"))))
+	assert.Equal(t, []byte(strings.Repeat("x", 100)), decodeSnappyLiterals(t, EncodeSnappy([]byte(strings.Repeat("x", 100)))))
+}
+
+func TestClientWrite(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, decodeWriteRequest(t, decodeSnappyLiterals(t, body))...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	ts := time.UnixMilli(1718000000123)
+	err := NewClient(receiver.URL).Write(context.Background(), []TimeSeries{
+		NewTimeSeries("mybank_loadtest_success_ratio", map[string]string{"run_id": "r1", "job": "loadtest"}, 0.5, ts),
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"__name__=mybank_loadtest_success_ratio,job=loadtest,run_id=r1 0.5@1718000000123"}, received)
+}
+
+func TestClientWriteReportsRejection(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	err := NewClient(receiver.URL).Write(context.Background(), []TimeSeries{NewTimeSeries("m", nil, 1, time.Now())})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order sample")
+}
@@ -0,0 +1,81 @@
+// Package shard maps accounts to the shards that own them, the way the bank will once
+// accounts are sharded by consistent hashing (roadmap phase 5).
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Mapper returns the shard that owns an account. Shards are numbered 0 to Shards()-1.
+type Mapper interface {
+	Shard(accountID string) int
+	Shards() int
+}
+
+// DefaultVirtualNodes is how many points every shard gets on a ring by default
+const DefaultVirtualNodes = 100
+
+// Ring is a consistent hash ring. Every shard owns the arcs that end at its virtual
+// nodes, an account belongs to the first virtual node at or after its hash.
+type Ring struct {
+	shards int
+	points []uint64 // Sorted hashes of the virtual nodes
+	owners []int    // Shard of every point
+}
+
+// NewRing creates a ring of shards with virtualNodes points each. More virtual nodes
+// spread the accounts more evenly.
+func NewRing(shards, virtualNodes int) (*Ring, error) {
+	if shards < 1 || virtualNodes < 1 {
+		return nil, fmt.Errorf("a ring needs at least one shard and one virtual node, got %d and %d", shards, virtualNodes)
+	}
+	type point struct {
+		hash  uint64
+		owner int
+	}
+	points := make([]point, 0, shards*virtualNodes)
+	for s := 0; s < shards; s++ {
+		for v := 0; v < virtualNodes; v++ {
+			points = append(points, point{hash("shard-" + strconv.Itoa(s) + "#" + strconv.Itoa(v)), s})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].hash < points[j].hash })
+
+	r := &Ring{shards: shards, points: make([]uint64, len(points)), owners: make([]int, len(points))}
+	for i, p := range points {
+		r.points[i], r.owners[i] = p.hash, p.owner
+	}
+	return r, nil
+}
+
+// Shard returns the shard owning the account
+func (r *Ring) Shard(accountID string) int {
+	h := hash(accountID)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0 // Wrap around
+	}
+	return r.owners[i]
+}
+
+// Shards returns the number of shards
+func (r *Ring) Shards() int {
+	return r.shards
+}
+
+// hash is FNV-1a followed by the splitmix64 finalizer. FNV alone maps keys that differ
+// in their last characters, like "acc-1" and "acc-2", to nearby points.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
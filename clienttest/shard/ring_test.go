@@ -0,0 +1,63 @@
+package shard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingIsStable(t *testing.T) {
+	a, err := NewRing(4, DefaultVirtualNodes)
+	require.NoError(t, err)
+	b, err := NewRing(4, DefaultVirtualNodes)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		account := fmt.Sprintf("acc-%d", i)
+		assert.Equal(t, a.Shard(account), b.Shard(account))
+		assert.Equal(t, a.Shard(account), a.Shard(account))
+	}
+}
+
+func TestVirtualNodesBalanceShards(t *testing.T) {
+	counts := func(virtualNodes int) []int {
+		r, err := NewRing(4, virtualNodes)
+		require.NoError(t, err)
+		counts := make([]int, r.Shards())
+		for i := 0; i < 10000; i++ {
+			counts[r.Shard(fmt.Sprintf("acc-%d", i))]++
+		}
+		return counts
+	}
+
+	for _, count := range counts(DefaultVirtualNodes) {
+		assert.InDelta(t, 2500, count, 500)
+	}
+	assert.Len(t, counts(1), 4)
+}
+
+func TestAddingAShardMovesFewAccounts(t *testing.T) {
+	four, err := NewRing(4, DefaultVirtualNodes)
+	require.NoError(t, err)
+	five, err := NewRing(5, DefaultVirtualNodes)
+	require.NoError(t, err)
+
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		account := fmt.Sprintf("acc-%d", i)
+		if before, after := four.Shard(account), five.Shard(account); before != after {
+			assert.Equal(t, 4, after, "accounts only move to the new shard")
+			moved++
+		}
+	}
+	assert.InDelta(t, 2000, moved, 500)
+}
+
+func TestNewRingValidates(t *testing.T) {
+	_, err := NewRing(0, 10)
+	assert.Error(t, err)
+	_, err = NewRing(2, 0)
+	assert.Error(t, err)
+}
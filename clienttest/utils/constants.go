@@ -1,3 +1,6 @@
 package utils
 
 const BASE_URL = "http://localhost:8080"
+
+// METRICS_URL is the server's Prometheus endpoint on the management port
+const METRICS_URL = "http://localhost:9001/actuator/prometheus"